@@ -23,7 +23,7 @@ func Chat(sc *storage.Client, model, ventrataToken, walksToken string) error {
 
 	walksTools := tools.New(sc, ventrataToken, walksToken, *slog.Default())
 
-	allTours, _ := walksTools.GetAllTours(tools.GetAllToursInput{})
+	allTours, _ := walksTools.GetAllTours(context.Background(), tools.GetAllToursInput{})
 
 	ch := &chatHandler{
 		messages: []api.Message{
@@ -79,7 +79,9 @@ func (ch *chatHandler) doChat(model string) error {
 
 		ch.responseMessage = []string{}
 
-		err := ch.client.Chat(ctx, req, ch.handleResponse)
+		err := ch.client.Chat(ctx, req, func(resp api.ChatResponse) error {
+			return ch.handleResponse(ctx, resp)
+		})
 		if err != nil {
 			return err
 		}
@@ -108,14 +110,14 @@ type chatHandler struct {
 	done      bool
 }
 
-func (ch *chatHandler) handleResponse(resp api.ChatResponse) error {
+func (ch *chatHandler) handleResponse(ctx context.Context, resp api.ChatResponse) error {
 	if len(resp.Message.ToolCalls) > 0 {
 		ch.messages = append(ch.messages, resp.Message)
 
 		ch.usingTool = true
 
 		tc := resp.Message.ToolCalls[0].Function
-		content, err := ch.walks.Execute(tc.Name, tc.Arguments)
+		content, err := ch.walks.Execute(ctx, tc.Name, tc.Arguments)
 		if err != nil {
 			return err
 		}
@@ -6,12 +6,16 @@ import (
 	"fmt"
 	"log/slog"
 	"net/url"
+	"time"
 
+	"walks-of-italy/metrics"
 	"walks-of-italy/storage"
 	"walks-of-italy/tours"
+	"walks-of-italy/tracing"
 
 	"github.com/mitchellh/mapstructure"
 	"github.com/ollama/ollama/api"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type Tools struct {
@@ -32,7 +36,7 @@ func New(sc *storage.Client, ventrataToken, walksToken string, logger slog.Logge
 	}
 }
 
-func executeToolFunction[T interface{ CacheKey() string }](cache map[string]string, args map[string]any, runTool func(T) (string, error)) (string, error) {
+func executeToolFunction[T interface{ CacheKey() string }](ctx context.Context, cache map[string]string, args map[string]any, runTool func(context.Context, T) (string, error)) (string, error) {
 	var input T
 	dec, _ := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
 		DecodeHook: mapstructure.TextUnmarshallerHookFunc(),
@@ -49,7 +53,7 @@ func executeToolFunction[T interface{ CacheKey() string }](cache map[string]stri
 		return cacheVal, nil
 	}
 
-	output, err := runTool(input)
+	output, err := runTool(ctx, input)
 	if err != nil {
 		return "", err
 	}
@@ -59,9 +63,17 @@ func executeToolFunction[T interface{ CacheKey() string }](cache map[string]stri
 	return output, nil
 }
 
-func (t Tools) Execute(name string, args map[string]any) (output string, _ error) {
+func (t Tools) Execute(ctx context.Context, name string, args map[string]any) (output string, err error) {
 	t.logger.With("name", name, "args", args).Debug("tool call")
 
+	// the name comes from the model, so unknown names share one label to keep the metric bounded
+	tool := name
+	ctx, span := tracing.Start(ctx, "ai.Execute", attribute.String("tool", name))
+	defer func() {
+		metrics.AIToolCalls.WithLabelValues(tool, metrics.Result(err)).Inc()
+		tracing.End(span, err)
+	}()
+
 	defer func(out *string) {
 		if out == nil {
 			return
@@ -71,12 +83,15 @@ func (t Tools) Execute(name string, args map[string]any) (output string, _ error
 
 	switch name {
 	case "getAllTours":
-		return executeToolFunction(t.cache, args, t.GetAllTours)
+		return executeToolFunction(ctx, t.cache, args, t.GetAllTours)
 	case "getTourDetails":
-		return executeToolFunction(t.cache, args, t.GetTourDetails)
+		return executeToolFunction(ctx, t.cache, args, t.GetTourDetails)
 	case "getTourAvailability":
-		return executeToolFunction(t.cache, args, t.GetAvailability)
+		return executeToolFunction(ctx, t.cache, args, t.GetAvailability)
+	case "getReleasePrediction":
+		return executeToolFunction(ctx, t.cache, args, t.GetReleasePrediction)
 	default:
+		tool = "unknown"
 		return "", fmt.Errorf("unknown function: %q", name)
 	}
 }
@@ -87,8 +102,8 @@ func (GetAllToursInput) CacheKey() string {
 	return "getAllTours"
 }
 
-func (t Tools) GetAllTours(GetAllToursInput) (string, error) {
-	allTours, err := t.sc.GetAll(context.Background(), url.Values{})
+func (t Tools) GetAllTours(ctx context.Context, _ GetAllToursInput) (string, error) {
+	allTours, err := t.sc.GetAll(ctx, url.Values{})
 	if err != nil {
 		return "", fmt.Errorf("error getting tours: %w", err)
 	}
@@ -115,13 +130,13 @@ func (g GetTourDetailsInput) CacheKey() string {
 	return "getTourDetails_" + g.TourID
 }
 
-func (t Tools) GetTourDetails(in GetTourDetailsInput) (string, error) {
-	tour, err := t.sc.Get(context.Background(), in.TourID)
+func (t Tools) GetTourDetails(ctx context.Context, in GetTourDetailsInput) (string, error) {
+	tour, err := t.sc.Get(ctx, in.TourID)
 	if err != nil {
 		return "", fmt.Errorf("error getting tour: %w", err)
 	}
 
-	desc, err := tour.GetDescription(context.Background(), tour.ApiUrl, t.walksToken)
+	desc, err := tour.GetDescription(ctx, tour.ApiUrl, t.walksToken)
 	if err != nil {
 		return "", fmt.Errorf("error getting description for %q: %w", tour.Name, err)
 	}
@@ -140,13 +155,13 @@ func (g GetAvailabilityInput) CacheKey() string {
 	return fmt.Sprintf("getTourDetails_%s_%s_%s", g.TourID, g.Start.String(), g.End.String())
 }
 
-func (t Tools) GetAvailability(in GetAvailabilityInput) (string, error) {
-	tour, err := t.sc.Get(context.Background(), in.TourID)
+func (t Tools) GetAvailability(ctx context.Context, in GetAvailabilityInput) (string, error) {
+	tour, err := t.sc.Get(ctx, in.TourID)
 	if err != nil {
 		return "", fmt.Errorf("error getting tour: %w", err)
 	}
 
-	avail, err := tour.GetAvailability(context.Background(), t.ventrataToken, in.Start, in.End)
+	avail, err := tour.GetAvailability(ctx, t.ventrataToken, in.Start, in.End)
 	if err != nil {
 		return "", fmt.Errorf("error getting description for %q: %w", tour.Name, err)
 	}
@@ -162,6 +177,42 @@ func (t Tools) GetAvailability(in GetAvailabilityInput) (string, error) {
 	return string(output), err
 }
 
+type GetReleasePredictionInput struct {
+	TourID string `mapstructure:"tour_id"`
+}
+
+func (g GetReleasePredictionInput) CacheKey() string {
+	return "getReleasePrediction_" + g.TourID
+}
+
+func (t Tools) GetReleasePrediction(ctx context.Context, in GetReleasePredictionInput) (string, error) {
+	tour, err := t.sc.Get(ctx, in.TourID)
+	if err != nil {
+		return "", fmt.Errorf("error getting tour: %w", err)
+	}
+
+	releases, err := t.sc.Releases(ctx, tour.ProductID)
+	if err != nil {
+		return "", fmt.Errorf("error getting releases for %q: %w", tour.Name, err)
+	}
+
+	prediction, ok := tours.PredictRelease(releases, time.Now(), time.Local)
+	if !ok {
+		return `{"instruction": "tell the user there aren't enough releases yet to predict the next one."}`, nil
+	}
+
+	output, err := json.Marshal(map[string]any{
+		"prediction":  prediction,
+		"timeZone":    time.Local.String(),
+		"instruction": "tell the user when the next dates are expected to be released and how confident the prediction is. do not describe the json structure.",
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return string(output), err
+}
+
 func (t Tools) Tools() api.Tools {
 	return api.Tools{
 		{
@@ -213,6 +264,23 @@ func (t Tools) Tools() api.Tools {
 				}.ToAPI(),
 			},
 		},
+		{
+			Type: "function",
+			Function: api.ToolFunction{
+				Name:        "getReleasePrediction",
+				Description: "Predict when a tour's next batch of dates will be released, based on when previous dates were released",
+				Parameters: ToolFunctionParameters{
+					Type:     "object",
+					Required: []string{"tour_id"},
+					Properties: ToolFunctionProperties{
+						"tour_id": {
+							Type:        api.PropertyType{"string"},
+							Description: "The UUID for identifying a tour",
+						},
+					},
+				}.ToAPI(),
+			},
+		},
 	}
 }
 
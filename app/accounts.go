@@ -0,0 +1,409 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"walks-of-italy/tours"
+
+	"github.com/calvinmclean/babyapi"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	sessionCookie   = "walks_session"
+	sessionDuration = 30 * 24 * time.Hour
+
+	// maxLoginFailures is how many failed logins are allowed from an IP address or for a username
+	// within loginFailureWindow
+	maxLoginFailures   = 5
+	loginFailureWindow = 15 * time.Minute
+)
+
+type userContextKey struct{}
+
+// userFromContext returns the logged-in user, or nil if the request doesn't have a valid session
+func userFromContext(ctx context.Context) *tours.User {
+	u, _ := ctx.Value(userContextKey{}).(*tours.User)
+	return u
+}
+
+func hashSessionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// sessionMiddleware adds the logged-in user to the request context when there is a valid session cookie
+func (a *App) sessionMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie(sessionCookie)
+		if err != nil || cookie.Value == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		u, err := a.sc.Users().SessionUser(r.Context(), hashSessionToken(cookie.Value), time.Now().UTC())
+		switch {
+		case errors.Is(err, babyapi.ErrNotFound):
+		case err != nil:
+			a.logger.Error("error getting session", "err", err)
+		default:
+			r = r.WithContext(context.WithValue(r.Context(), userContextKey{}, u))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+type loginPage struct {
+	Username string
+	Error    string
+}
+
+// GetLogin shows the login form
+func (a *App) GetLogin(w http.ResponseWriter, r *http.Request) {
+	if userFromContext(r.Context()) != nil {
+		http.Redirect(w, r, "/account", http.StatusSeeOther)
+		return
+	}
+	a.renderPage(w, http.StatusOK, "login", loginPage{})
+}
+
+// PostLogin checks the username and password and starts a session
+func (a *App) PostLogin(w http.ResponseWriter, r *http.Request) {
+	username := strings.TrimSpace(r.PostFormValue("username"))
+	password := r.PostFormValue("password")
+
+	keys := loginLimitKeys(r, username)
+	if !a.loginLimiter.allow(keys...) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(loginFailureWindow.Seconds())))
+		a.renderPage(w, http.StatusTooManyRequests, "login", loginPage{Username: username, Error: "Too many failed logins. Try again later."})
+		return
+	}
+
+	u, err := a.sc.Users().GetByUsername(r.Context(), username)
+	if err != nil && !errors.Is(err, babyapi.ErrNotFound) {
+		a.logger.Error("error getting user", "err", err)
+		a.renderPage(w, http.StatusInternalServerError, "login", loginPage{Username: username, Error: "Something went wrong. Try again."})
+		return
+	}
+	if !u.CheckPassword(password) {
+		a.loginLimiter.fail(keys...)
+		a.renderPage(w, http.StatusUnauthorized, "login", loginPage{Username: username, Error: "Invalid username or password"})
+		return
+	}
+	a.loginLimiter.reset(keys...)
+
+	tokenBytes := make([]byte, 32)
+	_, err = rand.Read(tokenBytes)
+	if err != nil {
+		http.Error(w, "error creating session", http.StatusInternalServerError)
+		return
+	}
+	token := hex.EncodeToString(tokenBytes)
+
+	now := time.Now().UTC()
+	err = a.sc.Users().AddSession(r.Context(), hashSessionToken(token), u.ID, now, now.Add(sessionDuration))
+	if err != nil {
+		a.logger.Error("error storing session", "err", err)
+		http.Error(w, "error creating session", http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  now.Add(sessionDuration),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, "/account", http.StatusSeeOther)
+}
+
+// loginLimitKeys are the keys that failed logins are counted by: the client's IP address and the username
+func loginLimitKeys(r *http.Request, username string) []string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	return []string{"ip:" + ip, "user:" + strings.ToLower(username)}
+}
+
+// loginLimiter counts failed logins for each key so guessing passwords is slowed down
+type loginLimiter struct {
+	now func() time.Time
+
+	lock     sync.Mutex
+	failures map[string][]time.Time
+}
+
+func newLoginLimiter() *loginLimiter {
+	return &loginLimiter{
+		now:      time.Now,
+		failures: map[string][]time.Time{},
+	}
+}
+
+// allow returns false if any of the keys has too many recent failures
+func (l *loginLimiter) allow(keys ...string) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.prune()
+	for _, key := range keys {
+		if len(l.failures[key]) >= maxLoginFailures {
+			return false
+		}
+	}
+	return true
+}
+
+func (l *loginLimiter) fail(keys ...string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.now()
+	for _, key := range keys {
+		l.failures[key] = append(l.failures[key], now)
+	}
+}
+
+// reset clears the failures after a successful login
+func (l *loginLimiter) reset(keys ...string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	for _, key := range keys {
+		delete(l.failures, key)
+	}
+}
+
+// prune removes failures that are older than the window
+func (l *loginLimiter) prune() {
+	cutoff := l.now().Add(-loginFailureWindow)
+	for key, times := range l.failures {
+		times = slices.DeleteFunc(times, func(t time.Time) bool { return t.Before(cutoff) })
+		if len(times) == 0 {
+			delete(l.failures, key)
+			continue
+		}
+		l.failures[key] = times
+	}
+}
+
+// PostLogout ends the session
+func (a *App) PostLogout(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie(sessionCookie)
+	if err == nil && cookie.Value != "" {
+		err = a.sc.Users().DeleteSession(r.Context(), hashSessionToken(cookie.Value))
+		if err != nil {
+			a.logger.Error("error deleting session", "err", err)
+		}
+	}
+
+	http.SetCookie(w, &http.Cookie{Name: sessionCookie, Value: "", Path: "/", MaxAge: -1})
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+type accountTour struct {
+	*tours.TourDetail
+	Subscribed     bool
+	SubscriptionID uuid.UUID
+}
+
+type accountRule struct {
+	*tours.AlertRule
+	TourName       string
+	Subscribed     bool
+	SubscriptionID uuid.UUID
+}
+
+type accountPage struct {
+	User    *tours.User
+	Tours   []accountTour
+	Rules   []accountRule
+	Message string
+	Error   string
+}
+
+// requireUser redirects to the login page if there is no logged-in user
+func requireUser(w http.ResponseWriter, r *http.Request) *tours.User {
+	u := userFromContext(r.Context())
+	if u == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+	}
+	return u
+}
+
+// GetAccount shows the user's notifier settings and subscriptions
+func (a *App) GetAccount(w http.ResponseWriter, r *http.Request) {
+	u := requireUser(w, r)
+	if u == nil {
+		return
+	}
+
+	a.renderAccount(w, r, u, http.StatusOK, r.URL.Query().Get("message"), "")
+}
+
+func (a *App) renderAccount(w http.ResponseWriter, r *http.Request, u *tours.User, status int, message, errMessage string) {
+	page, err := a.accountPage(r.Context(), u)
+	if err != nil {
+		a.logger.Error("error loading account", "err", err)
+		http.Error(w, "error loading account", http.StatusInternalServerError)
+		return
+	}
+	page.Message = message
+	page.Error = errMessage
+
+	a.renderPage(w, status, "account", page)
+}
+
+func (a *App) accountPage(ctx context.Context, u *tours.User) (*accountPage, error) {
+	subscriptions, err := a.sc.Users().UserSubscriptions(ctx, u.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting subscriptions: %w", err)
+	}
+
+	allTours, err := a.sc.GetAll(ctx, url.Values{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting tours: %w", err)
+	}
+
+	rules, err := a.sc.Rules().GetAll(ctx, url.Values{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting rules: %w", err)
+	}
+
+	page := &accountPage{User: u}
+	tourNames := map[uuid.UUID]string{}
+	for _, td := range allTours {
+		tourNames[td.ProductID] = td.Name
+
+		at := accountTour{TourDetail: td}
+		for _, s := range subscriptions {
+			if s.TourID == td.ProductID {
+				at.Subscribed = true
+				at.SubscriptionID = s.ID
+			}
+		}
+		page.Tours = append(page.Tours, at)
+	}
+
+	for _, rule := range rules {
+		ar := accountRule{AlertRule: rule, TourName: "All tours"}
+		if rule.TourID != uuid.Nil {
+			ar.TourName = tourNames[rule.TourID]
+		}
+		for _, s := range subscriptions {
+			if s.RuleID == rule.ID {
+				ar.Subscribed = true
+				ar.SubscriptionID = s.ID
+			}
+		}
+		page.Rules = append(page.Rules, ar)
+	}
+
+	return page, nil
+}
+
+// PostAccount updates the user's notifier settings and, optionally, their password
+func (a *App) PostAccount(w http.ResponseWriter, r *http.Request) {
+	u := requireUser(w, r)
+	if u == nil {
+		return
+	}
+
+	u.Notifier = tours.UserNotifier{
+		PushoverRecipient: strings.TrimSpace(r.PostFormValue("pushover_recipient")),
+		Email:             strings.TrimSpace(r.PostFormValue("email")),
+		NtfyTopic:         strings.TrimSpace(r.PostFormValue("ntfy_topic")),
+	}
+
+	if password := r.PostFormValue("password"); password != "" {
+		err := u.SetPassword(password)
+		if err != nil {
+			a.renderAccount(w, r, u, http.StatusBadRequest, "", err.Error())
+			return
+		}
+	}
+
+	err := a.sc.Users().Set(r.Context(), u)
+	if err != nil {
+		a.logger.Error("error updating user", "err", err)
+		a.renderAccount(w, r, u, http.StatusInternalServerError, "", "Error saving settings")
+		return
+	}
+
+	http.Redirect(w, r, "/account?message=Settings+saved", http.StatusSeeOther)
+}
+
+// PostSubscription subscribes the user to a tour or rule using the tour_id or rule_id form value
+func (a *App) PostSubscription(w http.ResponseWriter, r *http.Request) {
+	u := requireUser(w, r)
+	if u == nil {
+		return
+	}
+
+	s := &tours.Subscription{ID: uuid.New(), UserID: u.ID}
+	var err error
+	if id := r.PostFormValue("tour_id"); id != "" {
+		s.TourID, err = uuid.Parse(id)
+	}
+	if id := r.PostFormValue("rule_id"); id != "" && err == nil {
+		s.RuleID, err = uuid.Parse(id)
+	}
+	if err == nil {
+		err = s.Validate()
+	}
+	if err != nil {
+		a.renderAccount(w, r, u, http.StatusBadRequest, "", fmt.Sprintf("Invalid subscription: %v", err))
+		return
+	}
+
+	err = a.sc.Users().AddSubscription(r.Context(), s)
+	if err != nil {
+		a.logger.Error("error adding subscription", "err", err)
+		a.renderAccount(w, r, u, http.StatusInternalServerError, "", "Error adding subscription")
+		return
+	}
+
+	http.Redirect(w, r, "/account?message=Subscribed", http.StatusSeeOther)
+}
+
+// DeleteSubscription removes one of the user's subscriptions. It uses POST so it works from a form
+func (a *App) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	u := requireUser(w, r)
+	if u == nil {
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		a.renderAccount(w, r, u, http.StatusBadRequest, "", "Invalid subscription ID")
+		return
+	}
+
+	err = a.sc.Users().DeleteSubscription(r.Context(), u.ID, id)
+	if err != nil {
+		a.logger.Error("error deleting subscription", "err", err)
+		a.renderAccount(w, r, u, http.StatusInternalServerError, "", "Error removing subscription")
+		return
+	}
+
+	http.Redirect(w, r, "/account?message=Unsubscribed", http.StatusSeeOther)
+}
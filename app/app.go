@@ -6,41 +6,196 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"html/template"
 	"io"
 	"log/slog"
 	"net/http"
 	"net/url"
-	"strings"
 	"sync"
 	"time"
 
+	"walks-of-italy/metrics"
+	"walks-of-italy/notify"
 	"walks-of-italy/storage"
 	"walks-of-italy/storage/db"
 	"walks-of-italy/tours"
+	"walks-of-italy/tracing"
 
 	"github.com/calvinmclean/babyapi"
-	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// savedTourQueueSize is how many created or updated tours can wait to be polled
+const savedTourQueueSize = 32
+
 type App struct {
-	sc          *storage.Client
-	nc          *NotifyClient
+	sc *storage.Client
+	nc notify.Notifier
+	// notifiers are the backends that nc sends to
+	notifiers notify.Notifier
+	pipeline  *Pipeline
+	outbox    *Outbox
+	webhooks  *WebhookDispatcher
+	hooks     *HookRunner
+	receipts  *ReceiptTracker
+	status    *watchStatus
+	// stream sends events to subscribers of the /events endpoint
+	stream *EventStream
+	// lastMinuteHold has last-minute alerts that are waiting for quiet hours to end
+	lastMinuteHold *notificationHold
+	// availabilityCache has availability fetched for pages, separate from polling
+	availabilityCache *availabilityCache
+	// deadManSwitch sends a notification if there are no successful polls within this duration
+	deadManSwitch time.Duration
+	// userNotifiers enables sending notifications to subscribed users
+	userNotifiers *UserNotifierConfig
+	// pipelineConfig is used for each user's Pipeline
+	pipelineConfig    *PipelineConfig
+	userPipelines     map[uuid.UUID]*Pipeline
+	userPipelinesLock sync.Mutex
+	// requireAPIKey enables API key authentication for the API
+	requireAPIKey bool
+	// allowUnauthenticated allows serving without API keys on an address that isn't loopback
+	allowUnauthenticated bool
+	// loginLimiter slows down password guessing on the login page
+	loginLimiter *loginLimiter
+	// releasePolling is how often tours are polled during their predicted release window
+	releasePolling time.Duration
+	// pollLock prevents release polling from running at the same time as regular polling, which
+	// could store and notify about the same new date twice
+	pollLock sync.Mutex
+	// savedTours are tours that were just created or updated, which the watch loop polls
+	savedTours chan tours.TourDetail
+	// syncFile is a YAML file of tours that the stored tours are synced with when it changes
+	syncFile       string
+	syncMissing    MissingTours
+	syncAllowEmpty bool
+	// templates renders HTML pages
+	templates   *pageTemplates
 	api         *babyapi.API[*tours.TourDetail]
+	rulesAPI    *babyapi.API[*tours.AlertRule]
+	webhooksAPI *babyapi.API[*tours.Webhook]
 	addr        string
 	accessToken string
 	logger      slog.Logger
 }
 
-func New(addr string, accessToken string, sc *storage.Client, nc *NotifyClient) *App {
+// AvailabilityUpdate is the result of polling a tour's availability. Latest is only set when a new
+// furthest-out date is published
+type AvailabilityUpdate struct {
+	Latest  *tours.AvailabilityDetail
+	Changes []tours.Change
+}
+
+func New(addr string, accessToken string, sc *storage.Client, nc notify.Notifier) *App {
 	api := babyapi.
 		NewAPI("Tours", "/tours", func() *tours.TourDetail { return &tours.TourDetail{} }).
 		SetStorage(sc)
 
-	return &App{sc: sc, nc: nc, api: api, addr: addr, accessToken: accessToken, logger: *slog.Default()}
+	rulesAPI := babyapi.
+		NewAPI("Rules", "/rules", func() *tours.AlertRule { return &tours.AlertRule{} }).
+		SetStorage(sc.Rules())
+
+	webhooksAPI := babyapi.
+		NewAPI("Webhooks", "/webhooks", func() *tours.Webhook { return &tours.Webhook{} }).
+		SetStorage(sc.Webhooks())
+
+	a := &App{
+		sc:           sc,
+		nc:           nc,
+		api:          api,
+		rulesAPI:     rulesAPI,
+		webhooksAPI:  webhooksAPI,
+		status:       newWatchStatus(),
+		savedTours:   make(chan tours.TourDetail, savedTourQueueSize),
+		loginLimiter: newLoginLimiter(),
+		templates:    &pageTemplates{},
+		addr:         addr,
+		accessToken:  accessToken,
+		logger:       *slog.Default(),
+	}
+	a.webhooks = NewWebhookDispatcher(sc, &a.logger)
+	a.webhooksAPI.SetOnCreateOrUpdate(a.keepWebhookSecret)
+	a.availabilityCache = newAvailabilityCache()
+	a.lastMinuteHold = &notificationHold{}
+	a.userPipelines = map[uuid.UUID]*Pipeline{}
+	a.stream = NewEventStream()
+	if nc != nil {
+		a.notifiers = nc
+		a.nc = streamNotifier{nc, a.stream}
+	}
+	return a
+}
+
+// WithOutbox stores notifications, including ones for users, before sending them to each backend
+// so failed deliveries are retried and a history is kept. It should be used before WithPipeline so
+// only notifications that pass through the Pipeline are stored. The Outbox retries as part of Watch
+func (a *App) WithOutbox() *App {
+	a.outbox = NewOutbox(a.sc, a.notifiers, &a.logger)
+	a.outbox.recipients = a.notifierForUser
+	if a.notifiers != nil {
+		a.nc = streamNotifier{a.outbox, a.stream}
+	}
+	return a
+}
+
+// WithPushoverReceipts tracks receipts for emergency-priority Pushover messages. Receipts are
+// checked as part of Watch
+func (a *App) WithPushoverReceipts(p *notify.Pushover) *App {
+	if p == nil {
+		return a
+	}
+
+	a.receipts = NewReceiptTracker(a.sc, p, &a.logger)
+	p.TrackReceipts(a.receipts)
+	return a
+}
+
+// WithDeadManSwitch sends an urgent notification when no tours have been polled successfully within
+// the duration. It is disabled if the duration is zero
+func (a *App) WithDeadManSwitch(after time.Duration) *App {
+	a.deadManSwitch = after
+	return a
+}
+
+// WithTourSync keeps the stored tours in sync with a YAML file as part of Watch. The file is checked
+// for changes periodically. allowEmpty allows a file without tours to delete all of them
+func (a *App) WithTourSync(filename string, missing MissingTours, allowEmpty bool) *App {
+	a.syncFile = filename
+	a.syncMissing = missing
+	a.syncAllowEmpty = allowEmpty
+	return a
+}
+
+// WithHooks runs local commands for events from the watch loop
+func (a *App) WithHooks(config HookConfig) *App {
+	if len(config.Hooks) == 0 {
+		return a
+	}
+
+	a.hooks = NewHookRunner(config, &a.logger)
+	return a
+}
+
+// WithPipeline sends notifications through a Pipeline that deduplicates, holds, and batches them.
+// Each subscribed user gets their own Pipeline with the same config. Pipelines run as part of Watch
+func (a *App) WithPipeline(config PipelineConfig) *App {
+	a.pipelineConfig = &config
+	if a.nc == nil {
+		return a
+	}
+
+	a.pipeline = NewPipeline(a.nc, config, &a.logger)
+	a.nc = a.pipeline
+	return a
 }
 
 func (a *App) Run(ctx context.Context, watchInterval time.Duration) error {
+	err := a.checkAccess()
+	if err != nil {
+		return err
+	}
+
 	ctx, cancel := context.WithCancel(ctx)
 
 	var watchErr error
@@ -50,72 +205,130 @@ func (a *App) Run(ctx context.Context, watchInterval time.Duration) error {
 			cancel()
 		}
 	}()
+	go func() {
+		<-ctx.Done()
+		a.stream.Close()
+	}()
 
 	api := a.api.
 		WithContext(ctx).
 		SetOnCreateOrUpdate(func(w http.ResponseWriter, r *http.Request, td *tours.TourDetail) *babyapi.ErrResponse {
-			updated, err := a.UpdateLatestAvailability(r.Context(), *td)
-			if err != nil {
-				a.logger.Error("error updating availability", "tour_id", td.ProductID, "err", err)
-				return nil
+			a.updateSavedTour(td)
+			return nil
+		}).
+		SetAfterDelete(func(w http.ResponseWriter, r *http.Request) *babyapi.ErrResponse {
+			id, err := uuid.Parse(a.api.GetIDParam(r))
+			if err == nil {
+				a.status.remove(id)
 			}
-			a.logger.Debug("updated tour details", "tour_id", td.ProductID, "changed", updated != nil)
 			return nil
 		}).
 		AddCustomRoute(http.MethodGet, "/summary", babyapi.Handler(a.SummarizeLatestAvailabilities)).
-		AddCustomIDRoute(http.MethodGet, "/summary", a.api.GetRequestedResourceAndDo(a.SummarizeTourDates))
+		AddCustomRoute(http.MethodGet, "/manage", http.HandlerFunc(a.ManageTours)).
+		AddCustomRoute(http.MethodGet, "/new", http.HandlerFunc(a.NewTourForm)).
+		AddCustomRoute(http.MethodPost, "/new", http.HandlerFunc(a.CreateTour)).
+		AddCustomIDRoute(http.MethodGet, "/summary", a.tourPage(a.SummarizeTourDates)).
+		AddCustomIDRoute(http.MethodGet, "/availability", a.api.GetRequestedResourceAndDo(a.GetTourAvailability)).
+		AddCustomIDRoute(http.MethodGet, "/changes", a.api.GetRequestedResourceAndDo(a.GetTourChanges)).
+		AddCustomIDRoute(http.MethodGet, "/calendar", a.tourPage(a.TourCalendar)).
+		AddCustomIDRoute(http.MethodGet, "/calendar.ics", a.tourPage(a.GetTourCalendarFeed)).
+		AddCustomIDRoute(http.MethodGet, "/feed.atom", a.tourPage(a.GetTourFeed)).
+		AddCustomIDRoute(http.MethodGet, "/releases", a.api.GetRequestedResourceAndDo(a.GetTourReleases)).
+		AddCustomIDRoute(http.MethodGet, "/releases.svg", a.tourPage(a.GetTourReleasesChart)).
+		AddCustomIDRoute(http.MethodGet, "/prediction", a.api.GetRequestedResourceAndDo(a.GetTourPrediction)).
+		AddCustomIDRoute(http.MethodGet, "/timeline", a.tourPage(a.TourTimeline)).
+		AddCustomIDRoute(http.MethodGet, "/edit", a.tourPage(a.EditTourForm)).
+		AddCustomIDRoute(http.MethodPost, "/edit", a.tourPage(a.UpdateTour)).
+		AddCustomIDRoute(http.MethodPost, "/pause", a.tourPage(a.PauseTour)).
+		AddCustomIDRoute(http.MethodPost, "/delete", a.tourPage(a.DeleteTour)).
+		AddCustomIDRoute(http.MethodPost, "/test", a.tourPage(a.TestTourAvailability))
 
 	// setup root API to redirect from /
 	rootAPI := babyapi.NewRootAPI("walks-of-italy", "/").
 		SetAddress(a.addr).
+		AddMiddleware(metrics.Middleware).
+		AddMiddleware(tracing.Middleware).
+		AddMiddleware(a.sessionMiddleware).
+		AddMiddleware(a.authMiddleware).
+		AddCustomRoute(http.MethodGet, "/metrics", metrics.Handler()).
 		AddCustomRoute(http.MethodGet, "/", http.RedirectHandler("/tours/summary", http.StatusFound)).
-		AddNestedAPI(api)
-
-	err := rootAPI.Serve()
+		AddCustomRoute(http.MethodGet, "/static/*", staticHandler()).
+		AddCustomRoute(http.MethodGet, "/healthz", babyapi.Handler(a.GetHealth)).
+		AddCustomRoute(http.MethodGet, "/readyz", babyapi.Handler(a.GetReady)).
+		AddCustomRoute(http.MethodGet, "/status", babyapi.Handler(a.GetStatus)).
+		AddCustomRoute(http.MethodGet, "/events", http.HandlerFunc(a.GetEvents)).
+		AddCustomRoute(http.MethodGet, "/calendar.ics", http.HandlerFunc(a.GetCalendarFeed)).
+		AddCustomRoute(http.MethodGet, "/feed.atom", http.HandlerFunc(a.GetFeed)).
+		AddCustomRoute(http.MethodGet, "/changes", babyapi.Handler(a.GetChanges)).
+		AddCustomRoute(http.MethodGet, "/notifications", babyapi.Handler(a.GetNotifications)).
+		AddCustomRoute(http.MethodGet, "/notifications/receipts", babyapi.Handler(a.GetReceipts)).
+		AddCustomRoute(http.MethodGet, "/login", http.HandlerFunc(a.GetLogin)).
+		AddCustomRoute(http.MethodPost, "/login", http.HandlerFunc(a.PostLogin)).
+		AddCustomRoute(http.MethodPost, "/logout", http.HandlerFunc(a.PostLogout)).
+		AddCustomRoute(http.MethodGet, "/api-key", http.HandlerFunc(a.GetAPIKey)).
+		AddCustomRoute(http.MethodPost, "/api-key", http.HandlerFunc(a.PostAPIKey)).
+		AddCustomRoute(http.MethodGet, "/account", http.HandlerFunc(a.GetAccount)).
+		AddCustomRoute(http.MethodPost, "/account", http.HandlerFunc(a.PostAccount)).
+		AddCustomRoute(http.MethodPost, "/account/subscriptions", http.HandlerFunc(a.PostSubscription)).
+		AddCustomRoute(http.MethodPost, "/account/subscriptions/{id}/delete", http.HandlerFunc(a.DeleteSubscription)).
+		AddNestedAPI(api).
+		AddNestedAPI(a.rulesAPI).
+		AddNestedAPI(a.webhooksAPI.
+			AddCustomIDRoute(http.MethodGet, "/deliveries", a.webhooksAPI.GetRequestedResourceAndDo(a.GetWebhookDeliveries)).
+			AddCustomIDRoute(http.MethodPost, "/rotate-secret", a.webhooksAPI.GetRequestedResourceAndDo(a.RotateWebhookSecret)))
+
+	err = rootAPI.Serve()
 	if err != nil {
 		cancel()
 	}
 	return errors.Join(watchErr, err)
 }
 
-func (a *App) SummarizeLatestAvailabilities(w http.ResponseWriter, r *http.Request) render.Renderer {
-	availabilities, err := a.sc.GetAllLatestAvailabilities(r.Context())
-	if err != nil {
-		return babyapi.ErrInvalidRequest(fmt.Errorf("error getting availabilities: %w", err))
+// updateSavedTour queues a tour that was just created or updated to be polled by the watch loop, so
+// the request doesn't wait for the poll. If the queue is full, the tour is polled on the next interval
+func (a *App) updateSavedTour(td *tours.TourDetail) {
+	select {
+	case a.savedTours <- *td:
+	default:
+		a.logger.Warn("too many saved tours waiting to be polled", "tour_id", td.ProductID)
 	}
+}
 
-	tmpl := template.Must(template.New("tour_availability").Parse(toursSummaryHTML))
-	err = tmpl.Execute(w, availabilities)
-	if err != nil {
-		return babyapi.ErrInvalidRequest(fmt.Errorf("error executing template: %w", err))
+// runSavedTours polls tours queued by updateSavedTour until the context is cancelled
+func (a *App) runSavedTours(ctx context.Context) {
+	for {
+		select {
+		case td := <-a.savedTours:
+			a.pollSavedTour(ctx, &td)
+		case <-ctx.Done():
+			return
+		}
 	}
-
-	return nil
 }
 
-func (a *App) SummarizeTourDates(w http.ResponseWriter, r *http.Request, td *tours.TourDetail) (render.Renderer, *babyapi.ErrResponse) {
-	start := tours.DateFromTime(time.Now())
-	end := start.Add(1, 0, 0)
-	availability, err := td.FindAvailability(r.Context(), a.accessToken, start, end, func(a tours.AvailabilityDetail) bool {
-		return true
-	})
+// pollSavedTour polls a tour that was just created or updated. It's handled the same as a poll
+// from watching, so new dates and changes are notified and can't be recorded twice by a poll that
+// runs at the same time. Errors are logged since the tour was already saved
+func (a *App) pollSavedTour(ctx context.Context, td *tours.TourDetail) {
+	rules, err := a.sc.Rules().GetAll(ctx, url.Values{})
 	if err != nil {
-		return nil, babyapi.ErrInvalidRequest(fmt.Errorf("error getting availability: %w", err))
+		a.logger.Error("error getting rules", "tour_id", td.ProductID, "err", err)
+		return
 	}
 
-	err = availability.PrettySummary(w)
+	a.pollLock.Lock()
+	defer a.pollLock.Unlock()
+	err = a.UpdateLatestAvailabilities(ctx, []*tours.TourDetail{td}, a.onAvailabilityUpdate(ctx, rules), a.onPollError(ctx))
 	if err != nil {
-		return nil, babyapi.ErrInvalidRequest(fmt.Errorf("error creating summary: %w", err))
+		a.logger.Error("error updating availability", "tour_id", td.ProductID, "err", err)
 	}
-
-	return nil, nil
 }
 
 func (a *App) LogSummary(ctx context.Context, tours []tours.TourDetail) error {
 	for _, tour := range tours {
 		availability, err := a.sc.GetLatestAvailability(ctx, tour.ProductID)
 		if err != nil {
-			return fmt.Errorf("error getting availability for tour %q: %w", tour, err)
+			return fmt.Errorf("error getting availability for tour %q: %w", tour.Name, err)
 		}
 
 		a.logger.Info(
@@ -129,46 +342,46 @@ func (a *App) LogSummary(ctx context.Context, tours []tours.TourDetail) error {
 }
 
 func (a *App) PrettySummary(ctx context.Context, w io.Writer, tours []*tours.TourDetail) error {
-	tmpl := template.Must(template.New("availability").
-		Funcs(template.FuncMap{"truncate": func(s string, max int) string {
-			if len(s) <= max {
-				padding := max - len(s)
-				return s + strings.Repeat(" ", padding)
-			}
-			return s[:max-3] + "..."
-		}}).
-		Parse(`
-Tour Name                                                   | Available Date | Opened At
-------------------------------------------------------------|----------------|----------------
-{{ range . -}}
-{{ truncate .Name 59 }} | {{ .AvailabilityDate.Format "2006-01-02" }}     | {{ .RecordedAt.Format "2006-01-02 15:04:05" }}
-{{ end }}`))
-
 	availabilities, err := a.sc.GetAllLatestAvailabilities(ctx)
 	if err != nil {
 		return babyapi.ErrInvalidRequest(fmt.Errorf("error getting availabilities: %w", err))
 	}
 
-	return tmpl.Execute(w, availabilities)
+	return latestSummaryText(w, availabilities)
 }
 
-func (a *App) UpdateLatestAvailabilities(ctx context.Context, tours []*tours.TourDetail, onUpdate func(tours.TourDetail, tours.AvailabilityDetail)) error {
+// UpdateLatestAvailabilities polls all tours concurrently, except for paused tours. onUpdate is called for each tour that has a new
+// latest date or changed availability since the previous poll and onError is called for each tour that fails
+func (a *App) UpdateLatestAvailabilities(
+	ctx context.Context,
+	tours []*tours.TourDetail,
+	onUpdate func(tours.TourDetail, AvailabilityUpdate),
+	onError func(tours.TourDetail, error),
+) error {
 	var wg sync.WaitGroup
-	wg.Add(len(tours))
-
 	errChan := make(chan error, len(tours))
 	for _, tour := range tours {
+		if tour.Paused {
+			a.status.setPaused(*tour)
+			continue
+		}
+
+		wg.Add(1)
 		go func() {
 			defer wg.Done()
 
-			updated, err := a.UpdateLatestAvailability(ctx, *tour)
+			update, err := a.UpdateAvailability(ctx, *tour)
+			a.status.record(*tour, time.Now(), err)
 			if err != nil {
 				errChan <- fmt.Errorf("error updating availability for %q: %w", tour.ProductID, err)
+				if onError != nil {
+					onError(*tour, err)
+				}
 			}
-			a.logger.Debug("updated tour details", "tour_id", tour.ProductID, "changed", updated != nil)
+			a.logger.Debug("updated tour details", "tour_id", tour.ProductID, "changed", update.Latest != nil, "changes", len(update.Changes))
 
-			if onUpdate != nil && updated != nil {
-				onUpdate(*tour, *updated)
+			if onUpdate != nil && (update.Latest != nil || len(update.Changes) > 0) {
+				onUpdate(*tour, update)
 			}
 		}()
 	}
@@ -184,12 +397,57 @@ func (a *App) UpdateLatestAvailabilities(ctx context.Context, tours []*tours.Tou
 	return errors.Join(errs...)
 }
 
-func (a *App) UpdateLatestAvailability(ctx context.Context, tour tours.TourDetail) (*tours.AvailabilityDetail, error) {
-	availability, err := tour.GetLatestAvailability(ctx, a.accessToken)
+func (a *App) UpdateLatestAvailability(ctx context.Context, tour tours.TourDetail) (_ *tours.AvailabilityDetail, err error) {
+	ctx, span := tracing.Start(ctx, "app.UpdateLatestAvailability", tourAttributes(tour)...)
+	defer func() { tracing.End(span, err) }()
+
+	update, err := a.UpdateAvailability(ctx, tour)
+	if err != nil {
+		return nil, err
+	}
+	return update.Latest, nil
+}
+
+// UpdateAvailability gets a tour's availability for the next year to store a new latest date and
+// record changes from the previous poll
+func (a *App) UpdateAvailability(ctx context.Context, tour tours.TourDetail) (_ AvailabilityUpdate, err error) {
+	ctx, span := tracing.Start(ctx, "app.UpdateAvailability", tourAttributes(tour)...)
+	defer func() { tracing.End(span, err) }()
+
+	start := tours.DateFromTime(time.Now())
+	end := start.Add(1, 0, 0)
+
+	availability, err := tour.GetAvailability(ctx, a.accessToken, start, end)
+	if err != nil {
+		return AvailabilityUpdate{}, fmt.Errorf("error getting availability: %w", err)
+	}
+
+	latest, err := a.updateLatestAvailability(ctx, tour, availability.Latest(start))
+	if err != nil {
+		return AvailabilityUpdate{}, err
+	}
+
+	if latest != nil {
+		metrics.NewDates.WithLabelValues(tour.ProductID.String()).Inc()
+	}
+
+	changes, err := a.updateAvailabilityChanges(ctx, tour, availability, start, end)
 	if err != nil {
-		return nil, fmt.Errorf("error getting availability: %w", err)
+		return AvailabilityUpdate{Latest: latest}, err
+	}
+
+	for _, c := range changes {
+		metrics.AvailabilityChanges.WithLabelValues(tour.ProductID.String(), string(c.Type)).Inc()
 	}
+	span.SetAttributes(
+		attribute.Bool("availability.new_latest", latest != nil),
+		attribute.Int("availability.changes", len(changes)),
+	)
 
+	return AvailabilityUpdate{Latest: latest, Changes: changes}, nil
+}
+
+func (a *App) updateLatestAvailability(ctx context.Context, tour tours.TourDetail, availability tours.AvailabilityDetail) (*tours.AvailabilityDetail, error) {
 	storedAvailability, err := a.sc.GetLatestAvailability(ctx, tour.ProductID)
 	if errors.Is(err, sql.ErrNoRows) {
 		err = a.storeLatestAvailability(ctx, tour, availability)
@@ -215,7 +473,12 @@ func (a *App) UpdateLatestAvailability(ctx context.Context, tour tours.TourDetai
 	return &availability, nil
 }
 
-func (a *App) storeLatestAvailability(ctx context.Context, tour tours.TourDetail, availability tours.AvailabilityDetail) error {
+func (a *App) storeLatestAvailability(ctx context.Context, tour tours.TourDetail, availability tours.AvailabilityDetail) (err error) {
+	ctx, span := tracing.Start(ctx, "app.storeLatestAvailability",
+		append(tourAttributes(tour), attribute.String("availability.date", availability.LocalDateTimeStart.Format(time.DateOnly)))...,
+	)
+	defer func() { tracing.End(span, err) }()
+
 	availabilityJSON, err := json.Marshal(availability)
 	if err != nil {
 		return fmt.Errorf("error marshalling availability JSON: %w", err)
@@ -233,26 +496,27 @@ func (a *App) storeLatestAvailability(ctx context.Context, tour tours.TourDetail
 	return nil
 }
 
-func (a *App) updateAvailabilitiesForWatch(ctx context.Context, t time.Time) error {
+func (a *App) updateAvailabilitiesForWatch(ctx context.Context, t time.Time) (err error) {
+	ctx, span := tracing.Start(ctx, "app.Poll")
+	defer func() { tracing.End(span, err) }()
+
 	allTours, err := a.sc.GetAll(ctx, url.Values{})
 	if err != nil {
 		return fmt.Errorf("error getting tours: %w", err)
 	}
+	span.SetAttributes(attribute.Int("tours", len(allTours)))
+	a.status.retain(allTours)
 
-	a.logger.Debug("updating availabilities")
-	err = a.UpdateLatestAvailabilities(ctx, allTours, func(tour tours.TourDetail, availability tours.AvailabilityDetail) {
-		if a.nc == nil {
-			return
-		}
+	rules, err := a.sc.Rules().GetAll(ctx, url.Values{})
+	if err != nil {
+		return fmt.Errorf("error getting rules: %w", err)
+	}
 
-		err := a.nc.Send(
-			"New tour availabilities posted",
-			fmt.Sprintf("Tour: %s\nDate: %s", tour.Name, availability.LocalDateTimeStart.Format(time.DateOnly)),
-		)
-		if err != nil {
-			a.logger.Error("error sending notification", "err", err)
-		}
-	})
+	a.logger.Debug("updating availabilities")
+	defer metrics.Since(metrics.PollDuration, time.Now())
+	a.pollLock.Lock()
+	defer a.pollLock.Unlock()
+	err = a.UpdateLatestAvailabilities(ctx, allTours, a.onAvailabilityUpdate(ctx, rules), a.onPollError(ctx))
 	if err != nil {
 		return fmt.Errorf("error updating availabilities: %w", err)
 	}
@@ -260,7 +524,62 @@ func (a *App) updateAvailabilitiesForWatch(ctx context.Context, t time.Time) err
 	return nil
 }
 
+// onAvailabilityUpdate publishes events and sends notifications for a tour's update from polling
+func (a *App) onAvailabilityUpdate(ctx context.Context, rules []*tours.AlertRule) func(tours.TourDetail, AvailabilityUpdate) {
+	return func(tour tours.TourDetail, update AvailabilityUpdate) {
+		a.publish(ctx, availabilityEvents(tour, update, time.Now())...)
+
+		if a.nc == nil && a.userNotifiers == nil {
+			return
+		}
+
+		if update.Latest != nil {
+			date := update.Latest.LocalDateTimeStart
+			a.sendTourNotification(ctx, tour, nil, tourNotification(tour, date, false,
+				"New tour availabilities posted",
+				fmt.Sprintf("Tour: %s\nDate: %s", tour.Name, date.Format(time.DateOnly)),
+			))
+		}
+
+		a.notifyChanges(ctx, tour, update.Changes, rules)
+		a.notifyLastMinute(ctx, tour, update.Changes, time.Now())
+	}
+}
+
+func (a *App) onPollError(ctx context.Context) func(tours.TourDetail, error) {
+	return func(tour tours.TourDetail, err error) {
+		a.publish(ctx, tours.NewPollFailedEvent(tour, err, time.Now()))
+	}
+}
+
 func (a *App) Watch(ctx context.Context, interval time.Duration) error {
+	if a.pipeline != nil {
+		go a.pipeline.Run(ctx)
+	}
+	if a.pipelineConfig != nil && a.userNotifiers != nil {
+		go a.runUserPipelines(ctx)
+	}
+	if a.outbox != nil {
+		go a.outbox.Run(ctx)
+	}
+	if a.receipts != nil {
+		go a.receipts.Run(ctx)
+	}
+	go a.webhooks.Run(ctx)
+	go a.runHeldNotifications(ctx)
+	go a.runSavedTours(ctx)
+	if a.syncFile != "" {
+		go a.runTourSync(ctx)
+	}
+	if a.releasePolling > 0 {
+		go a.runReleasePolling(ctx)
+	}
+
+	a.status.start(time.Now())
+	if a.deadManSwitch > 0 {
+		go a.runDeadManSwitch(ctx)
+	}
+
 	err := a.updateAvailabilitiesForWatch(ctx, time.Now())
 	if err != nil {
 		a.logger.Error("error updating availabilities", "err", err)
@@ -270,6 +589,8 @@ func (a *App) Watch(ctx context.Context, interval time.Duration) error {
 
 	next := now.Truncate(interval).Add(interval)
 	untilNext := time.Until(next)
+	a.status.setNextRun(next)
+	a.publishStatus()
 
 	a.logger.Debug("waiting to start", "duration", untilNext.String())
 	select {
@@ -288,8 +609,17 @@ func (a *App) Watch(ctx context.Context, interval time.Duration) error {
 			if err != nil {
 				a.logger.Error("error updating availabilities", "err", err)
 			}
+			a.status.setNextRun(t.Add(interval))
+			a.publishStatus()
 		case <-ctx.Done():
 			return ctx.Err()
 		}
 	}
 }
+
+func tourAttributes(tour tours.TourDetail) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("tour.id", tour.ProductID.String()),
+		attribute.String("tour.name", tour.Name),
+	}
+}
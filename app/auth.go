@@ -0,0 +1,206 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"walks-of-italy/tours"
+
+	"github.com/calvinmclean/babyapi"
+	"github.com/go-chi/render"
+)
+
+const (
+	apiKeyHeader = "X-API-Key"
+	apiKeyCookie = "walks_api_key"
+	apiKeyQuery  = "api_key"
+)
+
+var errUnauthorized = &babyapi.ErrResponse{HTTPStatusCode: http.StatusUnauthorized, StatusText: "Unauthorized"}
+
+// publicPaths don't require an API key. The account pages use the login session instead, and static
+// files are needed to show the login page
+var publicPaths = []string{"/healthz", "/readyz", "/login", "/logout", "/api-key", "/account", "/static"}
+
+// WithAPIKeys requires an API key for API requests. Read-only requests need the read scope and
+// requests that create, update, or delete resources need the admin scope. Logged-in users have the
+// read scope
+func (a *App) WithAPIKeys(enabled bool) *App {
+	a.requireAPIKey = enabled
+	return a
+}
+
+// WithUnauthenticatedAccess allows serving without API keys on an address that other hosts can reach
+func (a *App) WithUnauthenticatedAccess(allowed bool) *App {
+	a.allowUnauthenticated = allowed
+	return a
+}
+
+// checkAccess returns an error if API keys aren't required and the server listens on an address
+// that other hosts can reach, unless that is explicitly allowed
+func (a *App) checkAccess() error {
+	if a.requireAPIKey || isLoopback(a.addr) {
+		return nil
+	}
+	if !a.allowUnauthenticated {
+		return fmt.Errorf("refusing to serve on %q without API keys: use --require-api-key, listen on a loopback address like localhost:7077, or use --allow-unauthenticated", a.addr)
+	}
+
+	a.logger.Warn("API KEYS ARE NOT REQUIRED: anyone who can reach the server can read and change tours, rules, and webhooks", "addr", a.addr)
+	return nil
+}
+
+// isLoopback returns true if the address only accepts connections from the same host. An empty
+// host listens on all interfaces
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// requiredScope returns the scope needed for the request, or an empty Scope if it's public
+func requiredScope(r *http.Request) tours.Scope {
+	for _, p := range publicPaths {
+		if r.URL.Path == p || strings.HasPrefix(r.URL.Path, p+"/") {
+			return ""
+		}
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return tours.ScopeRead
+	default:
+		return tours.ScopeAdmin
+	}
+}
+
+// isFeed returns true for feeds that are read by apps that can't set headers, like calendars and
+// feed readers
+func isFeed(r *http.Request) bool {
+	return strings.HasSuffix(r.URL.Path, ".ics") || strings.HasSuffix(r.URL.Path, ".atom")
+}
+
+// requestAPIKey gets the API key from the Authorization header, X-API-Key header, or the cookie
+// that is set by the web UI. Only feeds can use the api_key query parameter, since query strings end
+// up in access logs and browser history
+func requestAPIKey(r *http.Request) string {
+	if key, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(key)
+	}
+	if key := r.Header.Get(apiKeyHeader); key != "" {
+		return key
+	}
+	if cookie, err := r.Cookie(apiKeyCookie); err == nil {
+		return cookie.Value
+	}
+	if isFeed(r) {
+		return r.URL.Query().Get(apiKeyQuery)
+	}
+	return ""
+}
+
+// requestScope returns the scope of the request's API key or session. It returns an empty Scope if
+// the request doesn't have valid credentials
+func (a *App) requestScope(ctx context.Context, key string) (tours.Scope, error) {
+	if key == "" {
+		if userFromContext(ctx) != nil {
+			return tours.ScopeRead, nil
+		}
+		return "", nil
+	}
+
+	apiKey, err := a.sc.APIKeys().GetByHash(ctx, tours.HashAPIKey(key))
+	if errors.Is(err, babyapi.ErrNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return apiKey.Scope, nil
+}
+
+// authMiddleware checks the request's API key when API keys are required. Browsers without a key
+// are redirected to the page for entering one
+func (a *App) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		required := requiredScope(r)
+		if !a.requireAPIKey || required == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		scope, err := a.requestScope(r.Context(), requestAPIKey(r))
+		if err != nil {
+			a.logger.Error("error checking API key", "err", err)
+			_ = render.Render(w, r, babyapi.InternalServerError(err))
+			return
+		}
+
+		switch {
+		case scope == "" && r.Method == http.MethodGet && strings.Contains(r.Header.Get("Accept"), "text/html"):
+			http.Redirect(w, r, "/api-key", http.StatusSeeOther)
+		case scope == "":
+			w.Header().Set("WWW-Authenticate", `Bearer realm="walks-of-italy"`)
+			_ = render.Render(w, r, errUnauthorized)
+		case !scope.Allows(required):
+			_ = render.Render(w, r, babyapi.ErrForbidden)
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+type apiKeyPage struct {
+	HasKey bool
+	Error  string
+}
+
+// GetAPIKey shows a form for using an API key in the browser
+func (a *App) GetAPIKey(w http.ResponseWriter, r *http.Request) {
+	_, err := r.Cookie(apiKeyCookie)
+	a.renderPage(w, http.StatusOK, "api_key", apiKeyPage{HasKey: err == nil})
+}
+
+// PostAPIKey checks the API key and stores it in a cookie so the browser uses it for API requests.
+// An empty key removes the cookie
+func (a *App) PostAPIKey(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimSpace(r.PostFormValue("key"))
+	if key == "" {
+		http.SetCookie(w, &http.Cookie{Name: apiKeyCookie, Value: "", Path: "/", MaxAge: -1})
+		http.Redirect(w, r, "/api-key", http.StatusSeeOther)
+		return
+	}
+
+	scope, err := a.requestScope(r.Context(), key)
+	if err != nil {
+		a.logger.Error("error checking API key", "err", err)
+		a.renderPage(w, http.StatusInternalServerError, "api_key", apiKeyPage{Error: "Something went wrong. Try again."})
+		return
+	}
+	if scope == "" {
+		a.renderPage(w, http.StatusUnauthorized, "api_key", apiKeyPage{Error: "Invalid API key"})
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     apiKeyCookie,
+		Value:    key,
+		Path:     "/",
+		MaxAge:   int(sessionDuration.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		// Strict prevents other sites from using the key to make requests
+		SameSite: http.SameSiteStrictMode,
+	})
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
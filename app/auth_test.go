@@ -0,0 +1,143 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"walks-of-italy/storage"
+	"walks-of-italy/tours"
+)
+
+func TestAuthMiddleware(t *testing.T) {
+	ctx := context.Background()
+
+	sc, err := storage.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sc.Close()
+
+	keys := map[tours.Scope]string{}
+	for _, scope := range []tours.Scope{tours.ScopeRead, tours.ScopeAdmin} {
+		apiKey, key, err := tours.NewAPIKey(string(scope), scope)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = sc.APIKeys().Add(ctx, apiKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[scope] = key
+	}
+
+	user, err := tours.NewUser("alice", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := New("", "", sc, nil).WithAPIKeys(true)
+	handler := a.authMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		setup    func(*http.Request) *http.Request
+		expected int
+	}{
+		{"NoKey", http.MethodGet, "/tours", nil, http.StatusUnauthorized},
+		{"InvalidKey", http.MethodGet, "/tours", func(r *http.Request) *http.Request {
+			r.Header.Set("Authorization", "Bearer woi_invalid")
+			return r
+		}, http.StatusUnauthorized},
+		{"BrowserRedirect", http.MethodGet, "/tours/summary", func(r *http.Request) *http.Request {
+			r.Header.Set("Accept", "text/html")
+			return r
+		}, http.StatusSeeOther},
+		{"PublicPath", http.MethodGet, "/healthz", nil, http.StatusNoContent},
+		{"ReadKeyGet", http.MethodGet, "/tours", func(r *http.Request) *http.Request {
+			r.Header.Set("Authorization", "Bearer "+keys[tours.ScopeRead])
+			return r
+		}, http.StatusNoContent},
+		{"ReadKeyPost", http.MethodPost, "/tours", func(r *http.Request) *http.Request {
+			r.Header.Set(apiKeyHeader, keys[tours.ScopeRead])
+			return r
+		}, http.StatusForbidden},
+		{"AdminKeyDelete", http.MethodDelete, "/tours/1", func(r *http.Request) *http.Request {
+			r.Header.Set("Authorization", "Bearer "+keys[tours.ScopeAdmin])
+			return r
+		}, http.StatusNoContent},
+		{"AdminKeyCookie", http.MethodPost, "/tours", func(r *http.Request) *http.Request {
+			r.AddCookie(&http.Cookie{Name: apiKeyCookie, Value: keys[tours.ScopeAdmin]})
+			return r
+		}, http.StatusNoContent},
+		{"FeedQueryKey", http.MethodGet, "/calendar.ics?api_key=" + keys[tours.ScopeRead], nil, http.StatusNoContent},
+		{"AtomFeedQueryKey", http.MethodGet, "/feed.atom?api_key=" + keys[tours.ScopeRead], nil, http.StatusNoContent},
+		{"QueryKeyNotFeed", http.MethodGet, "/tours?api_key=" + keys[tours.ScopeRead], nil, http.StatusUnauthorized},
+		{"SessionGet", http.MethodGet, "/tours", func(r *http.Request) *http.Request {
+			return r.WithContext(context.WithValue(r.Context(), userContextKey{}, user))
+		}, http.StatusNoContent},
+		{"SessionPost", http.MethodPost, "/tours", func(r *http.Request) *http.Request {
+			return r.WithContext(context.WithValue(r.Context(), userContextKey{}, user))
+		}, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.setup != nil {
+				r = tt.setup(r)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.expected {
+				t.Errorf("expected status %d, got %d: %s", tt.expected, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		a.WithAPIKeys(false)
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/tours", nil))
+		if w.Code != http.StatusNoContent {
+			t.Errorf("expected status %d, got %d", http.StatusNoContent, w.Code)
+		}
+	})
+}
+
+func TestCheckAccess(t *testing.T) {
+	tests := []struct {
+		addr          string
+		requireAPIKey bool
+		allowed       bool
+		expectErr     bool
+	}{
+		{"localhost:7077", false, false, false},
+		{"127.0.0.1:7077", false, false, false},
+		{"[::1]:7077", false, false, false},
+		{":7077", false, false, true},
+		{"0.0.0.0:7077", false, false, true},
+		{"192.168.1.10:7077", false, false, true},
+		{":7077", true, false, false},
+		{":7077", false, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			a := &App{addr: tt.addr, logger: *slog.Default()}
+			a.WithAPIKeys(tt.requireAPIKey).WithUnauthenticatedAccess(tt.allowed)
+			err := a.checkAccess()
+			if (err != nil) != tt.expectErr {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"walks-of-italy/tours"
+
+	"github.com/calvinmclean/babyapi"
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+)
+
+// AvailabilityResponse is the response for a tour's filtered availability. FetchedAt is when the
+// availability was requested from Ventrata, which can be earlier than the request when it's cached
+type AvailabilityResponse struct {
+	TourID    uuid.UUID  `json:"tourId"`
+	Start     tours.Date `json:"start"`
+	End       tours.Date `json:"end"`
+	Option    string     `json:"option"`
+	FetchedAt time.Time  `json:"fetchedAt"`
+	Items     []TourDate `json:"items"`
+}
+
+func (*AvailabilityResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// availabilityQuery is the date range, product option, and filter for an availability request
+type availabilityQuery struct {
+	Start, End tours.Date
+	Option     string
+	Filter     tours.AvailabilityFilter
+}
+
+// parseAvailabilityQuery parses the query parameters for the availability endpoint. The default
+// range is one year starting today, which is the same as polling so the latest snapshot can be used.
+// Ranges can't be longer than one year
+func parseAvailabilityQuery(q url.Values, today tours.Date) (availabilityQuery, error) {
+	result := availabilityQuery{
+		Start:  today,
+		Option: tours.DefaultOptionID,
+	}
+
+	if s := q.Get("start"); s != "" {
+		err := result.Start.UnmarshalText([]byte(s))
+		if err != nil {
+			return availabilityQuery{}, errors.New("invalid start: must be formatted like 2025-11-01")
+		}
+	}
+
+	result.End = result.Start.Add(1, 0, 0)
+	if e := q.Get("end"); e != "" {
+		err := result.End.UnmarshalText([]byte(e))
+		if err != nil {
+			return availabilityQuery{}, errors.New("invalid end: must be formatted like 2025-11-30")
+		}
+	}
+	if result.End.ToTime().Before(result.Start.ToTime()) {
+		return availabilityQuery{}, errors.New("invalid end: must not be before start")
+	}
+	if result.End.ToTime().After(result.Start.Add(1, 0, 0).ToTime()) {
+		return availabilityQuery{}, errors.New("invalid end: must be within one year of start")
+	}
+
+	if o := q.Get("option"); o != "" {
+		result.Option = o
+	}
+
+	minVacancies, err := queryInt(q, "min_vacancies")
+	if err != nil {
+		return availabilityQuery{}, err
+	}
+	party, err := queryInt(q, "party")
+	if err != nil {
+		return availabilityQuery{}, err
+	}
+
+	result.Filter, err = tours.ParseAvailabilityFilter(minVacancies, party, q.Get("max_price"), q.Get("weekday"), q.Get("after"), q.Get("before"))
+	if err != nil {
+		return availabilityQuery{}, err
+	}
+
+	return result, nil
+}
+
+func queryInt(q url.Values, name string) (int, error) {
+	s := q.Get(name)
+	if s == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: must be a number", name)
+	}
+	return n, nil
+}
+
+// GetTourAvailability gets the tour's available slots that match the filters in the query. It uses
+// the same cache as the calendar, so it only requests availability from Ventrata when it's stale
+func (a *App) GetTourAvailability(w http.ResponseWriter, r *http.Request, td *tours.TourDetail) (render.Renderer, *babyapi.ErrResponse) {
+	query, err := parseAvailabilityQuery(r.URL.Query(), tours.DateFromTime(time.Now().UTC()))
+	if err != nil {
+		return nil, babyapi.ErrInvalidRequest(err)
+	}
+
+	availability, fetchedAt, err := a.cachedOptionAvailability(r.Context(), *td, query.Option, query.Start, query.End)
+	if err != nil {
+		return nil, &babyapi.ErrResponse{
+			Err:            err,
+			HTTPStatusCode: http.StatusBadGateway,
+			StatusText:     "Error getting availability.",
+			ErrorText:      err.Error(),
+		}
+	}
+
+	return &AvailabilityResponse{
+		TourID:    td.ProductID,
+		Start:     query.Start,
+		End:       query.End,
+		Option:    query.Option,
+		FetchedAt: fetchedAt,
+		Items:     tourDateItems(query.Filter.Apply(availability)),
+	}, nil
+}
@@ -0,0 +1,130 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"walks-of-italy/storage"
+	"walks-of-italy/storage/db"
+	"walks-of-italy/tours"
+
+	"github.com/google/uuid"
+)
+
+func TestParseAvailabilityQuery(t *testing.T) {
+	today := tours.NewDate(2025, time.November, 1)
+
+	t.Run("Defaults", func(t *testing.T) {
+		q, err := parseAvailabilityQuery(url.Values{}, today)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if q.Start != today || q.End != tours.NewDate(2026, time.November, 1) || q.Option != tours.DefaultOptionID {
+			t.Errorf("unexpected query: %+v", q)
+		}
+	})
+
+	t.Run("AllParameters", func(t *testing.T) {
+		values, _ := url.ParseQuery("start=2025-12-01&end=2025-12-31&option=EARLY&min_vacancies=2&party=3&max_price=99&weekday=sat&after=08:00&before=12:00")
+		q, err := parseAvailabilityQuery(values, today)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if q.Start != tours.NewDate(2025, time.December, 1) || q.End != tours.NewDate(2025, time.December, 31) || q.Option != "EARLY" {
+			t.Errorf("unexpected query: %+v", q)
+		}
+		if q.Filter.MinVacancies != 2 || q.Filter.Party != 3 || q.Filter.MaxPrice != 9900 || q.Filter.After != 8*60 || q.Filter.Before != 12*60 {
+			t.Errorf("unexpected filter: %+v", q.Filter)
+		}
+	})
+
+	errTests := []struct {
+		name  string
+		query string
+	}{
+		{"InvalidStart", "start=tomorrow"},
+		{"EndBeforeStart", "start=2025-12-01&end=2025-11-30"},
+		{"RangeOverOneYear", "start=2025-12-01&end=2026-12-02"},
+		{"InvalidParty", "party=two"},
+		{"InvalidWeekday", "weekday=someday"},
+	}
+	for _, tt := range errTests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, _ := url.ParseQuery(tt.query)
+			_, err := parseAvailabilityQuery(values, today)
+			if err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestGetTourAvailability(t *testing.T) {
+	ctx := context.Background()
+
+	sc, err := storage.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sc.Close()
+
+	start := time.Now().UTC().AddDate(0, 0, 7).Truncate(24 * time.Hour).Add(9 * time.Hour)
+	rawData, err := json.Marshal(tours.Availabilities{
+		{LocalDateTimeStart: start, LocalDateTimeEnd: start.Add(3 * time.Hour), Available: true, Vacancies: 2},
+		{LocalDateTimeStart: start.Add(5 * time.Hour), LocalDateTimeEnd: start.Add(8 * time.Hour), Available: true, Vacancies: 6},
+		{LocalDateTimeStart: start.Add(24 * time.Hour)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	td := tours.TourDetail{Name: "Colosseum", ProductID: uuid.New()}
+	err = sc.Set(ctx, &td)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sc.UpsertAvailabilitySnapshot(ctx, db.UpsertAvailabilitySnapshotParams{TourUuid: td.ProductID, RawData: string(rawData)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := New("", "", sc, nil)
+
+	t.Run("Filtered", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/tours/"+td.ProductID.String()+"/availability?party=4", nil)
+		resp, errResp := a.GetTourAvailability(httptest.NewRecorder(), r, &td)
+		if errResp != nil {
+			t.Fatal(errResp.Err)
+		}
+
+		availability := resp.(*AvailabilityResponse)
+		if len(availability.Items) != 1 || availability.Items[0].Vacancies != 6 || !availability.Items[0].Start.Equal(start.Add(5*time.Hour)) {
+			t.Errorf("unexpected items: %+v", availability.Items)
+		}
+	})
+
+	t.Run("AvailableOnly", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/tours/"+td.ProductID.String()+"/availability", nil)
+		resp, errResp := a.GetTourAvailability(httptest.NewRecorder(), r, &td)
+		if errResp != nil {
+			t.Fatal(errResp.Err)
+		}
+		if items := resp.(*AvailabilityResponse).Items; len(items) != 2 {
+			t.Errorf("expected 2 available slots, got %d", len(items))
+		}
+	})
+
+	t.Run("InvalidQuery", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/tours/"+td.ProductID.String()+"/availability?max_price=free", nil)
+		_, errResp := a.GetTourAvailability(httptest.NewRecorder(), r, &td)
+		if errResp == nil || errResp.HTTPStatusCode != http.StatusBadRequest {
+			t.Errorf("expected bad request, got %+v", errResp)
+		}
+	})
+}
@@ -0,0 +1,142 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"walks-of-italy/tours"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// availabilityCacheTTL is how long cached availability, including the snapshot from the last poll,
+	// is used before getting it from Ventrata again
+	availabilityCacheTTL = 15 * time.Minute
+	// availabilityCacheMaxEntries limits the cache size since the keys come from request parameters
+	availabilityCacheMaxEntries = 500
+)
+
+type availabilityCacheKey struct {
+	tourID     uuid.UUID
+	option     string
+	start, end tours.Date
+}
+
+type availabilityCacheEntry struct {
+	availability tours.Availabilities
+	fetchedAt    time.Time
+}
+
+// availabilityCache keeps availability that was fetched for pages and the API so browsing doesn't
+// make a Ventrata request every time
+type availabilityCache struct {
+	entries map[availabilityCacheKey]availabilityCacheEntry
+	mu      sync.Mutex
+}
+
+func newAvailabilityCache() *availabilityCache {
+	return &availabilityCache{entries: map[availabilityCacheKey]availabilityCacheEntry{}}
+}
+
+func (c *availabilityCache) get(key availabilityCacheKey, now time.Time) (availabilityCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || now.Sub(entry.fetchedAt) > availabilityCacheTTL {
+		return availabilityCacheEntry{}, false
+	}
+	return entry, true
+}
+
+// set stores the entry and removes expired entries. The oldest entry is removed if the cache is full
+func (c *availabilityCache) set(key availabilityCacheKey, entry availabilityCacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var oldest *availabilityCacheKey
+	for k, e := range c.entries {
+		if entry.fetchedAt.Sub(e.fetchedAt) > availabilityCacheTTL {
+			delete(c.entries, k)
+			continue
+		}
+		if oldest == nil || e.fetchedAt.Before(c.entries[*oldest].fetchedAt) {
+			oldest = &k
+		}
+	}
+
+	_, exists := c.entries[key]
+	if !exists && oldest != nil && len(c.entries) >= availabilityCacheMaxEntries {
+		delete(c.entries, *oldest)
+	}
+	c.entries[key] = entry
+}
+
+// cachedAvailability gets the tour's availability from start to end, inclusive, along with when it
+// was fetched. It uses the snapshot from the last poll when it's fresh and covers the dates, then
+// the cache, and gets it from Ventrata otherwise
+func (a *App) cachedAvailability(ctx context.Context, tour tours.TourDetail, start, end tours.Date) (tours.Availabilities, time.Time, error) {
+	return a.cachedOptionAvailability(ctx, tour, tours.DefaultOptionID, start, end)
+}
+
+// cachedOptionAvailability is like cachedAvailability for one of the tour's options. Polls only get
+// the default option, so the snapshot isn't used for others
+func (a *App) cachedOptionAvailability(ctx context.Context, tour tours.TourDetail, option string, start, end tours.Date) (tours.Availabilities, time.Time, error) {
+	now := time.Now().UTC()
+
+	if option == tours.DefaultOptionID {
+		availability, recordedAt, ok, err := a.snapshotAvailability(ctx, tour, start, end, now)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		if ok {
+			return availability, recordedAt, nil
+		}
+	}
+
+	key := availabilityCacheKey{tour.ProductID, option, start, end}
+	if entry, ok := a.availabilityCache.get(key, now); ok {
+		return entry.availability, entry.fetchedAt, nil
+	}
+
+	availability, err := tour.GetOptionAvailability(ctx, a.accessToken, option, start, end)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("error getting availability: %w", err)
+	}
+
+	a.availabilityCache.set(key, availabilityCacheEntry{availability, now})
+	return availability, now, nil
+}
+
+// snapshotAvailability gets availability from the snapshot from the last poll. It returns false if
+// there isn't a snapshot, or it's too old or doesn't cover the dates
+func (a *App) snapshotAvailability(ctx context.Context, tour tours.TourDetail, start, end tours.Date, now time.Time) (tours.Availabilities, time.Time, bool, error) {
+	snapshot, err := a.sc.GetAvailabilitySnapshot(ctx, tour.ProductID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, time.Time{}, false, nil
+	case err != nil:
+		return nil, time.Time{}, false, fmt.Errorf("error getting availability snapshot: %w", err)
+	case now.Sub(snapshot.RecordedAt) > availabilityCacheTTL:
+		return nil, time.Time{}, false, nil
+	}
+
+	// polls get availability for one year starting on the day of the poll
+	pollStart := tours.DateFromTime(snapshot.RecordedAt)
+	if start.ToTime().Before(pollStart.ToTime()) || end.ToTime().After(pollStart.Add(1, 0, 0).ToTime()) {
+		return nil, time.Time{}, false, nil
+	}
+
+	var availability tours.Availabilities
+	err = json.Unmarshal([]byte(snapshot.RawData), &availability)
+	if err != nil {
+		return nil, time.Time{}, false, fmt.Errorf("error parsing availability snapshot: %w", err)
+	}
+	return availability.Between(start, end), snapshot.RecordedAt, true, nil
+}
@@ -0,0 +1,155 @@
+package app
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"walks-of-italy/tours"
+)
+
+// limitedVacancies is the most vacancies a slot can have to be shown as limited
+const limitedVacancies = 5
+
+// SlotStatus is how bookable a slot or day is for the party size
+type SlotStatus string
+
+const (
+	SlotOpen    SlotStatus = "open"
+	SlotLimited SlotStatus = "limited"
+	SlotSoldOut SlotStatus = "sold_out"
+)
+
+// slotStatus returns open or limited if the slot has room for the party, and sold out otherwise
+func slotStatus(a tours.AvailabilityDetail, party int) SlotStatus {
+	switch {
+	case !a.Available || a.Vacancies < party:
+		return SlotSoldOut
+	case a.Vacancies <= limitedVacancies:
+		return SlotLimited
+	default:
+		return SlotOpen
+	}
+}
+
+type calendarSlot struct {
+	tours.AvailabilityDetail
+	Status SlotStatus
+}
+
+type calendarDay struct {
+	Date time.Time
+	// InMonth is false for days from the previous or next month that fill out the first and last weeks
+	InMonth bool
+	Slots   []calendarSlot
+	// Status is the best status of the day's slots, or empty if there are none
+	Status SlotStatus
+}
+
+type calendarPage struct {
+	Tour     *tours.TourDetail
+	Month    time.Time
+	Party    int
+	Weeks    [][]calendarDay
+	AsOf     time.Time
+	Error    string
+	Previous string
+	Next     string
+}
+
+// calendarWeeks arranges the month's availability into weeks starting on Monday
+func calendarWeeks(month time.Time, availability tours.Availabilities, party int) [][]calendarDay {
+	slots := map[tours.Date][]calendarSlot{}
+	for _, a := range availability {
+		d := tours.DateFromTime(a.LocalDateTimeStart)
+		slots[d] = append(slots[d], calendarSlot{a, slotStatus(a, party)})
+	}
+
+	// back up to the Monday on or before the first of the month
+	offset := (int(month.Weekday()) + 6) % 7
+	day := month.AddDate(0, 0, -offset)
+
+	var weeks [][]calendarDay
+	for day.Month() == month.Month() || len(weeks) == 0 {
+		week := make([]calendarDay, 7)
+		for i := range week {
+			cd := calendarDay{Date: day, InMonth: day.Month() == month.Month()}
+			if cd.InMonth {
+				cd.Slots = slots[tours.DateFromTime(day)]
+			}
+			for _, s := range cd.Slots {
+				if cd.Status == "" || s.Status == SlotOpen || (s.Status == SlotLimited && cd.Status == SlotSoldOut) {
+					cd.Status = s.Status
+				}
+			}
+
+			week[i] = cd
+			day = day.AddDate(0, 0, 1)
+		}
+		weeks = append(weeks, week)
+	}
+
+	return weeks
+}
+
+func calendarLink(td *tours.TourDetail, month time.Time, party int) string {
+	q := url.Values{"month": {month.Format("2006-01")}}
+	if party > 1 {
+		q.Set("party", strconv.Itoa(party))
+	}
+	return "/tours/" + td.GetID() + "/calendar?" + q.Encode()
+}
+
+// TourCalendar shows a month of the tour's availability. Use the month query parameter, like
+// 2025-11, to choose the month and party to only show slots with enough vacancies
+func (a *App) TourCalendar(w http.ResponseWriter, r *http.Request, td *tours.TourDetail) {
+	today := tours.DateFromTime(time.Now())
+	month := tours.NewDate(today.Year, today.Month, 1).ToTime()
+	if m := r.URL.Query().Get("month"); m != "" {
+		parsed, err := time.Parse("2006-01", m)
+		if err != nil {
+			http.Error(w, "invalid month: must be formatted like 2025-11", http.StatusBadRequest)
+			return
+		}
+		month = parsed
+	}
+
+	party := 1
+	if p := r.URL.Query().Get("party"); p != "" {
+		var err error
+		party, err = strconv.Atoi(p)
+		if err != nil || party < 1 {
+			http.Error(w, "invalid party: must be a positive number", http.StatusBadRequest)
+			return
+		}
+	}
+
+	page := calendarPage{
+		Tour:     td,
+		Month:    month,
+		Party:    party,
+		Previous: calendarLink(td, month.AddDate(0, -1, 0), party),
+		Next:     calendarLink(td, month.AddDate(0, 1, 0), party),
+	}
+
+	// past dates aren't available, so only get the rest of the current month
+	start := tours.DateFromTime(month)
+	if start.ToTime().Before(today.ToTime()) {
+		start = today
+	}
+	end := tours.DateFromTime(month.AddDate(0, 1, -1))
+
+	var availability tours.Availabilities
+	if !end.ToTime().Before(start.ToTime()) {
+		var err error
+		availability, page.AsOf, err = a.cachedAvailability(r.Context(), *td, start, end)
+		if err != nil {
+			a.logger.Error("error getting availability", "tour_id", td.ProductID, "err", err)
+			page.Error = "Error getting availability. Try again later."
+		}
+	}
+	page.Weeks = calendarWeeks(month, availability, party)
+
+	a.renderPage(w, http.StatusOK, "calendar", page)
+}
@@ -0,0 +1,141 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"walks-of-italy/storage"
+	"walks-of-italy/storage/db"
+	"walks-of-italy/tours"
+
+	"github.com/google/uuid"
+)
+
+func TestCalendarWeeks(t *testing.T) {
+	slot := func(day, hour, vacancies int) tours.AvailabilityDetail {
+		return tours.AvailabilityDetail{
+			LocalDateTimeStart: time.Date(2025, time.November, day, hour, 0, 0, 0, time.UTC),
+			Available:          vacancies > 0,
+			Vacancies:          vacancies,
+		}
+	}
+	availability := tours.Availabilities{
+		slot(3, 9, 10),
+		slot(3, 14, 0),
+		slot(4, 9, 3),
+		slot(5, 9, 0),
+		slot(6, 9, 1),
+	}
+
+	weeks := calendarWeeks(time.Date(2025, time.November, 1, 0, 0, 0, 0, time.UTC), availability, 2)
+	if len(weeks) != 5 {
+		t.Fatalf("expected 5 weeks, got %d", len(weeks))
+	}
+
+	first := weeks[0][0]
+	if first.Date.Day() != 27 || first.InMonth {
+		t.Errorf("expected first day to be Monday, October 27, got %s", first.Date)
+	}
+	if last := weeks[4][6]; last.Date.Day() != 30 || !last.InMonth {
+		t.Errorf("expected last day to be Sunday, November 30, got %s", last.Date)
+	}
+
+	expected := map[int]SlotStatus{
+		3: SlotOpen,
+		4: SlotLimited,
+		5: SlotSoldOut,
+		// not enough vacancies for the party
+		6: SlotSoldOut,
+		7: "",
+	}
+	for _, day := range weeks[1] {
+		status, ok := expected[day.Date.Day()]
+		if ok && day.Status != status {
+			t.Errorf("expected %q for November %d, got %q", status, day.Date.Day(), day.Status)
+		}
+	}
+	if len(weeks[1][0].Slots) != 2 {
+		t.Errorf("expected 2 slots on November 3, got %d", len(weeks[1][0].Slots))
+	}
+}
+
+func TestCachedAvailability(t *testing.T) {
+	ctx := context.Background()
+
+	sc, err := storage.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sc.Close()
+
+	today := tours.DateFromTime(time.Now().UTC())
+	tour := tours.TourDetail{Name: "Tour", ProductID: uuid.New()}
+	err = sc.Set(ctx, &tour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot := tours.Availabilities{
+		{LocalDateTimeStart: today.Add(0, 0, 1).ToTime().Add(9 * time.Hour), Available: true, Vacancies: 4},
+		{LocalDateTimeStart: today.Add(0, 0, 10).ToTime().Add(9 * time.Hour), Available: true, Vacancies: 4},
+	}
+	rawData, err := json.Marshal(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sc.UpsertAvailabilitySnapshot(ctx, db.UpsertAvailabilitySnapshotParams{TourUuid: tour.ProductID, RawData: string(rawData)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := New("", "", sc, nil)
+
+	// this would fail if it made a request since there is no access token
+	availability, asOf, err := a.cachedAvailability(ctx, tour, today, today.Add(0, 0, 5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(availability) != 1 {
+		t.Errorf("expected 1 slot from the snapshot, got %d", len(availability))
+	}
+	if time.Since(asOf) > time.Minute {
+		t.Errorf("unexpected snapshot time: %s", asOf)
+	}
+
+	key := availabilityCacheKey{tour.ProductID, tours.DefaultOptionID, today.Add(1, 1, 0), today.Add(1, 2, 0)}
+	a.availabilityCache.set(key, availabilityCacheEntry{snapshot, time.Now().UTC()})
+
+	availability, _, err = a.cachedAvailability(ctx, tour, key.start, key.end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(availability) != 2 {
+		t.Errorf("expected 2 slots from the cache, got %d", len(availability))
+	}
+}
+
+func TestAvailabilityCacheLimit(t *testing.T) {
+	now := time.Date(2025, time.May, 1, 9, 0, 0, 0, time.UTC)
+	c := newAvailabilityCache()
+
+	key := func(i int) availabilityCacheKey {
+		return availabilityCacheKey{option: fmt.Sprintf("option-%d", i)}
+	}
+	for i := range availabilityCacheMaxEntries + 10 {
+		c.set(key(i), availabilityCacheEntry{fetchedAt: now.Add(time.Duration(i) * time.Millisecond)})
+	}
+
+	if len(c.entries) != availabilityCacheMaxEntries {
+		t.Errorf("expected %d entries, got %d", availabilityCacheMaxEntries, len(c.entries))
+	}
+	if _, ok := c.get(key(0), now); ok {
+		t.Error("expected the oldest entry to be removed")
+	}
+	if _, ok := c.get(key(availabilityCacheMaxEntries+9), now); !ok {
+		t.Error("expected the newest entry to be kept")
+	}
+}
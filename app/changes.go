@@ -0,0 +1,311 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"walks-of-italy/notify"
+	"walks-of-italy/storage/db"
+	"walks-of-italy/tours"
+
+	"github.com/calvinmclean/babyapi"
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+)
+
+var changeTitles = map[tours.ChangeType]string{
+	tours.ChangeSlotAdded:        "New tour slot added",
+	tours.ChangeSlotRemoved:      "Tour slot removed",
+	tours.ChangeSoldOut:          "Tour slot sold out",
+	tours.ChangeReopened:         "Tour slot re-opened",
+	tours.ChangeVacanciesChanged: "Tour vacancies changed",
+	tours.ChangePriceChanged:     "Tour price changed",
+}
+
+// ChangeRecord is a stored availability change for a tour
+type ChangeRecord struct {
+	ID         int64     `json:"id"`
+	TourID     uuid.UUID `json:"tourId"`
+	RecordedAt time.Time `json:"recordedAt"`
+	tours.Change
+}
+
+// ChangeList is the response for the change history endpoints
+type ChangeList struct {
+	Items []ChangeRecord `json:"items"`
+}
+
+func (*ChangeList) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// updateAvailabilityChanges diffs the availability against the previous snapshot, stores the changes,
+// and replaces the snapshot. There are no changes on the first poll for a tour
+func (a *App) updateAvailabilityChanges(ctx context.Context, tour tours.TourDetail, availability tours.Availabilities, start, end tours.Date) ([]tours.Change, error) {
+	var changes []tours.Change
+
+	snapshot, err := a.sc.GetAvailabilitySnapshot(ctx, tour.ProductID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return nil, fmt.Errorf("error getting availability snapshot: %w", err)
+	default:
+		var previous tours.Availabilities
+		err = json.Unmarshal([]byte(snapshot.RawData), &previous)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing availability snapshot: %w", err)
+		}
+
+		// snapshots from before the window end was stored used the same one year window
+		previousEnd := tours.DateFromTime(snapshot.RecordedAt).Add(1, 0, 0)
+		if snapshot.WindowEnd.Valid {
+			previousEnd = tours.DateFromTime(snapshot.WindowEnd.Time)
+		}
+
+		changes = availability.Diff(previous, start, previousEnd)
+	}
+
+	for _, c := range changes {
+		rawData, err := json.Marshal(c.Availability)
+		if err != nil {
+			return nil, fmt.Errorf("error marshalling availability JSON: %w", err)
+		}
+
+		err = a.sc.AddAvailabilityChange(ctx, db.AddAvailabilityChangeParams{
+			TourUuid:          tour.ProductID,
+			ChangeType:        string(c.Type),
+			Slot:              c.Slot,
+			Vacancies:         int64(c.Vacancies),
+			PreviousVacancies: int64(c.PreviousVacancies),
+			PreviousPrice:     int64(c.PreviousPrice),
+			RawData:           string(rawData),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error storing availability change: %w", err)
+		}
+	}
+
+	availabilityJSON, err := json.Marshal(availability)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling availability JSON: %w", err)
+	}
+
+	err = a.sc.UpsertAvailabilitySnapshot(ctx, db.UpsertAvailabilitySnapshotParams{
+		TourUuid:  tour.ProductID,
+		RawData:   string(availabilityJSON),
+		WindowEnd: sql.NullTime{Time: end.ToTime(), Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error storing availability snapshot: %w", err)
+	}
+
+	return changes, nil
+}
+
+// notifyChanges sends a notification for each change that matches one of the rules. The notification
+// is urgent if any matching rule is urgent
+func (a *App) notifyChanges(ctx context.Context, tour tours.TourDetail, changes []tours.Change, rules []*tours.AlertRule) {
+	for _, c := range changes {
+		matched, urgent := matchRules(rules, tour.ProductID, c.Type)
+		if len(matched) == 0 {
+			continue
+		}
+
+		a.sendTourNotification(ctx, tour, matched, tourNotification(tour, c.Slot, urgent,
+			changeTitles[c.Type],
+			changeMessage(tour, c),
+		))
+	}
+}
+
+// changeMessage describes a change for a notification
+func changeMessage(tour tours.TourDetail, c tours.Change) string {
+	message := fmt.Sprintf("Tour: %s\nDate: %s\n", tour.Name, c.Slot.Format("2006-01-02 15:04"))
+	if c.Type == tours.ChangePriceChanged {
+		return message + fmt.Sprintf("Price: %s (was %s)", c.Availability.AdultPrice(), tours.FormatPrice(c.PreviousPrice))
+	}
+	return message + fmt.Sprintf("Vacancies: %d (was %d)", c.Vacancies, c.PreviousVacancies)
+}
+
+// notifyLastMinute sends a notification for slots that opened up within the tour's last-minute window.
+// These are urgent because they are time-sensitive and the tour already has its own quiet hours.
+// Notifications during the tour's quiet hours are held until they end
+func (a *App) notifyLastMinute(ctx context.Context, tour tours.TourDetail, changes []tours.Change, now time.Time) {
+	for _, c := range tour.LastMinute.Openings(changes, now) {
+		n := tourNotification(tour, c.Slot, true,
+			"Last-minute tour opening",
+			fmt.Sprintf(
+				"Tour: %s\nDate: %s\nVacancies: %d",
+				tour.Name, c.Slot.Format("2006-01-02 15:04"), c.Vacancies,
+			),
+		)
+
+		until, hold := tour.LastMinute.Hold(c, now)
+		if hold {
+			a.logger.Debug("holding last-minute alert until quiet hours end", "tour_id", tour.ProductID, "until", until)
+			a.lastMinuteHold.add(heldNotification{tour, c.Slot, n, until})
+			continue
+		}
+
+		a.sendTourNotification(ctx, tour, nil, n)
+	}
+}
+
+// tourNotification creates a notification about a date for a tour using the tour's notification settings.
+// Emergency priority notifications are always urgent
+func tourNotification(tour tours.TourDetail, date time.Time, urgent bool, title, message string) notify.Notification {
+	n := notify.Notification{
+		Title:     title,
+		Message:   message,
+		Urgent:    urgent || tour.Notifications.Emergency(),
+		Priority:  tour.Notifications.Priority,
+		Sound:     tour.Notifications.Sound,
+		Retry:     tour.Notifications.Retry.Duration,
+		Expire:    tour.Notifications.Expire.Duration,
+		Timestamp: time.Now(),
+	}
+
+	if tour.Link != "" {
+		n.URL = tour.Link
+		n.URLTitle = "Book " + date.Format("Mon, 02 Jan 2006")
+	}
+
+	return n
+}
+
+// matchRules returns the IDs of the rules that match the change
+func matchRules(rules []*tours.AlertRule, tourID uuid.UUID, ct tours.ChangeType) (matched []uuid.UUID, urgent bool) {
+	for _, rule := range rules {
+		if rule.Matches(tourID, ct) {
+			matched = append(matched, rule.ID)
+			urgent = urgent || rule.Urgent
+		}
+	}
+	return matched, urgent
+}
+
+// GetChanges lists recent changes for all tours. It accepts optional tour_id, type, and limit query parameters
+func (a *App) GetChanges(w http.ResponseWriter, r *http.Request) render.Renderer {
+	tourID := uuid.Nil
+	if id := r.URL.Query().Get("tour_id"); id != "" {
+		var err error
+		tourID, err = uuid.Parse(id)
+		if err != nil {
+			return babyapi.ErrInvalidRequest(fmt.Errorf("invalid tour_id: %w", err))
+		}
+	}
+
+	changes, httpErr := a.listChanges(r, tourID)
+	if httpErr != nil {
+		return httpErr
+	}
+
+	return changes
+}
+
+// GetTourChanges lists recent changes for a single tour
+func (a *App) GetTourChanges(w http.ResponseWriter, r *http.Request, td *tours.TourDetail) (render.Renderer, *babyapi.ErrResponse) {
+	changes, httpErr := a.listChanges(r, td.ProductID)
+	if httpErr != nil {
+		return nil, httpErr
+	}
+
+	return changes, nil
+}
+
+func (a *App) listChanges(r *http.Request, tourID uuid.UUID) (*ChangeList, *babyapi.ErrResponse) {
+	limit, httpErr := queryLimit(r)
+	if httpErr != nil {
+		return nil, httpErr
+	}
+
+	changeType := tours.ChangeType(r.URL.Query().Get("type"))
+	changeTypePattern := "%"
+	if changeType != "" {
+		err := changeType.Validate()
+		if err != nil {
+			return nil, babyapi.ErrInvalidRequest(err)
+		}
+		changeTypePattern = string(changeType)
+	}
+
+	var rows []db.AvailabilityChange
+	var err error
+	if tourID == uuid.Nil {
+		rows, err = a.sc.ListAvailabilityChanges(r.Context(), db.ListAvailabilityChangesParams{
+			ChangeType: changeTypePattern,
+			Limit:      int64(limit),
+		})
+	} else {
+		rows, err = a.sc.ListAvailabilityChangesForTour(r.Context(), db.ListAvailabilityChangesForTourParams{
+			TourUuid:   tourID,
+			ChangeType: changeTypePattern,
+			Limit:      int64(limit),
+		})
+	}
+	if err != nil {
+		return nil, babyapi.InternalServerError(fmt.Errorf("error getting changes: %w", err))
+	}
+
+	result := &ChangeList{Items: []ChangeRecord{}}
+	for _, row := range rows {
+		record, err := changeFromDB(row)
+		if err != nil {
+			return nil, babyapi.InternalServerError(err)
+		}
+		result.Items = append(result.Items, record)
+	}
+
+	return result, nil
+}
+
+func changeFromDB(row db.AvailabilityChange) (ChangeRecord, error) {
+	var availability tours.AvailabilityDetail
+	err := json.Unmarshal([]byte(row.RawData), &availability)
+	if err != nil {
+		return ChangeRecord{}, fmt.Errorf("error parsing change data: %w", err)
+	}
+
+	return ChangeRecord{
+		ID:         row.ID,
+		TourID:     row.TourUuid,
+		RecordedAt: row.RecordedAt,
+		Change: tours.Change{
+			Type:              tours.ChangeType(row.ChangeType),
+			Slot:              row.Slot,
+			Vacancies:         int(row.Vacancies),
+			PreviousVacancies: int(row.PreviousVacancies),
+			PreviousPrice:     int(row.PreviousPrice),
+			Availability:      availability,
+		},
+	}, nil
+}
+
+// queryLimit gets the optional limit query parameter for list endpoints. It has to be positive, and
+// larger values are reduced to maxListLimit
+func queryLimit(r *http.Request) (int, *babyapi.ErrResponse) {
+	l := r.URL.Query().Get("limit")
+	if l == "" {
+		return defaultListLimit, nil
+	}
+
+	limit, err := strconv.Atoi(l)
+	if err != nil {
+		return 0, babyapi.ErrInvalidRequest(fmt.Errorf("invalid limit: %w", err))
+	}
+	if limit <= 0 {
+		return 0, babyapi.ErrInvalidRequest(errors.New("invalid limit: must be greater than 0"))
+	}
+	return min(limit, maxListLimit), nil
+}
@@ -0,0 +1,38 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestQueryLimit(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected int
+		err      bool
+	}{
+		{"Default", "", defaultListLimit, false},
+		{"Valid", "?limit=5", 5, false},
+		{"Max", "?limit=100000", maxListLimit, false},
+		{"Zero", "?limit=0", 0, true},
+		{"Negative", "?limit=-1", 0, true},
+		{"NotANumber", "?limit=all", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limit, errResp := queryLimit(httptest.NewRequest(http.MethodGet, "/changes"+tt.query, nil))
+			if (errResp != nil) != tt.err {
+				t.Fatalf("unexpected error: %+v", errResp)
+			}
+			if errResp != nil && errResp.HTTPStatusCode != http.StatusBadRequest {
+				t.Errorf("expected bad request, got %d", errResp.HTTPStatusCode)
+			}
+			if limit != tt.expected {
+				t.Errorf("expected %d, got %d", tt.expected, limit)
+			}
+		})
+	}
+}
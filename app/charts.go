@@ -0,0 +1,217 @@
+package app
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"strings"
+	"time"
+
+	"walks-of-italy/tours"
+)
+
+const (
+	chartWidth  = 800
+	chartHeight = 360
+	chartMargin = 70
+)
+
+type chartPoint struct {
+	X, Y  float64
+	Title string
+}
+
+// chartAxis maps values to pixels and formats tick labels
+type chartAxis struct {
+	Min, Max float64
+	Label    string
+	Format   func(float64) string
+}
+
+// pad widens the axis by 5% on each side, or by the amount if the range is empty, so points aren't
+// drawn on the edges
+func (a chartAxis) pad(empty float64) chartAxis {
+	padding := (a.Max - a.Min) * 0.05
+	if padding == 0 {
+		padding = empty
+	}
+	a.Min -= padding
+	a.Max += padding
+	return a
+}
+
+func (a chartAxis) scale(v, from, to float64) float64 {
+	return from + (v-a.Min)/(a.Max-a.Min)*(to-from)
+}
+
+func timeValue(t time.Time) float64 {
+	return float64(t.Unix())
+}
+
+func formatTimeValue(layout string) func(float64) string {
+	return func(v float64) string {
+		return time.Unix(int64(v), 0).UTC().Format(layout)
+	}
+}
+
+func svgStart(sb *strings.Builder, title string) {
+	fmt.Fprintf(sb, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" width="100%%" font-family="sans-serif" font-size="12" role="img">`, chartWidth, chartHeight)
+	fmt.Fprintf(sb, `<title>%s</title>`, html.EscapeString(title))
+	fmt.Fprintf(sb, `<rect width="%d" height="%d" fill="white"/>`, chartWidth, chartHeight)
+}
+
+func emptyChartSVG(title, message string) string {
+	var sb strings.Builder
+	svgStart(&sb, title)
+	fmt.Fprintf(&sb, `<text x="%d" y="%d" text-anchor="middle" fill="#999">%s</text></svg>`, chartWidth/2, chartHeight/2, html.EscapeString(message))
+	return sb.String()
+}
+
+// scatterSVG draws the points connected by a step line, with 5 ticks on each axis
+func scatterSVG(title string, points []chartPoint, x, y chartAxis) string {
+	if len(points) == 0 {
+		return emptyChartSVG(title, "No releases recorded yet")
+	}
+
+	var sb strings.Builder
+	svgStart(&sb, title)
+
+	left, right := float64(chartMargin), float64(chartWidth-chartMargin/2)
+	top, bottom := float64(chartMargin/2), float64(chartHeight-chartMargin)
+
+	// axes and ticks
+	sb.WriteString(`<g stroke="#999">`)
+	fmt.Fprintf(&sb, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f"/>`, left, bottom, right, bottom)
+	fmt.Fprintf(&sb, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f"/>`, left, top, left, bottom)
+	sb.WriteString(`</g>`)
+
+	const ticks = 5
+	for i := 0; i < ticks; i++ {
+		xv := x.Min + (x.Max-x.Min)*float64(i)/(ticks-1)
+		px := x.scale(xv, left, right)
+		fmt.Fprintf(&sb, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#eee"/>`, px, top, px, bottom)
+		fmt.Fprintf(&sb, `<text x="%.1f" y="%.1f" text-anchor="middle" fill="#666">%s</text>`, px, bottom+18, html.EscapeString(x.Format(xv)))
+
+		yv := y.Min + (y.Max-y.Min)*float64(i)/(ticks-1)
+		py := y.scale(yv, bottom, top)
+		fmt.Fprintf(&sb, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#eee"/>`, left, py, right, py)
+		fmt.Fprintf(&sb, `<text x="%.1f" y="%.1f" text-anchor="end" fill="#666">%s</text>`, left-6, py+4, html.EscapeString(y.Format(yv)))
+	}
+
+	fmt.Fprintf(&sb, `<text x="%.1f" y="%d" text-anchor="middle">%s</text>`, (left+right)/2, chartHeight-20, html.EscapeString(x.Label))
+	fmt.Fprintf(&sb, `<text transform="translate(14 %.1f) rotate(-90)" text-anchor="middle">%s</text>`, (top+bottom)/2, html.EscapeString(y.Label))
+
+	// step line from each point to the next
+	var path strings.Builder
+	for i, p := range points {
+		px, py := x.scale(p.X, left, right), y.scale(p.Y, bottom, top)
+		if i == 0 {
+			fmt.Fprintf(&path, "M%.1f %.1f", px, py)
+			continue
+		}
+		fmt.Fprintf(&path, " H%.1f V%.1f", px, py)
+	}
+	fmt.Fprintf(&sb, `<path d="%s" fill="none" stroke="#1e87f0" stroke-opacity="0.4"/>`, path.String())
+
+	for _, p := range points {
+		fmt.Fprintf(&sb, `<circle cx="%.1f" cy="%.1f" r="4" fill="#1e87f0"><title>%s</title></circle>`,
+			x.scale(p.X, left, right), y.scale(p.Y, bottom, top), html.EscapeString(p.Title))
+	}
+
+	sb.WriteString(`</svg>`)
+	return sb.String()
+}
+
+func releaseTitle(r tours.Release, loc *time.Location) string {
+	return fmt.Sprintf("%s released %s (%d days ahead)",
+		r.Date.Format("Mon, 02 Jan 2006"), r.RecordedAt.In(loc).Format("Mon, 02 Jan 2006 15:04 MST"), r.LeadDays())
+}
+
+// releaseTimelineSVG plots each latest date against when it was recorded
+func releaseTimelineSVG(releases []tours.Release, loc *time.Location) string {
+	var points []chartPoint
+	x := chartAxis{Min: math.Inf(1), Max: math.Inf(-1), Label: "Recorded At", Format: formatTimeValue("02 Jan 06")}
+	y := chartAxis{Min: math.Inf(1), Max: math.Inf(-1), Label: "Latest Tour Date", Format: formatTimeValue("02 Jan 06")}
+	for _, r := range releases {
+		p := chartPoint{X: timeValue(r.RecordedAt), Y: timeValue(r.Date), Title: releaseTitle(r, loc)}
+		points = append(points, p)
+		x.Min, x.Max = min(x.Min, p.X), max(x.Max, p.X)
+		y.Min, y.Max = min(y.Min, p.Y), max(y.Max, p.Y)
+	}
+
+	day := (24 * time.Hour).Seconds()
+	return scatterSVG("Latest tour date by when it was recorded", points, x.pad(day), y.pad(day))
+}
+
+// leadTimeSVG plots how many days ahead each date was when it was released
+func leadTimeSVG(releases []tours.Release, loc *time.Location) string {
+	var points []chartPoint
+	x := chartAxis{Min: math.Inf(1), Max: math.Inf(-1), Label: "Recorded At", Format: formatTimeValue("02 Jan 06")}
+	y := chartAxis{Min: math.Inf(1), Max: math.Inf(-1), Label: "Days Ahead", Format: func(v float64) string { return fmt.Sprintf("%.0f", v) }}
+	for _, r := range releases {
+		if r.First {
+			continue
+		}
+		p := chartPoint{X: timeValue(r.RecordedAt), Y: float64(r.LeadDays()), Title: releaseTitle(r, loc)}
+		points = append(points, p)
+		x.Min, x.Max = min(x.Min, p.X), max(x.Max, p.X)
+		y.Min, y.Max = min(y.Min, p.Y), max(y.Max, p.Y)
+	}
+
+	return scatterSVG("Days ahead of each release", points, x.pad((24 * time.Hour).Seconds()), y.pad(1))
+}
+
+// releaseTimesSVG shows how many releases were recorded at each weekday and hour, with larger circles
+// for more releases
+func releaseTimesSVG(releases []tours.Release, loc *time.Location) string {
+	const title = "Releases by weekday and hour"
+
+	var counts [7][24]int
+	most := 0
+	for _, r := range releases {
+		if r.First {
+			continue
+		}
+		t := r.RecordedAt.In(loc)
+		// start the week on Monday
+		weekday := (int(t.Weekday()) + 6) % 7
+		counts[weekday][t.Hour()]++
+		most = max(most, counts[weekday][t.Hour()])
+	}
+	if most == 0 {
+		return emptyChartSVG(title, "No releases recorded yet")
+	}
+
+	var sb strings.Builder
+	svgStart(&sb, title)
+
+	left, top := float64(chartMargin), float64(chartMargin/2)
+	cellWidth := float64(chartWidth-chartMargin-chartMargin/2) / 24
+	cellHeight := float64(chartHeight-chartMargin-chartMargin/2) / 7
+
+	for hour := 0; hour < 24; hour += 3 {
+		fmt.Fprintf(&sb, `<text x="%.1f" y="%.1f" text-anchor="middle" fill="#666">%02d:00</text>`,
+			left+cellWidth*(float64(hour)+0.5), top+cellHeight*7+18, hour)
+	}
+	fmt.Fprintf(&sb, `<text x="%.1f" y="%d" text-anchor="middle">Hour (%s)</text>`, left+cellWidth*12, chartHeight-20, html.EscapeString(loc.String()))
+
+	for weekday := 0; weekday < 7; weekday++ {
+		cy := top + cellHeight*(float64(weekday)+0.5)
+		name := time.Weekday((weekday + 1) % 7).String()[:3]
+		fmt.Fprintf(&sb, `<text x="%.1f" y="%.1f" text-anchor="end" fill="#666">%s</text>`, left-8, cy+4, name)
+		fmt.Fprintf(&sb, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#eee"/>`, left, cy, left+cellWidth*24, cy)
+
+		for hour := 0; hour < 24; hour++ {
+			count := counts[weekday][hour]
+			if count == 0 {
+				continue
+			}
+			radius := math.Sqrt(float64(count)/float64(most)) * min(cellWidth, cellHeight) / 2
+			fmt.Fprintf(&sb, `<circle cx="%.1f" cy="%.1f" r="%.1f" fill="#1e87f0"><title>%s %02d:00: %d releases</title></circle>`,
+				left+cellWidth*(float64(hour)+0.5), cy, max(radius, 2), name, hour, count)
+		}
+	}
+
+	sb.WriteString(`</svg>`)
+	return sb.String()
+}
@@ -0,0 +1,42 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"walks-of-italy/tours"
+)
+
+// availabilityEvents creates the events for a tour's availability update
+func availabilityEvents(tour tours.TourDetail, update AvailabilityUpdate, now time.Time) []tours.Event {
+	var events []tours.Event
+	if update.Latest != nil {
+		events = append(events, tours.NewLatestDateEvent(tour, *update.Latest, now))
+	}
+	for _, c := range update.Changes {
+		events = append(events, tours.NewChangeEvent(tour, c, now))
+	}
+	return events
+}
+
+// publish sends events to all of the event consumers
+func (a *App) publish(ctx context.Context, events ...tours.Event) {
+	for _, e := range events {
+		err := a.stream.Send(string(e.Type), e)
+		if err != nil {
+			a.logger.Error("error publishing event to stream", "event_id", e.ID, "err", err)
+		}
+
+		err = a.webhooks.Publish(ctx, e)
+		if err != nil {
+			a.logger.Error("error publishing event to webhooks", "event_id", e.ID, "err", err)
+		}
+
+		if a.hooks != nil {
+			err = a.hooks.Publish(ctx, e)
+			if err != nil {
+				a.logger.Error("error publishing event to hooks", "event_id", e.ID, "err", err)
+			}
+		}
+	}
+}
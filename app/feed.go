@@ -0,0 +1,285 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"walks-of-italy/storage/db"
+	"walks-of-italy/tours"
+
+	"github.com/google/uuid"
+)
+
+// feedNamespace is used to create stable entry IDs so feed readers don't show an entry twice
+var feedNamespace = uuid.MustParse("2b6a4f0e-7d0c-4c1e-9a57-6a3f1e8d2c41")
+
+// feedChangeTypes are the stored changes that are included in feeds
+var feedChangeTypes = []tours.ChangeType{tours.ChangeReopened, tours.ChangePriceChanged}
+
+// feedEntry is an event from the stored history of a tour's latest dates and changes
+type feedEntry struct {
+	ID       uuid.UUID
+	Type     tours.EventType
+	Time     time.Time
+	Tour     tours.TourDetail
+	Date     time.Time
+	Title    string
+	Summary  string
+	Calendar string
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomAuthor  `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Href  string `xml:"href,attr"`
+	Rel   string `xml:"rel,attr,omitempty"`
+	Type  string `xml:"type,attr,omitempty"`
+	Title string `xml:"title,attr,omitempty"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomEntry struct {
+	ID        string       `xml:"id"`
+	Title     string       `xml:"title"`
+	Updated   string       `xml:"updated"`
+	Published string       `xml:"published"`
+	Category  atomCategory `xml:"category"`
+	Links     []atomLink   `xml:"link"`
+	Summary   string       `xml:"summary"`
+}
+
+// feedEntries gets the newest entries for the tours from the stored history. If tourID is set, only
+// that tour's changes are read
+func (a *App) feedEntries(ctx context.Context, allTours []*tours.TourDetail, tourID uuid.UUID, limit int) ([]feedEntry, error) {
+	toursByID := map[uuid.UUID]tours.TourDetail{}
+	var entries []feedEntry
+	for _, td := range allTours {
+		toursByID[td.ProductID] = *td
+
+		releases, err := a.sc.Releases(ctx, td.ProductID)
+		if err != nil {
+			return nil, fmt.Errorf("error getting releases: %w", err)
+		}
+		for _, release := range releases {
+			if !release.First {
+				entries = append(entries, releaseEntry(*td, release))
+			}
+		}
+	}
+
+	for _, ct := range feedChangeTypes {
+		var rows []db.AvailabilityChange
+		var err error
+		if tourID == uuid.Nil {
+			rows, err = a.sc.ListAvailabilityChanges(ctx, db.ListAvailabilityChangesParams{
+				ChangeType: string(ct),
+				Limit:      int64(limit),
+			})
+		} else {
+			rows, err = a.sc.ListAvailabilityChangesForTour(ctx, db.ListAvailabilityChangesForTourParams{
+				TourUuid:   tourID,
+				ChangeType: string(ct),
+				Limit:      int64(limit),
+			})
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error getting changes: %w", err)
+		}
+
+		for _, row := range rows {
+			td, ok := toursByID[row.TourUuid]
+			if !ok {
+				continue
+			}
+
+			record, err := changeFromDB(row)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, changeEntry(td, record))
+		}
+	}
+
+	slices.SortStableFunc(entries, func(x, y feedEntry) int {
+		return y.Time.Compare(x.Time)
+	})
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+
+	return entries, nil
+}
+
+func releaseEntry(td tours.TourDetail, release tours.Release) feedEntry {
+	date := tours.DateFromTime(release.Date)
+	return feedEntry{
+		ID:       uuid.NewSHA1(feedNamespace, []byte(td.GetID()+"/new_latest_date/"+date.String())),
+		Type:     tours.EventNewLatestDate,
+		Time:     release.RecordedAt,
+		Tour:     td,
+		Date:     release.Date,
+		Title:    fmt.Sprintf("New latest date for %s: %s", td.Name, release.Date.Format("Mon, 02 Jan 2006")),
+		Summary:  fmt.Sprintf("Dates are available through %s, %d days ahead.", release.Date.Format("Mon, 02 Jan 2006"), release.LeadDays()),
+		Calendar: calendarPath(td, release.Date),
+	}
+}
+
+func changeEntry(td tours.TourDetail, c ChangeRecord) feedEntry {
+	slot := c.Slot.Format("Mon, 02 Jan 2006 15:04")
+	summary := fmt.Sprintf("%s re-opened with %d spots for %s.", slot, c.Vacancies, c.Availability.AdultPrice())
+	if c.Type == tours.ChangePriceChanged {
+		summary = fmt.Sprintf("The price for %s changed from %s to %s.", slot, tours.FormatPrice(c.PreviousPrice), c.Availability.AdultPrice())
+	}
+
+	return feedEntry{
+		ID:       uuid.NewSHA1(feedNamespace, []byte(fmt.Sprintf("change/%d", c.ID))),
+		Type:     tours.EventType(c.Type),
+		Time:     c.RecordedAt,
+		Tour:     td,
+		Date:     c.Slot,
+		Title:    fmt.Sprintf("%s: %s on %s", changeTitles[c.Type], td.Name, slot),
+		Summary:  summary,
+		Calendar: calendarPath(td, c.Slot),
+	}
+}
+
+func calendarPath(td tours.TourDetail, date time.Time) string {
+	return fmt.Sprintf("/tours/%s/calendar?month=%s", td.GetID(), date.Format("2006-01"))
+}
+
+// requestBaseURL is the scheme and host that the request was made to, so feeds can use absolute links
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host
+}
+
+func newAtomFeed(r *http.Request, title, alternate string, entries []feedEntry) atomFeed {
+	base := requestBaseURL(r)
+	self := base + r.URL.Path
+
+	updated := time.Now()
+	if len(entries) > 0 {
+		updated = entries[0].Time
+	}
+
+	feed := atomFeed{
+		ID:      self,
+		Title:   title,
+		Updated: updated.UTC().Format(time.RFC3339),
+		Author:  atomAuthor{Name: "walks-of-italy"},
+		Links: []atomLink{
+			{Href: self, Rel: "self", Type: "application/atom+xml"},
+			{Href: base + alternate, Rel: "alternate", Type: "text/html"},
+		},
+	}
+
+	for _, e := range entries {
+		entry := atomEntry{
+			ID:        "urn:uuid:" + e.ID.String(),
+			Title:     e.Title,
+			Updated:   e.Time.UTC().Format(time.RFC3339),
+			Published: e.Time.UTC().Format(time.RFC3339),
+			Category:  atomCategory{Term: string(e.Type)},
+			Links: []atomLink{
+				{Href: base + e.Calendar, Rel: "related", Type: "text/html", Title: "Calendar"},
+			},
+			Summary: e.Summary,
+		}
+		if e.Tour.Link != "" {
+			entry.Links = append([]atomLink{{Href: e.Tour.Link, Rel: "alternate", Type: "text/html", Title: "Tour"}}, entry.Links...)
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+
+	return feed
+}
+
+func (a *App) writeFeed(w http.ResponseWriter, feed atomFeed) {
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	err := enc.Encode(feed)
+	if err != nil {
+		a.logger.Error("error encoding feed", "err", err)
+		http.Error(w, "error encoding feed", http.StatusInternalServerError)
+		return
+	}
+
+	_, err = w.Write(buf.Bytes())
+	if err != nil {
+		a.logger.Error("error writing feed", "err", err)
+	}
+}
+
+// GetFeed is an Atom feed of new latest dates, re-opened slots, and price changes for all tours
+func (a *App) GetFeed(w http.ResponseWriter, r *http.Request) {
+	limit, httpErr := queryLimit(r)
+	if httpErr != nil {
+		http.Error(w, httpErr.ErrorText, httpErr.HTTPStatusCode)
+		return
+	}
+
+	allTours, err := a.sc.GetAll(r.Context(), url.Values{})
+	if err != nil {
+		a.logger.Error("error getting tours", "err", err)
+		http.Error(w, "error getting tours", http.StatusInternalServerError)
+		return
+	}
+
+	entries, err := a.feedEntries(r.Context(), allTours, uuid.Nil, limit)
+	if err != nil {
+		a.logger.Error("error getting feed entries", "err", err)
+		http.Error(w, "error getting feed entries", http.StatusInternalServerError)
+		return
+	}
+
+	a.writeFeed(w, newAtomFeed(r, "Walks of Italy", "/tours/summary", entries))
+}
+
+// GetTourFeed is an Atom feed of new latest dates, re-opened slots, and price changes for a tour
+func (a *App) GetTourFeed(w http.ResponseWriter, r *http.Request, td *tours.TourDetail) {
+	limit, httpErr := queryLimit(r)
+	if httpErr != nil {
+		http.Error(w, httpErr.ErrorText, httpErr.HTTPStatusCode)
+		return
+	}
+
+	entries, err := a.feedEntries(r.Context(), []*tours.TourDetail{td}, td.ProductID, limit)
+	if err != nil {
+		a.logger.Error("error getting feed entries", "tour_id", td.ProductID, "err", err)
+		http.Error(w, "error getting feed entries", http.StatusInternalServerError)
+		return
+	}
+
+	a.writeFeed(w, newAtomFeed(r, td.Name, calendarPath(*td, time.Now()), entries))
+}
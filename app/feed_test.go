@@ -0,0 +1,147 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"walks-of-italy/storage"
+	"walks-of-italy/storage/db"
+	"walks-of-italy/tours"
+
+	"github.com/google/uuid"
+)
+
+func TestGetFeed(t *testing.T) {
+	ctx := context.Background()
+
+	sc, err := storage.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sc.Close()
+
+	vatican := tours.TourDetail{Name: "Vatican", Link: "https://example.com/vatican", ProductID: uuid.New()}
+	colosseum := tours.TourDetail{Name: "Colosseum", ProductID: uuid.New()}
+	for _, td := range []*tours.TourDetail{&vatican, &colosseum} {
+		err = sc.Set(ctx, td)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the first date isn't a release, so this is one new latest date
+	for _, d := range []time.Time{time.Now().AddDate(0, 3, 0), time.Now().AddDate(0, 4, 0)} {
+		err = sc.AddLatestAvailability(ctx, db.AddLatestAvailabilityParams{
+			TourUuid:         vatican.ProductID,
+			AvailabilityDate: tours.DateFromTime(d).ToTime(),
+			RawData:          "[]",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	slot := time.Date(2025, time.November, 3, 9, 0, 0, 0, time.UTC)
+	rawData, err := json.Marshal(tours.AvailabilityDetail{
+		LocalDateTimeStart: slot,
+		Available:          true,
+		Vacancies:          2,
+		UnitPricing:        []tours.UnitPricing{{UnitType: "ADULT", Retail: 10900}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := []db.AddAvailabilityChangeParams{
+		{TourUuid: vatican.ProductID, ChangeType: string(tours.ChangeReopened), Vacancies: 2},
+		{TourUuid: colosseum.ProductID, ChangeType: string(tours.ChangePriceChanged), Vacancies: 2, PreviousVacancies: 2, PreviousPrice: 9900},
+		// not included in feeds
+		{TourUuid: vatican.ProductID, ChangeType: string(tours.ChangeSoldOut)},
+	}
+	for _, c := range changes {
+		c.Slot = slot
+		c.RawData = string(rawData)
+		err = sc.AddAvailabilityChange(ctx, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a := New("", "", sc, nil)
+
+	getFeed := func(t *testing.T, handler http.HandlerFunc, path string) atomFeed {
+		t.Helper()
+
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/atom+xml") {
+			t.Fatalf("unexpected response %d %q: %s", w.Code, w.Header().Get("Content-Type"), w.Body.String())
+		}
+
+		var feed atomFeed
+		err := xml.Unmarshal(w.Body.Bytes(), &feed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return feed
+	}
+
+	t.Run("AllTours", func(t *testing.T) {
+		feed := getFeed(t, a.GetFeed, "/feed.atom")
+		if len(feed.Entries) != 3 {
+			t.Fatalf("expected 3 entries, got %d", len(feed.Entries))
+		}
+
+		terms := map[string]atomEntry{}
+		for _, e := range feed.Entries {
+			terms[e.Category.Term] = e
+		}
+		for _, et := range []tours.EventType{tours.EventNewLatestDate, tours.EventType(tours.ChangeReopened), tours.EventType(tours.ChangePriceChanged)} {
+			if _, ok := terms[string(et)]; !ok {
+				t.Errorf("missing %s entry", et)
+			}
+		}
+
+		price := terms[string(tours.ChangePriceChanged)]
+		if !strings.Contains(price.Summary, "from $99.00 to $109.00") {
+			t.Errorf("unexpected summary: %q", price.Summary)
+		}
+		if len(price.Links) != 1 || price.Links[0].Href != "http://example.com/tours/"+colosseum.GetID()+"/calendar?month=2025-11" {
+			t.Errorf("unexpected links: %+v", price.Links)
+		}
+
+		reopened := terms[string(tours.ChangeReopened)]
+		if len(reopened.Links) != 2 || reopened.Links[0].Href != "https://example.com/vatican" {
+			t.Errorf("unexpected links: %+v", reopened.Links)
+		}
+
+		again := getFeed(t, a.GetFeed, "/feed.atom")
+		for i := range feed.Entries {
+			if feed.Entries[i].ID != again.Entries[i].ID {
+				t.Errorf("expected stable IDs, got %q and %q", feed.Entries[i].ID, again.Entries[i].ID)
+			}
+		}
+	})
+
+	t.Run("Tour", func(t *testing.T) {
+		feed := getFeed(t, func(w http.ResponseWriter, r *http.Request) {
+			a.GetTourFeed(w, r, &vatican)
+		}, "/tours/"+vatican.GetID()+"/feed.atom")
+		if len(feed.Entries) != 2 || feed.Title != vatican.Name {
+			t.Errorf("unexpected feed: %+v", feed)
+		}
+	})
+
+	t.Run("Limit", func(t *testing.T) {
+		feed := getFeed(t, a.GetFeed, "/feed.atom?limit=1")
+		if len(feed.Entries) != 1 {
+			t.Errorf("expected 1 entry, got %d", len(feed.Entries))
+		}
+	})
+}
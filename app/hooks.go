@@ -0,0 +1,173 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"walks-of-italy/tours"
+)
+
+const (
+	defaultHookTimeout     = 30 * time.Second
+	defaultHookConcurrency = 4
+	maxHookOutput          = 4096
+)
+
+// Hook is a local command that runs when an event occurs. The command is run with "sh -c" and receives
+// the event JSON on stdin
+type Hook struct {
+	EventType tours.EventType
+	Command   string
+}
+
+// ParseHook parses a hook in the format "event_type=command"
+func ParseHook(s string) (Hook, error) {
+	eventType, command, ok := strings.Cut(s, "=")
+	if !ok || strings.TrimSpace(command) == "" {
+		return Hook{}, fmt.Errorf("invalid hook %q: expected format event_type=command", s)
+	}
+
+	h := Hook{EventType: tours.EventType(strings.TrimSpace(eventType)), Command: command}
+	err := h.EventType.Validate()
+	if err != nil {
+		return Hook{}, fmt.Errorf("invalid hook %q: %w", s, err)
+	}
+
+	return h, nil
+}
+
+type HookConfig struct {
+	Hooks []Hook
+	// Timeout is the maximum time a hook can run before it is killed
+	Timeout time.Duration
+	// Concurrency is the maximum number of hooks that run at the same time
+	Concurrency int
+}
+
+// HookRunner runs the configured hooks for each event in the background
+type HookRunner struct {
+	config HookConfig
+	sem    chan struct{}
+	wg     sync.WaitGroup
+	logger *slog.Logger
+}
+
+func NewHookRunner(config HookConfig, logger *slog.Logger) *HookRunner {
+	if config.Timeout <= 0 {
+		config.Timeout = defaultHookTimeout
+	}
+	if config.Concurrency <= 0 {
+		config.Concurrency = defaultHookConcurrency
+	}
+
+	return &HookRunner{
+		config: config,
+		sem:    make(chan struct{}, config.Concurrency),
+		logger: logger,
+	}
+}
+
+// Publish starts each hook that matches the event. Hooks wait for a free slot if the concurrency
+// limit is reached, so this does not block the watch loop
+func (hr *HookRunner) Publish(ctx context.Context, e tours.Event) error {
+	input, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("error marshalling event: %w", err)
+	}
+	input = append(input, '\n')
+
+	for _, h := range hr.config.Hooks {
+		if h.EventType != e.Type {
+			continue
+		}
+
+		hr.wg.Add(1)
+		go func() {
+			defer hr.wg.Done()
+
+			select {
+			case hr.sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-hr.sem }()
+
+			hr.run(ctx, h, e, input)
+		}()
+	}
+
+	return nil
+}
+
+// Wait blocks until all running hooks finish
+func (hr *HookRunner) Wait() {
+	hr.wg.Wait()
+}
+
+func (hr *HookRunner) run(ctx context.Context, h Hook, e tours.Event, input []byte) {
+	ctx, cancel := context.WithTimeout(ctx, hr.config.Timeout)
+	defer cancel()
+
+	logger := hr.logger.With("hook", h.Command, "event_type", e.Type, "event_id", e.ID)
+
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", h.Command)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	cmd.WaitDelay = time.Second
+	cmd.Env = append(os.Environ(), hookEnv(e)...)
+
+	start := time.Now()
+	err := cmd.Run()
+	logger = logger.With("duration", time.Since(start).String(), "output", truncateOutput(output.String()))
+
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		logger.Error("hook timed out", "timeout", hr.config.Timeout.String())
+	case err != nil:
+		logger.Error("error running hook", "err", err)
+	default:
+		logger.Info("ran hook")
+	}
+}
+
+// hookEnv creates the environment variables describing the event
+func hookEnv(e tours.Event) []string {
+	env := []string{
+		"EVENT_ID=" + e.ID.String(),
+		"EVENT_TYPE=" + string(e.Type),
+		"TOUR_ID=" + e.TourID.String(),
+		"TOUR_NAME=" + e.TourName,
+	}
+	if e.Date != nil {
+		env = append(env,
+			"DATE="+e.Date.Format(time.DateOnly),
+			"DATETIME="+e.Date.Format(time.RFC3339),
+		)
+	}
+	if e.Change != nil {
+		env = append(env, fmt.Sprintf("VACANCIES=%d", e.Change.Vacancies))
+	}
+	if e.Error != "" {
+		env = append(env, "ERROR="+e.Error)
+	}
+	return env
+}
+
+func truncateOutput(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) <= maxHookOutput {
+		return s
+	}
+	return s[:maxHookOutput] + "..."
+}
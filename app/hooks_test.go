@@ -0,0 +1,89 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"walks-of-italy/tours"
+
+	"github.com/google/uuid"
+)
+
+func TestParseHook(t *testing.T) {
+	h, err := ParseHook("reopened=./notify.sh --flag=value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.EventType != tours.EventType(tours.ChangeReopened) || h.Command != "./notify.sh --flag=value" {
+		t.Errorf("unexpected hook: %+v", h)
+	}
+
+	for _, invalid := range []string{"reopened", "reopened=", "unknown=./notify.sh"} {
+		_, err = ParseHook(invalid)
+		if err == nil {
+			t.Errorf("expected error for %q", invalid)
+		}
+	}
+}
+
+func TestHookRunner(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "output")
+
+	hr := NewHookRunner(HookConfig{
+		Hooks: []Hook{
+			{EventType: tours.EventNewLatestDate, Command: `cat > ` + output + ` && echo "$TOUR_ID $DATE" >> ` + output},
+			{EventType: tours.EventNewLatestDate, Command: "sleep 5"},
+			{EventType: tours.EventPollFailed, Command: "touch " + filepath.Join(dir, "failed")},
+		},
+		Timeout: 100 * time.Millisecond,
+	}, slog.Default())
+
+	tour := tours.TourDetail{Name: "Tour", ProductID: uuid.New()}
+	date := time.Date(2025, time.May, 1, 9, 0, 0, 0, time.UTC)
+	e := tours.NewLatestDateEvent(tour, tours.AvailabilityDetail{LocalDateTimeStart: date}, time.Now())
+
+	start := time.Now()
+	err := hr.Publish(context.Background(), e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hr.Wait()
+
+	if time.Since(start) > 3*time.Second {
+		t.Errorf("expected slow hook to time out")
+	}
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.SplitN(strings.TrimSpace(string(data)), "\n", 2)
+	if len(lines) != 2 {
+		t.Fatalf("unexpected output: %q", data)
+	}
+
+	var received tours.Event
+	err = json.Unmarshal([]byte(lines[0]), &received)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if received.ID != e.ID {
+		t.Errorf("unexpected event: %+v", received)
+	}
+	if lines[1] != tour.ProductID.String()+" 2025-05-01" {
+		t.Errorf("unexpected environment: %q", lines[1])
+	}
+
+	_, err = os.Stat(filepath.Join(dir, "failed"))
+	if err == nil {
+		t.Errorf("expected poll_failed hook to not run")
+	}
+}
@@ -0,0 +1,258 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"walks-of-italy/tours"
+)
+
+const (
+	icalDateTime = "20060102T150405Z"
+	icalDate     = "20060102"
+	// icalLineLength is the most octets allowed on a line before it has to be folded
+	icalLineLength = 75
+)
+
+// icalEvent is a VEVENT in a calendar feed. AllDay events only use the date of Start
+type icalEvent struct {
+	UID         string
+	Start, End  time.Time
+	AllDay      bool
+	Summary     string
+	Location    string
+	Description string
+	URL         string
+}
+
+// icalEscape escapes text values as described in RFC 5545 section 3.3.11
+var icalEscape = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+// icalWriter writes content lines with CRLF line endings, folding lines that are too long
+type icalWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (iw *icalWriter) line(name, value string) {
+	if iw.err != nil {
+		return
+	}
+
+	line := name + ":" + value
+	var sb strings.Builder
+	length := 0
+	for _, r := range line {
+		size := len(string(r))
+		// continuation lines start with a space, which counts toward the length
+		if length+size > icalLineLength {
+			sb.WriteString("\r\n ")
+			length = 1
+		}
+		sb.WriteRune(r)
+		length += size
+	}
+	sb.WriteString("\r\n")
+
+	_, iw.err = io.WriteString(iw.w, sb.String())
+}
+
+func (iw *icalWriter) text(name, value string) {
+	if value != "" {
+		iw.line(name, icalEscape.Replace(value))
+	}
+}
+
+// writeCalendar writes an RFC 5545 calendar with the events
+func writeCalendar(w io.Writer, name string, events []icalEvent, now time.Time) error {
+	iw := &icalWriter{w: w}
+	iw.line("BEGIN", "VCALENDAR")
+	iw.line("VERSION", "2.0")
+	iw.line("PRODID", "-//walks-of-italy//Availability//EN")
+	iw.line("CALSCALE", "GREGORIAN")
+	iw.line("METHOD", "PUBLISH")
+	iw.text("X-WR-CALNAME", name)
+
+	for _, e := range events {
+		iw.line("BEGIN", "VEVENT")
+		iw.line("UID", e.UID)
+		iw.line("DTSTAMP", now.UTC().Format(icalDateTime))
+		if e.AllDay {
+			start := tours.DateFromTime(e.Start)
+			iw.line("DTSTART;VALUE=DATE", start.ToTime().Format(icalDate))
+			iw.line("DTEND;VALUE=DATE", start.Add(0, 0, 1).ToTime().Format(icalDate))
+		} else {
+			iw.line("DTSTART", e.Start.UTC().Format(icalDateTime))
+			iw.line("DTEND", e.End.UTC().Format(icalDateTime))
+		}
+		iw.text("SUMMARY", e.Summary)
+		iw.text("LOCATION", e.Location)
+		iw.text("DESCRIPTION", e.Description)
+		if e.URL != "" {
+			iw.line("URL", e.URL)
+		}
+		iw.line("TRANSP", "TRANSPARENT")
+		iw.line("END", "VEVENT")
+	}
+
+	iw.line("END", "VCALENDAR")
+	return iw.err
+}
+
+// slotEvents creates an event for each available slot
+func slotEvents(td tours.TourDetail, availability tours.Availabilities) []icalEvent {
+	var events []icalEvent
+	for _, a := range availability {
+		if !a.Available || a.Vacancies < 1 {
+			continue
+		}
+
+		end := a.LocalDateTimeEnd
+		if end.IsZero() || !end.After(a.LocalDateTimeStart) {
+			end = a.LocalDateTimeStart.Add(time.Hour)
+		}
+
+		description := fmt.Sprintf("Price: %s\nVacancies: %d", a.AdultPrice(), a.Vacancies)
+		if td.Link != "" {
+			description += "\nBook: " + td.Link
+		}
+
+		events = append(events, icalEvent{
+			UID:         fmt.Sprintf("%s-%d@walks-of-italy", td.ProductID, a.LocalDateTimeStart.Unix()),
+			Start:       a.LocalDateTimeStart,
+			End:         end,
+			Summary:     td.Name,
+			Location:    a.MeetingPoint,
+			Description: description,
+			URL:         td.Link,
+		})
+	}
+	return events
+}
+
+// furthestDateEvent creates an all-day event on the tour's furthest released date
+func furthestDateEvent(td tours.TourDetail, date, recordedAt time.Time) icalEvent {
+	description := "Furthest date released " + recordedAt.Format("Mon, 02 Jan 2006 15:04 MST")
+	if td.Link != "" {
+		description += "\nBook: " + td.Link
+	}
+
+	return icalEvent{
+		UID:         fmt.Sprintf("%s-furthest@walks-of-italy", td.ProductID),
+		Start:       date,
+		AllDay:      true,
+		Summary:     "Furthest date: " + td.Name,
+		Description: description,
+		URL:         td.Link,
+	}
+}
+
+// tourCalendarEvents gets the events for a tour's feed. When furthest is true, it's only an all-day
+// event on the furthest released date, or nothing if there isn't one yet
+func (a *App) tourCalendarEvents(ctx context.Context, td tours.TourDetail, furthest bool) ([]icalEvent, error) {
+	if furthest {
+		latest, err := a.sc.GetLatestAvailability(ctx, td.ProductID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error getting latest availability: %w", err)
+		}
+		return []icalEvent{furthestDateEvent(td, latest.AvailabilityDate, latest.RecordedAt)}, nil
+	}
+
+	// this is the same range as polling so the latest snapshot can be used
+	start := tours.DateFromTime(time.Now().UTC())
+	availability, _, err := a.cachedAvailability(ctx, td, start, start.Add(1, 0, 0))
+	if err != nil {
+		return nil, err
+	}
+	return slotEvents(td, availability), nil
+}
+
+func parseFurthest(q url.Values) (bool, error) {
+	furthest := q.Get("furthest")
+	if furthest == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(furthest)
+}
+
+func writeCalendarResponse(w http.ResponseWriter, filename, name string, events []icalEvent) error {
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", filename))
+	return writeCalendar(w, name, events, time.Now())
+}
+
+// GetTourCalendarFeed is an iCalendar feed of the tour's available slots. Use furthest=true to only
+// get an all-day event on the furthest released date
+func (a *App) GetTourCalendarFeed(w http.ResponseWriter, r *http.Request, td *tours.TourDetail) {
+	furthest, err := parseFurthest(r.URL.Query())
+	if err != nil {
+		http.Error(w, "invalid furthest: must be true or false", http.StatusBadRequest)
+		return
+	}
+
+	events, err := a.tourCalendarEvents(r.Context(), *td, furthest)
+	if err != nil {
+		a.logger.Error("error getting calendar events", "tour_id", td.ProductID, "err", err)
+		http.Error(w, "error getting availability", http.StatusBadGateway)
+		return
+	}
+
+	err = writeCalendarResponse(w, td.GetID()+".ics", td.Name, events)
+	if err != nil {
+		a.logger.Error("error writing calendar", "tour_id", td.ProductID, "err", err)
+	}
+}
+
+// GetCalendarFeed is an iCalendar feed of available slots for all unpaused tours, or only tours with
+// the tag.
+// Use furthest=true to only get an all-day event on each tour's furthest released date
+func (a *App) GetCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	furthest, err := parseFurthest(r.URL.Query())
+	if err != nil {
+		http.Error(w, "invalid furthest: must be true or false", http.StatusBadRequest)
+		return
+	}
+	tag := r.URL.Query().Get("tag")
+
+	allTours, err := a.sc.GetAll(r.Context(), url.Values{})
+	if err != nil {
+		a.logger.Error("error getting tours", "err", err)
+		http.Error(w, "error getting tours", http.StatusInternalServerError)
+		return
+	}
+
+	var events []icalEvent
+	for _, td := range allTours {
+		if td.Paused || (tag != "" && !td.HasTag(tag)) {
+			continue
+		}
+
+		tourEvents, err := a.tourCalendarEvents(r.Context(), *td, furthest)
+		if err != nil {
+			// skip the tour so one failure doesn't break the whole feed
+			a.logger.Error("error getting calendar events", "tour_id", td.ProductID, "err", err)
+			continue
+		}
+		events = append(events, tourEvents...)
+	}
+
+	name := "Walks of Italy"
+	if tag != "" {
+		name += ": " + tag
+	}
+	err = writeCalendarResponse(w, "calendar.ics", name, events)
+	if err != nil {
+		a.logger.Error("error writing calendar", "err", err)
+	}
+}
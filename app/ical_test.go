@@ -0,0 +1,181 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"walks-of-italy/storage"
+	"walks-of-italy/storage/db"
+	"walks-of-italy/tours"
+
+	"github.com/google/uuid"
+)
+
+func TestWriteCalendar(t *testing.T) {
+	now := time.Date(2025, time.November, 1, 12, 0, 0, 0, time.UTC)
+	rome := time.FixedZone("CET", 3600)
+	events := []icalEvent{
+		{
+			UID:         "slot@walks-of-italy",
+			Start:       time.Date(2025, time.November, 3, 9, 0, 0, 0, rome),
+			End:         time.Date(2025, time.November, 3, 12, 0, 0, 0, rome),
+			Summary:     "Vatican, Sistine Chapel; and St. Peter's",
+			Location:    strings.Repeat("Meeting point ", 10),
+			Description: "Price: $99.00\nVacancies: 4",
+		},
+		{
+			UID:     "furthest@walks-of-italy",
+			Start:   time.Date(2026, time.February, 28, 0, 0, 0, 0, time.UTC),
+			AllDay:  true,
+			Summary: "Furthest date",
+		},
+	}
+
+	var sb strings.Builder
+	err := writeCalendar(&sb, "Rome", events, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := sb.String()
+
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+		if len(line) > icalLineLength {
+			t.Errorf("line is longer than %d octets: %q", icalLineLength, line)
+		}
+	}
+
+	expected := []string{
+		"BEGIN:VCALENDAR\r\n",
+		"X-WR-CALNAME:Rome\r\n",
+		"DTSTAMP:20251101T120000Z\r\n",
+		"DTSTART:20251103T080000Z\r\n",
+		"DTEND:20251103T110000Z\r\n",
+		`SUMMARY:Vatican\, Sistine Chapel\; and St. Peter's` + "\r\n",
+		`DESCRIPTION:Price: $99.00\nVacancies: 4` + "\r\n",
+		"DTSTART;VALUE=DATE:20260228\r\n",
+		"DTEND;VALUE=DATE:20260301\r\n",
+		"END:VCALENDAR\r\n",
+	}
+	for _, e := range expected {
+		if !strings.Contains(out, e) {
+			t.Errorf("expected calendar to contain %q:\n%s", e, out)
+		}
+	}
+
+	unfolded := strings.ReplaceAll(out, "\r\n ", "")
+	if !strings.Contains(unfolded, "LOCATION:"+strings.Repeat("Meeting point ", 10)) {
+		t.Errorf("expected unfolded location:\n%s", unfolded)
+	}
+}
+
+func TestSlotEvents(t *testing.T) {
+	start := time.Date(2025, time.November, 3, 9, 0, 0, 0, time.UTC)
+	availability := tours.Availabilities{
+		{
+			LocalDateTimeStart: start,
+			LocalDateTimeEnd:   start.Add(3 * time.Hour),
+			Available:          true,
+			Vacancies:          4,
+			MeetingPoint:       "Piazza Risorgimento",
+			UnitPricing:        []tours.UnitPricing{{UnitType: "ADULT", Retail: 9900}},
+		},
+		{LocalDateTimeStart: start.Add(24 * time.Hour), Available: false},
+		{LocalDateTimeStart: start.Add(48 * time.Hour), Available: true, Vacancies: 0},
+		// missing end time
+		{LocalDateTimeStart: start.Add(72 * time.Hour), Available: true, Vacancies: 1},
+	}
+
+	td := tours.TourDetail{Name: "Vatican", Link: "https://example.com/vatican", ProductID: uuid.New()}
+	events := slotEvents(td, availability)
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+
+	e := events[0]
+	if e.Location != "Piazza Risorgimento" || e.URL != td.Link || !e.End.Equal(start.Add(3*time.Hour)) {
+		t.Errorf("unexpected event: %+v", e)
+	}
+	if !strings.Contains(e.Description, "$99.00") || !strings.Contains(e.Description, td.Link) {
+		t.Errorf("unexpected description: %q", e.Description)
+	}
+	if !events[1].End.Equal(events[1].Start.Add(time.Hour)) {
+		t.Errorf("expected default end time, got %s", events[1].End)
+	}
+}
+
+func TestGetCalendarFeed(t *testing.T) {
+	ctx := context.Background()
+
+	sc, err := storage.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sc.Close()
+
+	start := time.Now().UTC().AddDate(0, 0, 7).Truncate(time.Hour)
+	rawData, err := json.Marshal(tours.Availabilities{
+		{LocalDateTimeStart: start, LocalDateTimeEnd: start.Add(time.Hour), Available: true, Vacancies: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rome := tours.TourDetail{Name: "Colosseum", ProductID: uuid.New(), Tags: []string{"Rome"}}
+	florence := tours.TourDetail{Name: "Uffizi", ProductID: uuid.New(), Tags: []string{"Florence"}}
+	for _, td := range []*tours.TourDetail{&rome, &florence} {
+		err = sc.Set(ctx, td)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = sc.UpsertAvailabilitySnapshot(ctx, db.UpsertAvailabilitySnapshotParams{TourUuid: td.ProductID, RawData: string(rawData)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a := New("", "", sc, nil)
+
+	t.Run("FilterByTag", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		a.GetCalendarFeed(w, httptest.NewRequest(http.MethodGet, "/calendar.ics?tag=rome", nil))
+		if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/calendar") {
+			t.Fatalf("unexpected response %d %q", w.Code, w.Header().Get("Content-Type"))
+		}
+		body := w.Body.String()
+		if !strings.Contains(body, "SUMMARY:Colosseum") || strings.Contains(body, "Uffizi") {
+			t.Errorf("expected only the Rome tour:\n%s", body)
+		}
+	})
+
+	t.Run("Furthest", func(t *testing.T) {
+		err = sc.AddLatestAvailability(ctx, db.AddLatestAvailabilityParams{
+			TourUuid:         rome.ProductID,
+			AvailabilityDate: tours.DateFromTime(start.AddDate(0, 3, 0)).ToTime(),
+			RawData:          "[]",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w := httptest.NewRecorder()
+		a.GetTourCalendarFeed(w, httptest.NewRequest(http.MethodGet, "/tours/"+rome.GetID()+"/calendar.ics?furthest=true", nil), &rome)
+		body := w.Body.String()
+		if strings.Count(body, "BEGIN:VEVENT") != 1 || !strings.Contains(body, "DTSTART;VALUE=DATE:") {
+			t.Errorf("expected one all-day event:\n%s", body)
+		}
+	})
+
+	t.Run("InvalidFurthest", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		a.GetCalendarFeed(w, httptest.NewRequest(http.MethodGet, "/calendar.ics?furthest=maybe", nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+}
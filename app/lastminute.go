@@ -0,0 +1,74 @@
+package app
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"walks-of-italy/notify"
+	"walks-of-italy/tours"
+)
+
+// heldNotification is a last-minute alert that's waiting for the tour's quiet hours to end
+type heldNotification struct {
+	tour         tours.TourDetail
+	slot         time.Time
+	notification notify.Notification
+	until        time.Time
+}
+
+// notificationHold keeps last-minute alerts until their quiet hours end. Held alerts are only kept in
+// memory, like notifications held by the Pipeline
+type notificationHold struct {
+	lock sync.Mutex
+	held []heldNotification
+}
+
+func (h *notificationHold) add(n heldNotification) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.held = append(h.held, n)
+}
+
+// due removes and returns the alerts that are no longer held
+func (h *notificationHold) due(now time.Time) []heldNotification {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	var result, remaining []heldNotification
+	for _, n := range h.held {
+		if n.until.After(now) {
+			remaining = append(remaining, n)
+			continue
+		}
+		result = append(result, n)
+	}
+	h.held = remaining
+	return result
+}
+
+// runHeldNotifications sends held last-minute alerts when their quiet hours end. Alerts for slots
+// that already started are dropped
+func (a *App) runHeldNotifications(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case t := <-ticker.C:
+			a.sendHeldNotifications(ctx, t)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (a *App) sendHeldNotifications(ctx context.Context, now time.Time) {
+	for _, n := range a.lastMinuteHold.due(now) {
+		if n.slot.Before(now) {
+			a.logger.Debug("dropping held last-minute alert for past slot", "tour_id", n.tour.ProductID, "slot", n.slot)
+			continue
+		}
+		a.sendTourNotification(ctx, n.tour, nil, n.notification)
+	}
+}
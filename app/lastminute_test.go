@@ -0,0 +1,61 @@
+package app
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"walks-of-italy/storage"
+	"walks-of-italy/tours"
+
+	"github.com/google/uuid"
+)
+
+func TestNotifyLastMinuteQuietHours(t *testing.T) {
+	ctx := context.Background()
+
+	sc, err := storage.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sc.Close()
+
+	nc := &recordingNotifier{}
+	a := New("", "", sc, nc)
+
+	now := time.Date(2025, time.May, 1, 23, 0, 0, 0, time.UTC)
+	tour := tours.TourDetail{
+		Name:      "Colosseum",
+		ProductID: uuid.New(),
+		LastMinute: tours.LastMinuteSettings{
+			Window:     tours.Duration{Duration: 72 * time.Hour},
+			QuietHours: tours.QuietHours{Start: 22 * 60, End: 7 * 60},
+		},
+	}
+	changes := []tours.Change{
+		{Type: tours.ChangeReopened, Slot: now.Add(12 * time.Hour), Vacancies: 2, Availability: tours.AvailabilityDetail{Available: true}},
+		{Type: tours.ChangeReopened, Slot: now.Add(4 * time.Hour), Vacancies: 2, Availability: tours.AvailabilityDetail{Available: true}},
+	}
+
+	a.notifyLastMinute(ctx, tour, changes, now)
+	if len(nc.sent) != 0 {
+		t.Fatalf("expected notifications to be held during quiet hours: %+v", nc.sent)
+	}
+
+	a.sendHeldNotifications(ctx, now.Add(time.Hour))
+	if len(nc.sent) != 0 {
+		t.Fatalf("expected notifications to be held until quiet hours end: %+v", nc.sent)
+	}
+
+	// the slot that started during quiet hours is dropped
+	a.sendHeldNotifications(ctx, now.Add(8*time.Hour))
+	if len(nc.sent) != 1 || !nc.sent[0].Urgent {
+		t.Fatalf("expected one urgent notification after quiet hours: %+v", nc.sent)
+	}
+
+	a.sendHeldNotifications(ctx, now.Add(9*time.Hour))
+	if len(nc.sent) != 1 {
+		t.Errorf("expected held notifications to only be sent once: %+v", nc.sent)
+	}
+}
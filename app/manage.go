@@ -0,0 +1,349 @@
+package app
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"walks-of-italy/tours"
+
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+)
+
+// testAvailabilityDays is how far ahead the "test availability" button checks
+const testAvailabilityDays = 14
+
+// tourForm has the form values for creating or editing a tour. Values are kept as strings so
+// invalid input can be shown again along with the errors
+type tourForm struct {
+	Name         string
+	Link         string
+	ApiUrl       string
+	ProductID    string
+	Paused       bool
+	Tags         string
+	Window       string
+	MinVacancies string
+	QuietHours   string
+	Priority     string
+	Sound        string
+	Retry        string
+	Expire       string
+
+	// Errors has a message for each invalid field
+	Errors map[string]string
+}
+
+func newTourForm(td *tours.TourDetail) tourForm {
+	f := tourForm{
+		Name:         td.Name,
+		Link:         td.Link,
+		ApiUrl:       td.ApiUrl,
+		Paused:       td.Paused,
+		Tags:         strings.Join(td.Tags, ", "),
+		MinVacancies: strconv.Itoa(td.LastMinute.MinVacancies),
+		Priority:     strconv.Itoa(td.Notifications.Priority),
+		Sound:        td.Notifications.Sound,
+	}
+	if td.ProductID != uuid.Nil {
+		f.ProductID = td.ProductID.String()
+	}
+	if td.LastMinute.Window.Duration > 0 {
+		f.Window = td.LastMinute.Window.String()
+	}
+	if td.LastMinute.QuietHours.Enabled() {
+		f.QuietHours = td.LastMinute.QuietHours.String()
+	}
+	if td.Notifications.Retry.Duration > 0 {
+		f.Retry = td.Notifications.Retry.String()
+	}
+	if td.Notifications.Expire.Duration > 0 {
+		f.Expire = td.Notifications.Expire.String()
+	}
+	return f
+}
+
+func parseTourForm(r *http.Request) tourForm {
+	value := func(key string) string {
+		return strings.TrimSpace(r.PostFormValue(key))
+	}
+	return tourForm{
+		Name:         value("name"),
+		Link:         value("link"),
+		ApiUrl:       value("api_url"),
+		ProductID:    value("product_id"),
+		Paused:       r.PostFormValue("paused") == "on",
+		Tags:         value("tags"),
+		Window:       value("window"),
+		MinVacancies: value("min_vacancies"),
+		QuietHours:   value("quiet_hours"),
+		Priority:     value("priority"),
+		Sound:        value("sound"),
+		Retry:        value("retry"),
+		Expire:       value("expire"),
+	}
+}
+
+// TourDetail validates the form and creates a TourDetail from it. Errors are added to the form
+func (f *tourForm) TourDetail() (*tours.TourDetail, bool) {
+	f.Errors = map[string]string{}
+	td := &tours.TourDetail{Name: f.Name, Link: f.Link, ApiUrl: f.ApiUrl, Paused: f.Paused, Tags: tours.ParseTags(f.Tags)}
+
+	if f.Name == "" {
+		f.Errors["name"] = "Name is required"
+	}
+
+	var err error
+	td.ProductID, err = uuid.Parse(f.ProductID)
+	if err != nil {
+		f.Errors["product_id"] = "Product ID must be a UUID"
+	}
+
+	for field, value := range map[string]string{"link": f.Link, "api_url": f.ApiUrl} {
+		if value == "" {
+			continue
+		}
+		u, err := url.Parse(value)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			f.Errors[field] = "Must be an http or https URL"
+		}
+	}
+
+	parseDuration := func(field, value string, dest *tours.Duration) {
+		err := dest.UnmarshalText([]byte(value))
+		if err != nil {
+			f.Errors[field] = "Must be a duration like 72h or 30m"
+		}
+	}
+	parseInt := func(field, value string, dest *int) {
+		if value == "" {
+			return
+		}
+		var err error
+		*dest, err = strconv.Atoi(value)
+		if err != nil {
+			f.Errors[field] = "Must be a whole number"
+		}
+	}
+
+	parseDuration("window", f.Window, &td.LastMinute.Window)
+	parseInt("min_vacancies", f.MinVacancies, &td.LastMinute.MinVacancies)
+	err = td.LastMinute.QuietHours.UnmarshalText([]byte(f.QuietHours))
+	if err != nil {
+		f.Errors["quiet_hours"] = "Must be a range like 22:00-07:00"
+	}
+
+	td.Notifications.Sound = f.Sound
+	parseInt("priority", f.Priority, &td.Notifications.Priority)
+	parseDuration("retry", f.Retry, &td.Notifications.Retry)
+	parseDuration("expire", f.Expire, &td.Notifications.Expire)
+
+	// only check settings that are otherwise valid so the error messages aren't confusing
+	if f.Errors["window"] == "" && f.Errors["min_vacancies"] == "" {
+		err = td.LastMinute.Validate()
+		if err != nil {
+			f.Errors["last_minute"] = err.Error()
+		}
+	}
+	if f.Errors["priority"] == "" && f.Errors["retry"] == "" && f.Errors["expire"] == "" {
+		err = td.Notifications.Validate()
+		if err != nil {
+			f.Errors["notifications"] = err.Error()
+		}
+	}
+
+	return td, len(f.Errors) == 0
+}
+
+type manageTour struct {
+	*tours.TourDetail
+	LatestDate time.Time
+}
+
+type manageToursPage struct {
+	Tours   []manageTour
+	Message string
+	Error   string
+}
+
+type tourFormPage struct {
+	Form tourForm
+	// Edit is true when editing an existing tour, so the product ID can't be changed
+	Edit bool
+}
+
+type tourTestPage struct {
+	Tour         *tours.TourDetail
+	Start, End   time.Time
+	Availability tours.Availabilities
+	Error        string
+}
+
+// tourPage gets the requested tour and calls the handler. Unlike GetRequestedResourceAndDo, it
+// doesn't write a response after the handler
+func (a *App) tourPage(do func(http.ResponseWriter, *http.Request, *tours.TourDetail)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		td, httpErr := a.api.GetRequestedResource(r)
+		if httpErr != nil {
+			_ = render.Render(w, r, httpErr)
+			return
+		}
+		do(w, r, td)
+	}
+}
+
+// ManageTours lists tours with links to add, edit, pause, and delete them
+func (a *App) ManageTours(w http.ResponseWriter, r *http.Request) {
+	allTours, err := a.sc.GetAll(r.Context(), url.Values{})
+	if err != nil {
+		a.logger.Error("error getting tours", "err", err)
+		http.Error(w, "error getting tours", http.StatusInternalServerError)
+		return
+	}
+
+	latest, err := a.sc.GetAllLatestAvailabilities(r.Context())
+	if err != nil {
+		a.logger.Error("error getting latest availabilities", "err", err)
+		http.Error(w, "error getting latest availabilities", http.StatusInternalServerError)
+		return
+	}
+
+	latestDates := map[uuid.UUID]time.Time{}
+	for _, la := range latest {
+		latestDates[la.Uuid] = la.AvailabilityDate
+	}
+
+	page := manageToursPage{
+		Message: r.URL.Query().Get("message"),
+		Error:   r.URL.Query().Get("error"),
+	}
+	for _, td := range allTours {
+		page.Tours = append(page.Tours, manageTour{TourDetail: td, LatestDate: latestDates[td.ProductID]})
+	}
+
+	a.renderPage(w, http.StatusOK, "manage_tours", page)
+}
+
+func redirectToManage(w http.ResponseWriter, r *http.Request, key, message string) {
+	http.Redirect(w, r, "/tours/manage?"+url.Values{key: {message}}.Encode(), http.StatusSeeOther)
+}
+
+// NewTourForm shows the form for adding a tour
+func (a *App) NewTourForm(w http.ResponseWriter, r *http.Request) {
+	a.renderPage(w, http.StatusOK, "tour_form", tourFormPage{Form: newTourForm(&tours.TourDetail{})})
+}
+
+// CreateTour adds a tour from the form
+func (a *App) CreateTour(w http.ResponseWriter, r *http.Request) {
+	form := parseTourForm(r)
+	td, ok := form.TourDetail()
+	if ok {
+		_, err := a.sc.Get(r.Context(), td.GetID())
+		switch {
+		case err == nil:
+			form.Errors["product_id"] = "A tour with this product ID already exists"
+			ok = false
+		case !errors.Is(err, sql.ErrNoRows):
+			a.logger.Error("error getting tour", "err", err)
+			http.Error(w, "error getting tour", http.StatusInternalServerError)
+			return
+		}
+	}
+	if !ok {
+		a.renderPage(w, http.StatusBadRequest, "tour_form", tourFormPage{Form: form})
+		return
+	}
+
+	a.saveTour(w, r, td, fmt.Sprintf("Added %s", td.Name))
+}
+
+// EditTourForm shows the form for editing a tour
+func (a *App) EditTourForm(w http.ResponseWriter, r *http.Request, td *tours.TourDetail) {
+	a.renderPage(w, http.StatusOK, "tour_form", tourFormPage{Form: newTourForm(td), Edit: true})
+}
+
+// UpdateTour updates the tour from the form. The product ID can't be changed
+func (a *App) UpdateTour(w http.ResponseWriter, r *http.Request, existing *tours.TourDetail) {
+	form := parseTourForm(r)
+	form.ProductID = existing.GetID()
+
+	td, ok := form.TourDetail()
+	if !ok {
+		a.renderPage(w, http.StatusBadRequest, "tour_form", tourFormPage{Form: form, Edit: true})
+		return
+	}
+
+	a.saveTour(w, r, td, fmt.Sprintf("Updated %s", td.Name))
+}
+
+func (a *App) saveTour(w http.ResponseWriter, r *http.Request, td *tours.TourDetail, message string) {
+	err := a.sc.Set(r.Context(), td)
+	if err != nil {
+		a.logger.Error("error storing tour", "tour_id", td.ProductID, "err", err)
+		redirectToManage(w, r, "error", fmt.Sprintf("Error saving %s", td.Name))
+		return
+	}
+
+	if !td.Paused {
+		a.updateSavedTour(td)
+	}
+
+	redirectToManage(w, r, "message", message)
+}
+
+// PauseTour pauses or resumes polling for a tour using the paused form value
+func (a *App) PauseTour(w http.ResponseWriter, r *http.Request, td *tours.TourDetail) {
+	td.Paused = r.PostFormValue("paused") == "true"
+
+	err := a.sc.Set(r.Context(), td)
+	if err != nil {
+		a.logger.Error("error storing tour", "tour_id", td.ProductID, "err", err)
+		redirectToManage(w, r, "error", fmt.Sprintf("Error updating %s", td.Name))
+		return
+	}
+	a.status.setPaused(*td)
+
+	message := fmt.Sprintf("Resumed %s", td.Name)
+	if td.Paused {
+		message = fmt.Sprintf("Paused %s", td.Name)
+	}
+	redirectToManage(w, r, "message", message)
+}
+
+// DeleteTour deletes the tour. It uses POST so it works from a form
+func (a *App) DeleteTour(w http.ResponseWriter, r *http.Request, td *tours.TourDetail) {
+	err := a.sc.Delete(r.Context(), td.GetID())
+	if err != nil {
+		a.logger.Error("error deleting tour", "tour_id", td.ProductID, "err", err)
+		redirectToManage(w, r, "error", fmt.Sprintf("Error deleting %s", td.Name))
+		return
+	}
+	a.status.remove(td.ProductID)
+
+	redirectToManage(w, r, "message", fmt.Sprintf("Deleted %s", td.Name))
+}
+
+// TestTourAvailability fetches the tour's availability for the next few days without storing it, so
+// the tour's settings can be checked
+func (a *App) TestTourAvailability(w http.ResponseWriter, r *http.Request, td *tours.TourDetail) {
+	start := tours.DateFromTime(time.Now())
+	end := start.Add(0, 0, testAvailabilityDays)
+
+	page := tourTestPage{Tour: td, Start: start.ToTime(), End: end.ToTime()}
+	status := http.StatusOK
+
+	availability, err := td.GetAvailability(r.Context(), a.accessToken, start, end)
+	if err != nil {
+		page.Error = err.Error()
+		status = http.StatusBadGateway
+	}
+	page.Availability = availability
+
+	a.renderPage(w, status, "tour_test", page)
+}
@@ -0,0 +1,124 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"walks-of-italy/storage"
+)
+
+func TestTourForm(t *testing.T) {
+	valid := tourForm{
+		Name:         "Tour",
+		ProductID:    "e9d2d819-5f04-4b1f-a07f-612387494b8f",
+		Link:         "https://www.walksofitaly.com/tour",
+		Window:       "72h",
+		MinVacancies: "2",
+		QuietHours:   "22:00-07:00",
+		Priority:     "2",
+		Retry:        "2m",
+	}
+
+	tests := []struct {
+		name           string
+		update         func(*tourForm)
+		expectedErrors []string
+	}{
+		{"Valid", func(*tourForm) {}, nil},
+		{"MissingName", func(f *tourForm) { f.Name = "" }, []string{"name"}},
+		{"InvalidProductID", func(f *tourForm) { f.ProductID = "abc" }, []string{"product_id"}},
+		{"InvalidLink", func(f *tourForm) { f.Link = "walksofitaly.com" }, []string{"link"}},
+		{"InvalidWindow", func(f *tourForm) { f.Window = "3 days" }, []string{"window"}},
+		{"NegativeVacancies", func(f *tourForm) { f.MinVacancies = "-1" }, []string{"last_minute"}},
+		{"InvalidQuietHours", func(f *tourForm) { f.QuietHours = "night" }, []string{"quiet_hours"}},
+		{"RetryWithoutEmergency", func(f *tourForm) { f.Priority = "1" }, []string{"notifications"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := valid
+			tt.update(&f)
+
+			td, ok := f.TourDetail()
+			if ok != (len(tt.expectedErrors) == 0) {
+				t.Errorf("unexpected result %t with errors: %v", ok, f.Errors)
+			}
+			if len(f.Errors) != len(tt.expectedErrors) {
+				t.Errorf("expected errors for %v, got %v", tt.expectedErrors, f.Errors)
+			}
+			for _, field := range tt.expectedErrors {
+				if f.Errors[field] == "" {
+					t.Errorf("expected error for %q, got %v", field, f.Errors)
+				}
+			}
+
+			if ok && td.LastMinute.Window.Duration != 72*time.Hour {
+				t.Errorf("unexpected window: %s", td.LastMinute.Window)
+			}
+		})
+	}
+}
+
+func TestCreateTour(t *testing.T) {
+	sc, err := storage.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sc.Close()
+
+	a := New("", "", sc, nil)
+
+	create := func(form url.Values) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/tours/new", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		a.CreateTour(w, r)
+		return w
+	}
+
+	// paused so it isn't polled after it's created
+	form := url.Values{
+		"name":       {"Tour"},
+		"product_id": {"e9d2d819-5f04-4b1f-a07f-612387494b8f"},
+		"paused":     {"on"},
+	}
+
+	w := create(form)
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("expected redirect, got %d: %s", w.Code, w.Body.String())
+	}
+
+	td, err := sc.Get(context.Background(), "e9d2d819-5f04-4b1f-a07f-612387494b8f")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if td.Name != "Tour" || !td.Paused {
+		t.Errorf("unexpected tour: %+v", td)
+	}
+
+	w = create(form)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected duplicate to fail, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "already exists") {
+		t.Errorf("expected duplicate error in form")
+	}
+
+	// the poll is queued for the watch loop instead of running in the request
+	w = create(url.Values{"name": {"Other"}, "product_id": {"1b5c4a9e-8f0a-4c52-9a49-2f4e3e1f6a10"}})
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("expected redirect, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(a.savedTours) != 1 {
+		t.Fatalf("expected the new tour to be queued for polling, got %d", len(a.savedTours))
+	}
+	if queued := <-a.savedTours; queued.Name != "Other" {
+		t.Errorf("unexpected queued tour: %+v", queued)
+	}
+}
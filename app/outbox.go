@@ -0,0 +1,434 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"walks-of-italy/notify"
+	"walks-of-italy/storage"
+	"walks-of-italy/storage/db"
+
+	"github.com/calvinmclean/babyapi"
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+)
+
+const (
+	NotificationPending  = "pending"
+	NotificationSent     = "sent"
+	NotificationFailed   = "failed"
+	NotificationCanceled = "canceled"
+
+	outboxRetryInterval = 30 * time.Second
+	outboxMaxBackoff    = time.Hour
+	outboxMaxAttempts   = 10
+)
+
+// Outbox is a Notifier that stores each notification before sending it to each backend in the
+// next Notifier. Each backend's delivery is stored separately so only failed backends are retried,
+// with exponential backoff, until they are delivered or fail after outboxMaxAttempts.
+// Notifications for a user are stored with the user's ID and sent using recipients
+type Outbox struct {
+	sc         *storage.Client
+	next       notify.Notifier
+	recipients func(context.Context, uuid.UUID) (notify.Notifier, error)
+	logger     *slog.Logger
+	now        func() time.Time
+}
+
+var _ notify.Notifier = &Outbox{}
+
+func NewOutbox(sc *storage.Client, next notify.Notifier, logger *slog.Logger) *Outbox {
+	return &Outbox{
+		sc:     sc,
+		next:   next,
+		logger: logger,
+		now:    func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Send stores the notification and attempts to deliver it. Delivery errors are only logged since
+// the notification will be retried. An error is returned if the notification could not be stored
+func (o *Outbox) Send(ctx context.Context, n notify.Notification) error {
+	return o.send(ctx, uuid.Nil, n)
+}
+
+// ForUser returns a Notifier that stores notifications for the user so they are retried and kept
+// in the history like the server's notifications
+func (o *Outbox) ForUser(userID uuid.UUID) notify.Notifier {
+	return outboxRecipient{o, userID}
+}
+
+type outboxRecipient struct {
+	outbox *Outbox
+	userID uuid.UUID
+}
+
+func (r outboxRecipient) Send(ctx context.Context, n notify.Notification) error {
+	return r.outbox.send(ctx, r.userID, n)
+}
+
+func (o *Outbox) send(ctx context.Context, userID uuid.UUID, n notify.Notification) error {
+	rawData, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("error marshalling notification: %w", err)
+	}
+
+	now := o.now()
+	stored, err := o.sc.AddNotification(ctx, db.AddNotificationParams{
+		Title:     n.Title,
+		Message:   n.Message,
+		RawData:   string(rawData),
+		Status:    NotificationPending,
+		CreatedAt: now,
+		// This is delayed so Run doesn't attempt it at the same time
+		NextAttemptAt: now.Add(backoff(1)),
+		UserUuid:      userID,
+	})
+	if err != nil {
+		return fmt.Errorf("error storing notification: %w", err)
+	}
+
+	o.deliver(ctx, stored, n)
+	return nil
+}
+
+// Run retries pending notifications until the context is cancelled
+func (o *Outbox) Run(ctx context.Context) {
+	ticker := time.NewTicker(outboxRetryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			err := o.retry(ctx)
+			if err != nil {
+				o.logger.Error("error retrying notifications", "err", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (o *Outbox) retry(ctx context.Context) error {
+	due, err := o.sc.ListDueNotifications(ctx, o.now())
+	if err != nil {
+		return fmt.Errorf("error getting pending notifications: %w", err)
+	}
+
+	for _, stored := range due {
+		var n notify.Notification
+		err = json.Unmarshal([]byte(stored.RawData), &n)
+		if err != nil {
+			return fmt.Errorf("error parsing notification %d: %w", stored.ID, err)
+		}
+
+		o.deliver(ctx, stored, n)
+	}
+
+	return nil
+}
+
+// backends gets the backends for the stored notification's recipient. It returns none if the
+// recipient no longer has any notifiers
+func (o *Outbox) backends(ctx context.Context, stored db.Notification) ([]notify.Backend, error) {
+	next := o.next
+	if stored.UserUuid != uuid.Nil {
+		if o.recipients == nil {
+			return nil, nil
+		}
+
+		var err error
+		next, err = o.recipients(ctx, stored.UserUuid)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return notify.Backends(next), nil
+}
+
+// deliver sends the notification to each backend that hasn't received it yet and records the
+// attempts. Deliveries to backends that are no longer configured are canceled
+func (o *Outbox) deliver(ctx context.Context, stored db.Notification, n notify.Notification) {
+	logger := o.logger.With("notification_id", stored.ID)
+	if stored.UserUuid != uuid.Nil {
+		logger = logger.With("user_id", stored.UserUuid)
+	}
+
+	now := o.now()
+	backends, err := o.backends(ctx, stored)
+	if err != nil {
+		logger.Error("error getting notifiers, will retry", "err", err)
+		o.reschedule(ctx, stored, now.Add(outboxRetryInterval), logger)
+		return
+	}
+
+	deliveries, err := o.sc.ListNotificationDeliveries(ctx, stored.ID)
+	if err != nil {
+		logger.Error("error getting deliveries, will retry", "err", err)
+		o.reschedule(ctx, stored, now.Add(outboxRetryInterval), logger)
+		return
+	}
+
+	active := map[string]notify.Backend{}
+	for _, b := range backends {
+		active[b.Name] = b
+		if slices.ContainsFunc(deliveries, func(d db.NotificationDelivery) bool { return d.Notifier == b.Name }) {
+			continue
+		}
+
+		d, err := o.sc.AddNotificationDelivery(ctx, db.AddNotificationDeliveryParams{
+			NotificationID: stored.ID,
+			Notifier:       b.Name,
+			Status:         NotificationPending,
+			NextAttemptAt:  now,
+		})
+		if err != nil {
+			logger.Error("error storing delivery", "notifier", b.Name, "err", err)
+			continue
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	for i, d := range deliveries {
+		if d.Status != NotificationPending || d.NextAttemptAt.After(now) {
+			continue
+		}
+
+		b, ok := active[d.Notifier]
+		if ok {
+			d = o.attempt(ctx, b, d, n, logger)
+		} else {
+			d.Status = NotificationCanceled
+			d.LastError = "notifier is no longer configured"
+		}
+		deliveries[i] = d
+
+		err = o.sc.UpdateNotificationDelivery(ctx, db.UpdateNotificationDeliveryParams{
+			ID:            d.ID,
+			Status:        d.Status,
+			Attempts:      d.Attempts,
+			LastError:     d.LastError,
+			NextAttemptAt: d.NextAttemptAt,
+			SentAt:        d.SentAt,
+		})
+		if err != nil {
+			logger.Error("error updating delivery", "notifier", d.Notifier, "err", err)
+		}
+	}
+
+	err = o.sc.UpdateNotificationAttempt(ctx, summarizeDeliveries(stored, deliveries))
+	if err != nil {
+		logger.Error("error updating notification", "err", err)
+	}
+}
+
+// attempt sends the notification to one backend. The delivery fails once it reaches outboxMaxAttempts
+func (o *Outbox) attempt(ctx context.Context, b notify.Backend, d db.NotificationDelivery, n notify.Notification, logger *slog.Logger) db.NotificationDelivery {
+	d.Attempts++
+	logger = logger.With("notifier", b.Name, "attempts", d.Attempts)
+
+	err := b.Send(ctx, n)
+	switch {
+	case err == nil:
+		d.Status = NotificationSent
+		d.SentAt = sql.NullTime{Time: o.now(), Valid: true}
+	case d.Attempts >= outboxMaxAttempts:
+		logger.Error("error sending notification, giving up", "err", err)
+		d.Status = NotificationFailed
+		d.LastError = err.Error()
+	default:
+		logger.Error("error sending notification, will retry", "err", err)
+		d.LastError = err.Error()
+		d.NextAttemptAt = o.now().Add(backoff(int(d.Attempts)))
+	}
+
+	return d
+}
+
+// reschedule keeps the notification pending without recording an attempt, like when its
+// recipient's settings can't be loaded
+func (o *Outbox) reschedule(ctx context.Context, stored db.Notification, next time.Time, logger *slog.Logger) {
+	err := o.sc.UpdateNotificationAttempt(ctx, db.UpdateNotificationAttemptParams{
+		ID:            stored.ID,
+		Status:        NotificationPending,
+		Attempts:      stored.Attempts,
+		LastError:     stored.LastError,
+		NextAttemptAt: next,
+		SentAt:        stored.SentAt,
+	})
+	if err != nil {
+		logger.Error("error updating notification", "err", err)
+	}
+}
+
+// summarizeDeliveries sets the notification's status from its deliveries. It is pending until every
+// backend is done, and failed if any backend failed. The next attempt is the earliest pending one
+func summarizeDeliveries(stored db.Notification, deliveries []db.NotificationDelivery) db.UpdateNotificationAttemptParams {
+	params := db.UpdateNotificationAttemptParams{
+		ID:            stored.ID,
+		Status:        NotificationCanceled,
+		NextAttemptAt: stored.NextAttemptAt,
+	}
+	if len(deliveries) == 0 {
+		params.LastError = "recipient has no notifiers"
+		return params
+	}
+
+	statuses := map[string]bool{}
+	var errs []string
+	var nextAttempt time.Time
+	for _, d := range deliveries {
+		statuses[d.Status] = true
+		params.Attempts = max(params.Attempts, d.Attempts)
+		if d.LastError != "" {
+			errs = append(errs, d.Notifier+": "+d.LastError)
+		}
+		if d.Status == NotificationPending && (nextAttempt.IsZero() || d.NextAttemptAt.Before(nextAttempt)) {
+			nextAttempt = d.NextAttemptAt
+		}
+		if d.SentAt.Valid && (!params.SentAt.Valid || d.SentAt.Time.After(params.SentAt.Time)) {
+			params.SentAt = d.SentAt
+		}
+	}
+	params.LastError = strings.Join(errs, "; ")
+
+	switch {
+	case statuses[NotificationPending]:
+		params.Status = NotificationPending
+		params.NextAttemptAt = nextAttempt
+		params.SentAt = sql.NullTime{}
+	case statuses[NotificationFailed]:
+		params.Status = NotificationFailed
+	case statuses[NotificationSent]:
+		params.Status = NotificationSent
+	}
+
+	return params
+}
+
+// backoff doubles the retry interval after each attempt, up to a maximum
+func backoff(attempts int) time.Duration {
+	delay := outboxRetryInterval
+	for i := 1; i < attempts && delay < outboxMaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, outboxMaxBackoff)
+}
+
+// NotificationRecord is a notification from the outbox history
+type NotificationRecord struct {
+	ID            int64      `json:"id"`
+	UserID        *uuid.UUID `json:"userId,omitempty"`
+	Title         string     `json:"title"`
+	Message       string     `json:"message"`
+	Urgent        bool       `json:"urgent"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"lastError,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	NextAttemptAt *time.Time `json:"nextAttemptAt,omitempty"`
+	SentAt        *time.Time `json:"sentAt,omitempty"`
+
+	Deliveries []NotificationDeliveryRecord `json:"deliveries"`
+}
+
+// NotificationDeliveryRecord is the delivery of a notification to one backend
+type NotificationDeliveryRecord struct {
+	Notifier      string     `json:"notifier"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"lastError,omitempty"`
+	NextAttemptAt *time.Time `json:"nextAttemptAt,omitempty"`
+	SentAt        *time.Time `json:"sentAt,omitempty"`
+}
+
+// NotificationList is the response for the notification history
+type NotificationList struct {
+	Items []NotificationRecord `json:"items"`
+}
+
+func (*NotificationList) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// GetNotifications lists the most recent notifications, or renders a page for browsers. It accepts an
+// optional limit query parameter
+func (a *App) GetNotifications(w http.ResponseWriter, r *http.Request) render.Renderer {
+	limit, httpErr := queryLimit(r)
+	if httpErr != nil {
+		return httpErr
+	}
+
+	rows, err := a.sc.ListNotifications(r.Context(), int64(limit))
+	if err != nil {
+		return babyapi.InternalServerError(fmt.Errorf("error getting notifications: %w", err))
+	}
+
+	result := &NotificationList{Items: []NotificationRecord{}}
+	for _, row := range rows {
+		var n notify.Notification
+		err = json.Unmarshal([]byte(row.RawData), &n)
+		if err != nil {
+			return babyapi.InternalServerError(fmt.Errorf("error parsing notification %d: %w", row.ID, err))
+		}
+
+		record := NotificationRecord{
+			ID:         row.ID,
+			Title:      row.Title,
+			Message:    row.Message,
+			Urgent:     n.Urgent,
+			Status:     row.Status,
+			Attempts:   int(row.Attempts),
+			LastError:  row.LastError,
+			CreatedAt:  row.CreatedAt,
+			Deliveries: []NotificationDeliveryRecord{},
+		}
+		if row.UserUuid != uuid.Nil {
+			record.UserID = &row.UserUuid
+		}
+		if row.Status == NotificationPending {
+			record.NextAttemptAt = &row.NextAttemptAt
+		}
+		if row.SentAt.Valid {
+			record.SentAt = &row.SentAt.Time
+		}
+
+		deliveries, err := a.sc.ListNotificationDeliveries(r.Context(), row.ID)
+		if err != nil {
+			return babyapi.InternalServerError(fmt.Errorf("error getting deliveries for notification %d: %w", row.ID, err))
+		}
+		for _, d := range deliveries {
+			delivery := NotificationDeliveryRecord{
+				Notifier:  d.Notifier,
+				Status:    d.Status,
+				Attempts:  int(d.Attempts),
+				LastError: d.LastError,
+			}
+			if d.Status == NotificationPending {
+				delivery.NextAttemptAt = &d.NextAttemptAt
+			}
+			if d.SentAt.Valid {
+				delivery.SentAt = &d.SentAt.Time
+			}
+			record.Deliveries = append(record.Deliveries, delivery)
+		}
+
+		result.Items = append(result.Items, record)
+	}
+
+	if render.GetAcceptedContentType(r) == render.ContentTypeHTML {
+		a.renderPage(w, http.StatusOK, "notifications", result)
+		return nil
+	}
+
+	return result
+}
@@ -0,0 +1,191 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"walks-of-italy/notify"
+	"walks-of-italy/storage"
+
+	"github.com/calvinmclean/babyapi"
+)
+
+type flakyNotifier struct {
+	failures int
+	recordingNotifier
+}
+
+func (f *flakyNotifier) Send(ctx context.Context, n notify.Notification) error {
+	if f.failures > 0 {
+		f.failures--
+		return errors.New("unavailable")
+	}
+	return f.recordingNotifier.Send(ctx, n)
+}
+
+func TestOutbox(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, time.May, 1, 3, 0, 0, 0, time.UTC)
+
+	sc, err := storage.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sc.Close()
+
+	next := &flakyNotifier{failures: 2}
+	o := NewOutbox(sc, next, slog.Default())
+	o.now = func() time.Time { return now }
+
+	err = o.Send(ctx, notify.Notification{Title: "Tour slot re-opened", Message: "Tour: A", Urgent: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// First retry is not due until after the backoff
+	now = now.Add(backoff(1) - time.Second)
+	err = o.retry(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.failures != 1 {
+		t.Fatalf("expected no retry before backoff, failures remaining: %d", next.failures)
+	}
+
+	for _, d := range []time.Duration{backoff(1), backoff(2)} {
+		now = now.Add(d)
+		err = o.retry(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(next.sent) != 1 || !next.sent[0].Urgent {
+		t.Fatalf("expected notification to be delivered once, got %+v", next.sent)
+	}
+
+	stored, err := sc.ListNotifications(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 1 {
+		t.Fatalf("expected 1 notification, got %d", len(stored))
+	}
+	if stored[0].Status != NotificationSent || stored[0].Attempts != 3 || stored[0].LastError != "notifier: unavailable" || !stored[0].SentAt.Valid {
+		t.Errorf("unexpected notification: %+v", stored[0])
+	}
+}
+
+func TestOutboxBackends(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, time.May, 1, 3, 0, 0, 0, time.UTC)
+
+	sc, err := storage.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sc.Close()
+
+	healthy := &recordingNotifier{}
+	broken := &flakyNotifier{failures: outboxMaxAttempts + 1}
+	o := NewOutbox(sc, notify.Multi{healthy, broken}, slog.Default())
+	o.now = func() time.Time { return now }
+
+	err = o.Send(ctx, notify.Notification{Title: "Tour slot re-opened", Message: "Tour: A"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for attempt := 1; attempt < outboxMaxAttempts+2; attempt++ {
+		now = now.Add(backoff(attempt))
+		err = o.retry(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// only the broken backend is retried, and it gives up after the max attempts
+	if len(healthy.sent) != 1 || len(broken.sent) != 0 || broken.failures != 1 {
+		t.Errorf("unexpected sends: healthy %d, broken %d with %d failures left", len(healthy.sent), len(broken.sent), broken.failures)
+	}
+
+	stored, err := sc.ListNotifications(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored[0].Status != NotificationFailed || stored[0].Attempts != outboxMaxAttempts || stored[0].LastError != "notifier-2: unavailable" {
+		t.Errorf("unexpected notification: %+v", stored[0])
+	}
+
+	w := httptest.NewRecorder()
+	a := &App{sc: sc}
+	babyapi.Handler(a.GetNotifications).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/notifications", nil))
+
+	var list NotificationList
+	err = json.Unmarshal(w.Body.Bytes(), &list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deliveries := list.Items[0].Deliveries
+	if list.Items[0].Status != NotificationFailed || len(deliveries) != 2 {
+		t.Fatalf("unexpected notification: %+v", list.Items[0])
+	}
+	if deliveries[0].Notifier != "notifier" || deliveries[0].Status != NotificationSent || deliveries[0].Attempts != 1 {
+		t.Errorf("unexpected delivery: %+v", deliveries[0])
+	}
+	if deliveries[1].Notifier != "notifier-2" || deliveries[1].Status != NotificationFailed || deliveries[1].Attempts != outboxMaxAttempts {
+		t.Errorf("unexpected delivery: %+v", deliveries[1])
+	}
+}
+
+func TestGetNotificationsHTML(t *testing.T) {
+	ctx := context.Background()
+
+	sc, err := storage.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sc.Close()
+
+	a := New("", "", sc, &recordingNotifier{}).WithOutbox()
+	err = a.nc.Send(ctx, notify.Notification{Title: "Tour slot re-opened", Message: "Tour: A"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func() *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/notifications", nil)
+		r.Header.Set("Accept", "text/html")
+		w := httptest.NewRecorder()
+		babyapi.Handler(a.GetNotifications).ServeHTTP(w, r)
+		return w
+	}
+
+	w := get()
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Tour slot re-opened") {
+		t.Errorf("unexpected response %d: %s", w.Code, w.Body.String())
+	}
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "pages", "notifications.html"), `{{ define "content" }}{{ .Missing }}{{ end }}`)
+	a.WithTemplates(dir)
+
+	w = get()
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected internal server error, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	if backoff(1) != outboxRetryInterval || backoff(3) != 4*outboxRetryInterval || backoff(20) != outboxMaxBackoff {
+		t.Errorf("unexpected backoff: %s %s %s", backoff(1), backoff(3), backoff(20))
+	}
+}
@@ -0,0 +1,216 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"walks-of-italy/notify"
+	"walks-of-italy/tours"
+)
+
+// PipelineConfig controls how notifications are filtered and grouped before sending
+type PipelineConfig struct {
+	// DedupeWindow drops notifications identical to one received within the window
+	DedupeWindow time.Duration
+	// QuietHours holds non-urgent notifications until the quiet hours end
+	QuietHours tours.QuietHours
+	// DigestInterval batches non-urgent notifications into a single message sent on this interval.
+	// Batching is disabled if it is zero
+	DigestInterval time.Duration
+}
+
+// flushInterval is how often held notifications are checked: every DigestInterval, or every minute
+// if only quiet hours are used
+func (c PipelineConfig) flushInterval() time.Duration {
+	if c.DigestInterval == 0 {
+		return time.Minute
+	}
+	return c.DigestInterval
+}
+
+// Pipeline is a Notifier that deduplicates, holds, and batches notifications before sending them
+// to the next Notifier. Urgent notifications skip quiet hours and batching, but are still deduplicated
+type Pipeline struct {
+	next   notify.Notifier
+	config PipelineConfig
+	logger *slog.Logger
+	now    func() time.Time
+
+	lock    sync.Mutex
+	seen    map[string]time.Time
+	pending []notify.Notification
+}
+
+var _ notify.Notifier = &Pipeline{}
+
+func NewPipeline(next notify.Notifier, config PipelineConfig, logger *slog.Logger) *Pipeline {
+	return &Pipeline{
+		next:   next,
+		config: config,
+		logger: logger,
+		now:    time.Now,
+		seen:   map[string]time.Time{},
+	}
+}
+
+func (p *Pipeline) Send(ctx context.Context, n notify.Notification) error {
+	if p.isDuplicate(n) {
+		p.logger.Debug("dropping duplicate notification", "title", n.Title)
+		return nil
+	}
+
+	if n.Urgent {
+		return p.next.Send(ctx, n)
+	}
+
+	if p.config.DigestInterval > 0 || p.config.QuietHours.Contains(p.now()) {
+		p.lock.Lock()
+		p.pending = append(p.pending, n)
+		p.lock.Unlock()
+
+		p.logger.Debug("holding notification", "title", n.Title)
+		return nil
+	}
+
+	return p.next.Send(ctx, n)
+}
+
+// Run periodically flushes held notifications until the context is cancelled
+func (p *Pipeline) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.config.flushInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			err := p.Flush(ctx)
+			if err != nil {
+				p.logger.Error("error sending digest notification", "err", err)
+			}
+		case <-ctx.Done():
+			p.lock.Lock()
+			if len(p.pending) > 0 {
+				p.logger.Warn("dropping held notifications on shutdown", "count", len(p.pending))
+			}
+			p.lock.Unlock()
+			return
+		}
+	}
+}
+
+// Flush sends all held notifications as a single digest, unless it is currently quiet hours
+func (p *Pipeline) Flush(ctx context.Context) error {
+	if p.config.QuietHours.Contains(p.now()) {
+		return nil
+	}
+
+	p.lock.Lock()
+	pending := p.pending
+	p.pending = nil
+	p.lock.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	var errs []error
+	for _, n := range digests(pending) {
+		err := p.next.Send(ctx, n)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// isDuplicate records the notification and returns true if an identical one was seen within the
+// dedupe window
+func (p *Pipeline) isDuplicate(n notify.Notification) bool {
+	if p.config.DedupeWindow <= 0 {
+		return false
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	now := p.now()
+	for key, t := range p.seen {
+		if now.Sub(t) >= p.config.DedupeWindow {
+			delete(p.seen, key)
+		}
+	}
+
+	key := n.Title + "\x00" + n.Message
+	_, ok := p.seen[key]
+	if !ok {
+		p.seen[key] = now
+	}
+	return ok
+}
+
+// digests splits notifications into as few digests as possible without any of their messages going
+// over notify.MaxMessageLength. A notification that is too long by itself is sent on its own
+func digests(notifications []notify.Notification) []notify.Notification {
+	var result []notify.Notification
+	start, length := 0, 0
+	for i, n := range notifications {
+		entryLength := notify.MessageLength(digestEntry(n))
+		if i > start && length+len(digestSeparator)+entryLength > notify.MaxMessageLength {
+			result = append(result, digest(notifications[start:i]))
+			start, length = i, 0
+		}
+		if i > start {
+			length += len(digestSeparator)
+		}
+		length += entryLength
+	}
+	return append(result, digest(notifications[start:]))
+}
+
+const digestSeparator = "\n\n"
+
+// digestEntry is how a notification is shown in a digest
+func digestEntry(n notify.Notification) string {
+	return fmt.Sprintf("%s\n%s", n.Title, n.Message)
+}
+
+// digest combines notifications into one message. A single notification is sent unchanged. The
+// digest uses the highest priority and its sound, retry, and expire, and keeps the URL if all of the
+// notifications have the same one. Timestamp is the earliest one
+func digest(notifications []notify.Notification) notify.Notification {
+	if len(notifications) == 1 {
+		return notifications[0]
+	}
+
+	highest := notifications[0]
+	sameURL := true
+	messages := make([]string, 0, len(notifications))
+	for _, n := range notifications {
+		messages = append(messages, digestEntry(n))
+		if n.Priority > highest.Priority {
+			highest = n
+		}
+		sameURL = sameURL && n.URL == notifications[0].URL
+	}
+
+	result := notify.Notification{
+		Title:     fmt.Sprintf("%d tour updates", len(notifications)),
+		Message:   strings.Join(messages, digestSeparator),
+		Priority:  highest.Priority,
+		Sound:     highest.Sound,
+		Retry:     highest.Retry,
+		Expire:    highest.Expire,
+		Timestamp: slices.MinFunc(notifications, func(a, b notify.Notification) int { return a.Timestamp.Compare(b.Timestamp) }).Timestamp,
+	}
+	if sameURL {
+		result.URL = notifications[0].URL
+		result.URLTitle = notifications[0].URLTitle
+	}
+	return result
+}
@@ -0,0 +1,128 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"walks-of-italy/notify"
+	"walks-of-italy/tours"
+)
+
+type recordingNotifier struct {
+	sent []notify.Notification
+}
+
+func (r *recordingNotifier) Send(_ context.Context, n notify.Notification) error {
+	r.sent = append(r.sent, n)
+	return nil
+}
+
+func TestPipeline(t *testing.T) {
+	now := time.Date(2025, time.May, 1, 3, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	next := &recordingNotifier{}
+	p := NewPipeline(next, PipelineConfig{
+		DedupeWindow: time.Hour,
+		QuietHours:   tours.QuietHours{Start: 22 * 60, End: 7 * 60},
+	}, slog.Default())
+	p.now = func() time.Time { return now }
+
+	a := notify.Notification{Title: "New tour availabilities posted", Message: "Tour: A"}
+	b := notify.Notification{Title: "New tour availabilities posted", Message: "Tour: B"}
+	urgent := notify.Notification{Title: "Tour slot re-opened", Message: "Tour: C", Urgent: true}
+
+	for _, n := range []notify.Notification{a, b, a, urgent, urgent} {
+		err := p.Send(ctx, n)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(next.sent) != 1 || next.sent[0] != urgent {
+		t.Fatalf("expected only the urgent notification during quiet hours: %+v", next.sent)
+	}
+
+	err := p.Flush(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(next.sent) != 1 {
+		t.Fatal("expected no flush during quiet hours")
+	}
+
+	now = now.Add(5 * time.Hour)
+	err = p.Flush(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(next.sent) != 2 {
+		t.Fatalf("expected digest after quiet hours: %+v", next.sent)
+	}
+
+	expected := "New tour availabilities posted\nTour: A\n\nNew tour availabilities posted\nTour: B"
+	if next.sent[1].Title != "2 tour updates" || next.sent[1].Message != expected {
+		t.Errorf("unexpected digest: %+v", next.sent[1])
+	}
+
+	// duplicate is allowed again after the window
+	err = p.Send(ctx, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(next.sent) != 3 || next.sent[2] != a {
+		t.Errorf("expected notification to be sent after dedupe window: %+v", next.sent)
+	}
+}
+
+func TestDigest(t *testing.T) {
+	now := time.Date(2025, time.May, 1, 3, 0, 0, 0, time.UTC)
+	normal := notify.Notification{Title: "New tour availabilities posted", Message: "Tour: A", URL: "https://example.com/a", Timestamp: now}
+	high := notify.Notification{Title: "Tour slot re-opened", Message: "Tour: A", URL: "https://example.com/a", Priority: 2, Sound: "siren", Retry: time.Minute, Expire: time.Hour, Timestamp: now.Add(time.Minute)}
+
+	n := digest([]notify.Notification{normal, high})
+	if n.Priority != 2 || n.Sound != "siren" || n.Retry != time.Minute || n.Expire != time.Hour {
+		t.Errorf("expected highest priority settings: %+v", n)
+	}
+	if n.URL != "https://example.com/a" || !n.Timestamp.Equal(now) {
+		t.Errorf("expected shared URL and earliest timestamp: %+v", n)
+	}
+
+	other := normal
+	other.URL = "https://example.com/b"
+	n = digest([]notify.Notification{normal, high, other})
+	if n.URL != "" || n.Priority != 2 {
+		t.Errorf("expected no URL when they are different: %+v", n)
+	}
+}
+
+func TestDigestsLength(t *testing.T) {
+	var notifications []notify.Notification
+	for i := range 50 {
+		notifications = append(notifications, notify.Notification{
+			Title:   "New tour availabilities posted",
+			Message: fmt.Sprintf("Tour: VIP Vatican Key Master's Tour %d\nDate: Mon, 02 Jun 2025 07:00 CEST\nSlots: %d", i, i),
+		})
+	}
+
+	result := digests(notifications)
+	if len(result) < 2 {
+		t.Fatalf("expected the digest to be split: %d", len(result))
+	}
+
+	total := 0
+	for _, n := range result {
+		if notify.MessageLength(n.Message) > notify.MaxMessageLength {
+			t.Errorf("digest is too long: %d", notify.MessageLength(n.Message))
+		}
+		total += strings.Count(n.Message, "Key Master")
+	}
+	if total != len(notifications) {
+		t.Errorf("expected all %d notifications in the digests: %d", len(notifications), total)
+	}
+}
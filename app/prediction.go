@@ -0,0 +1,118 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"walks-of-italy/tours"
+
+	"github.com/calvinmclean/babyapi"
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+)
+
+// WithReleasePolling polls each tour on this interval while it's in its predicted release window, in
+// addition to the regular polling. It is disabled if the interval is zero
+func (a *App) WithReleasePolling(interval time.Duration) *App {
+	a.releasePolling = interval
+	return a
+}
+
+// predictRelease predicts the tour's next release. It returns nil if there aren't enough releases
+func (a *App) predictRelease(ctx context.Context, tourID uuid.UUID, now time.Time) (*tours.ReleasePrediction, error) {
+	releases, err := a.sc.Releases(ctx, tourID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting releases: %w", err)
+	}
+
+	prediction, ok := tours.PredictRelease(releases, now, time.Local)
+	if !ok {
+		return nil, nil
+	}
+	return &prediction, nil
+}
+
+// PredictionResponse is the predicted next release for a tour. Prediction is null if the tour
+// doesn't have enough releases yet
+type PredictionResponse struct {
+	TourID     uuid.UUID                `json:"tourId"`
+	Prediction *tours.ReleasePrediction `json:"prediction"`
+}
+
+func (*PredictionResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// GetTourPrediction predicts when the tour's next batch of dates will be released
+func (a *App) GetTourPrediction(w http.ResponseWriter, r *http.Request, td *tours.TourDetail) (render.Renderer, *babyapi.ErrResponse) {
+	prediction, err := a.predictRelease(r.Context(), td.ProductID, time.Now())
+	if err != nil {
+		return nil, babyapi.InternalServerError(err)
+	}
+	return &PredictionResponse{TourID: td.ProductID, Prediction: prediction}, nil
+}
+
+// toursInReleaseWindow returns the unpaused tours that are in their predicted release window
+func (a *App) toursInReleaseWindow(ctx context.Context, now time.Time) ([]*tours.TourDetail, error) {
+	allTours, err := a.sc.GetAll(ctx, url.Values{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting tours: %w", err)
+	}
+
+	var result []*tours.TourDetail
+	for _, td := range allTours {
+		if td.Paused {
+			continue
+		}
+
+		prediction, err := a.predictRelease(ctx, td.ProductID, now)
+		if err != nil {
+			return nil, err
+		}
+		if prediction != nil && prediction.Contains(now) {
+			result = append(result, td)
+		}
+	}
+	return result, nil
+}
+
+// runReleasePolling polls tours more often while they are in their predicted release window so new
+// dates are found as soon as they are released
+func (a *App) runReleasePolling(ctx context.Context) {
+	ticker := time.NewTicker(a.releasePolling)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case t := <-ticker.C:
+			inWindow, err := a.toursInReleaseWindow(ctx, t)
+			if err != nil {
+				a.logger.Error("error getting tours in release window", "err", err)
+				continue
+			}
+			if len(inWindow) == 0 {
+				continue
+			}
+
+			rules, err := a.sc.Rules().GetAll(ctx, url.Values{})
+			if err != nil {
+				a.logger.Error("error getting rules", "err", err)
+				continue
+			}
+
+			a.logger.Debug("polling tours in release window", "tours", len(inWindow))
+			a.pollLock.Lock()
+			err = a.UpdateLatestAvailabilities(ctx, inWindow, a.onAvailabilityUpdate(ctx, rules), a.onPollError(ctx))
+			a.pollLock.Unlock()
+			if err != nil {
+				a.logger.Error("error updating availabilities in release window", "err", err)
+			}
+			a.publishStatus()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
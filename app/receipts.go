@@ -0,0 +1,172 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"walks-of-italy/notify"
+	"walks-of-italy/storage"
+	"walks-of-italy/storage/db"
+
+	"github.com/calvinmclean/babyapi"
+	"github.com/go-chi/render"
+)
+
+const receiptCheckInterval = time.Minute
+
+// ReceiptTracker stores receipts for emergency-priority Pushover messages and checks them until they
+// are acknowledged or expire
+type ReceiptTracker struct {
+	sc       *storage.Client
+	pushover *notify.Pushover
+	logger   *slog.Logger
+	now      func() time.Time
+}
+
+var _ notify.ReceiptTracker = &ReceiptTracker{}
+
+func NewReceiptTracker(sc *storage.Client, pushover *notify.Pushover, logger *slog.Logger) *ReceiptTracker {
+	return &ReceiptTracker{
+		sc:       sc,
+		pushover: pushover,
+		logger:   logger,
+		now:      func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// TrackReceipt stores the receipt. Errors are only logged because the message was already sent
+func (rt *ReceiptTracker) TrackReceipt(ctx context.Context, receipt string, n notify.Notification) {
+	err := rt.sc.AddPushoverReceipt(ctx, db.AddPushoverReceiptParams{
+		Receipt:   receipt,
+		Title:     n.Title,
+		Message:   n.Message,
+		CreatedAt: rt.now(),
+	})
+	if err != nil {
+		rt.logger.Error("error storing pushover receipt", "receipt", receipt, "err", err)
+	}
+}
+
+// Run checks pending receipts until the context is cancelled
+func (rt *ReceiptTracker) Run(ctx context.Context) {
+	ticker := time.NewTicker(receiptCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			err := rt.check(ctx)
+			if err != nil {
+				rt.logger.Error("error checking pushover receipts", "err", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (rt *ReceiptTracker) check(ctx context.Context) error {
+	pending, err := rt.sc.ListPendingPushoverReceipts(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting pending receipts: %w", err)
+	}
+
+	for _, stored := range pending {
+		receipt, err := rt.pushover.Receipt(stored.Receipt)
+		if err != nil {
+			rt.logger.Error("error checking pushover receipt", "receipt", stored.Receipt, "err", err)
+			continue
+		}
+
+		if receipt.Acknowledged {
+			rt.logger.Info("emergency notification acknowledged", "title", stored.Title, "acknowledged_by", receipt.AcknowledgedBy)
+		}
+
+		err = rt.sc.UpdatePushoverReceipt(ctx, db.UpdatePushoverReceiptParams{
+			Receipt:        stored.Receipt,
+			Acknowledged:   receipt.Acknowledged,
+			AcknowledgedBy: receipt.AcknowledgedBy,
+			AcknowledgedAt: nullTime(receipt.AcknowledgedAt),
+			Expired:        receipt.Expired,
+			ExpiresAt:      nullTime(receipt.ExpiresAt),
+			LastCheckedAt:  sql.NullTime{Time: rt.now(), Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("error updating receipt: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
+
+func timePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// ReceiptRecord is the status of an emergency-priority Pushover message
+type ReceiptRecord struct {
+	Receipt        string     `json:"receipt"`
+	Title          string     `json:"title"`
+	Message        string     `json:"message"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	Acknowledged   bool       `json:"acknowledged"`
+	AcknowledgedBy string     `json:"acknowledgedBy,omitempty"`
+	AcknowledgedAt *time.Time `json:"acknowledgedAt,omitempty"`
+	Expired        bool       `json:"expired"`
+	ExpiresAt      *time.Time `json:"expiresAt,omitempty"`
+	LastCheckedAt  *time.Time `json:"lastCheckedAt,omitempty"`
+}
+
+// ReceiptList is the response for the Pushover receipts endpoint
+type ReceiptList struct {
+	Items []ReceiptRecord `json:"items"`
+}
+
+func (*ReceiptList) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// GetReceipts lists the most recent emergency-priority Pushover receipts. It accepts an optional limit query parameter
+func (a *App) GetReceipts(w http.ResponseWriter, r *http.Request) render.Renderer {
+	limit, httpErr := queryLimit(r)
+	if httpErr != nil {
+		return httpErr
+	}
+
+	rows, err := a.sc.ListPushoverReceipts(r.Context(), int64(limit))
+	if err != nil {
+		return babyapi.InternalServerError(fmt.Errorf("error getting receipts: %w", err))
+	}
+
+	result := &ReceiptList{Items: []ReceiptRecord{}}
+	for _, row := range rows {
+		result.Items = append(result.Items, ReceiptRecord{
+			Receipt:        row.Receipt,
+			Title:          row.Title,
+			Message:        row.Message,
+			CreatedAt:      row.CreatedAt,
+			Acknowledged:   row.Acknowledged,
+			AcknowledgedBy: row.AcknowledgedBy,
+			AcknowledgedAt: timePtr(row.AcknowledgedAt),
+			Expired:        row.Expired,
+			ExpiresAt:      timePtr(row.ExpiresAt),
+			LastCheckedAt:  timePtr(row.LastCheckedAt),
+		})
+	}
+
+	return result
+}
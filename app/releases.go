@@ -0,0 +1,153 @@
+package app
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"time"
+
+	"walks-of-italy/tours"
+
+	"github.com/calvinmclean/babyapi"
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+)
+
+// ReleaseRecord is a new latest date for a tour and when it was first seen
+type ReleaseRecord struct {
+	RecordedAt time.Time  `json:"recordedAt"`
+	Date       tours.Date `json:"date"`
+	LeadDays   int        `json:"leadDays"`
+	// First is true for the first recorded date, which isn't included in the summary
+	First bool `json:"first"`
+}
+
+// ReleaseStats describes how far ahead and when a tour's dates are released. Weekday and hour
+// counts use the server's time zone
+type ReleaseStats struct {
+	Count          int            `json:"count"`
+	MinLeadDays    int            `json:"minLeadDays"`
+	MaxLeadDays    int            `json:"maxLeadDays"`
+	MedianLeadDays int            `json:"medianLeadDays"`
+	Weekdays       map[string]int `json:"weekdays"`
+	Hours          map[int]int    `json:"hours"`
+	TimeZone       string         `json:"timeZone"`
+}
+
+// ReleaseHistory is the response for a tour's release history
+type ReleaseHistory struct {
+	TourID   uuid.UUID       `json:"tourId"`
+	Items    []ReleaseRecord `json:"items"`
+	Summary  ReleaseStats    `json:"summary"`
+	releases []tours.Release
+}
+
+func (*ReleaseHistory) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+func (a *App) releaseHistory(r *http.Request, td *tours.TourDetail) (*ReleaseHistory, error) {
+	releases, err := a.sc.Releases(r.Context(), td.ProductID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting releases: %w", err)
+	}
+
+	s := tours.SummarizeReleases(releases, time.Local)
+	history := &ReleaseHistory{
+		TourID: td.ProductID,
+		Items:  []ReleaseRecord{},
+		Summary: ReleaseStats{
+			Count:          s.Count,
+			MinLeadDays:    s.MinLeadDays,
+			MaxLeadDays:    s.MaxLeadDays,
+			MedianLeadDays: s.MedianLeadDays,
+			Weekdays:       map[string]int{},
+			Hours:          map[int]int{},
+			TimeZone:       time.Local.String(),
+		},
+		releases: releases,
+	}
+	for _, release := range releases {
+		history.Items = append(history.Items, ReleaseRecord{
+			RecordedAt: release.RecordedAt,
+			Date:       tours.DateFromTime(release.Date),
+			LeadDays:   release.LeadDays(),
+			First:      release.First,
+		})
+	}
+	for weekday, count := range s.Weekdays {
+		if count > 0 {
+			history.Summary.Weekdays[time.Weekday(weekday).String()] = count
+		}
+	}
+	for hour, count := range s.Hours {
+		if count > 0 {
+			history.Summary.Hours[hour] = count
+		}
+	}
+
+	return history, nil
+}
+
+// GetTourReleases lists each new latest date for the tour along with a summary of how far ahead and
+// when dates are released
+func (a *App) GetTourReleases(w http.ResponseWriter, r *http.Request, td *tours.TourDetail) (render.Renderer, *babyapi.ErrResponse) {
+	history, err := a.releaseHistory(r, td)
+	if err != nil {
+		return nil, babyapi.InternalServerError(err)
+	}
+	return history, nil
+}
+
+// GetTourReleasesChart renders a release chart as SVG. Use the chart query parameter to choose
+// timeline (default), lead, or times
+func (a *App) GetTourReleasesChart(w http.ResponseWriter, r *http.Request, td *tours.TourDetail) {
+	chart := releaseTimelineSVG
+	switch r.URL.Query().Get("chart") {
+	case "", "timeline":
+	case "lead":
+		chart = leadTimeSVG
+	case "times":
+		chart = releaseTimesSVG
+	default:
+		http.Error(w, "invalid chart: must be timeline, lead, or times", http.StatusBadRequest)
+		return
+	}
+
+	releases, err := a.sc.Releases(r.Context(), td.ProductID)
+	if err != nil {
+		a.logger.Error("error getting releases", "tour_id", td.ProductID, "err", err)
+		http.Error(w, "error getting releases", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+	_, _ = w.Write([]byte(chart(releases, time.Local)))
+}
+
+type timelinePage struct {
+	Tour     *tours.TourDetail
+	History  *ReleaseHistory
+	Timeline template.HTML
+	LeadTime template.HTML
+	Times    template.HTML
+}
+
+// TourTimeline shows charts of the tour's release history
+func (a *App) TourTimeline(w http.ResponseWriter, r *http.Request, td *tours.TourDetail) {
+	history, err := a.releaseHistory(r, td)
+	if err != nil {
+		a.logger.Error("error getting releases", "tour_id", td.ProductID, "err", err)
+		http.Error(w, "error getting releases", http.StatusInternalServerError)
+		return
+	}
+
+	// the charts are generated with escaped text, so they are safe to include
+	a.renderPage(w, http.StatusOK, "timeline", timelinePage{
+		Tour:     td,
+		History:  history,
+		Timeline: template.HTML(releaseTimelineSVG(history.releases, time.Local)),
+		LeadTime: template.HTML(leadTimeSVG(history.releases, time.Local)),
+		Times:    template.HTML(releaseTimesSVG(history.releases, time.Local)),
+	})
+}
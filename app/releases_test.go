@@ -0,0 +1,122 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"walks-of-italy/storage"
+	"walks-of-italy/storage/db"
+	"walks-of-italy/tours"
+
+	"github.com/google/uuid"
+)
+
+func TestReleaseCharts(t *testing.T) {
+	releases := []tours.Release{
+		{RecordedAt: time.Date(2025, time.March, 1, 8, 0, 0, 0, time.UTC), Date: time.Date(2025, time.June, 30, 0, 0, 0, 0, time.UTC), First: true},
+		{RecordedAt: time.Date(2025, time.March, 3, 9, 0, 0, 0, time.UTC), Date: time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC)},
+		{RecordedAt: time.Date(2025, time.March, 10, 9, 0, 0, 0, time.UTC), Date: time.Date(2025, time.July, 10, 0, 0, 0, 0, time.UTC)},
+	}
+
+	tests := []struct {
+		name    string
+		chart   func([]tours.Release, *time.Location) string
+		circles int
+	}{
+		{"Timeline", releaseTimelineSVG, 3},
+		{"LeadTime", leadTimeSVG, 2},
+		// both releases were on Monday at 09:00
+		{"Times", releaseTimesSVG, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svg := tt.chart(releases, time.UTC)
+			if !strings.HasPrefix(svg, "<svg") || !strings.HasSuffix(svg, "</svg>") {
+				t.Errorf("unexpected SVG: %s", svg)
+			}
+			if n := strings.Count(svg, "<circle"); n != tt.circles {
+				t.Errorf("expected %d points, got %d", tt.circles, n)
+			}
+			if strings.Contains(svg, "NaN") {
+				t.Errorf("unexpected NaN in SVG: %s", svg)
+			}
+
+			empty := tt.chart(nil, time.UTC)
+			if !strings.Contains(empty, "No releases recorded yet") {
+				t.Errorf("expected empty message, got %s", empty)
+			}
+		})
+	}
+}
+
+func TestGetTourReleases(t *testing.T) {
+	ctx := context.Background()
+
+	sc, err := storage.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sc.Close()
+
+	tour := tours.TourDetail{Name: "Tour", ProductID: uuid.New()}
+	err = sc.Set(ctx, &tour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, d := range []time.Time{time.Now().AddDate(0, 3, 0), time.Now().AddDate(0, 4, 0)} {
+		err = sc.AddLatestAvailability(ctx, db.AddLatestAvailabilityParams{
+			TourUuid:         tour.ProductID,
+			AvailabilityDate: tours.DateFromTime(d).ToTime(),
+			RawData:          "[]",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a := New("", "", sc, nil)
+
+	r := httptest.NewRequest(http.MethodGet, "/tours/"+tour.GetID()+"/releases", nil)
+	resp, httpErr := a.GetTourReleases(httptest.NewRecorder(), r, &tour)
+	if httpErr != nil {
+		t.Fatal(httpErr.Err)
+	}
+
+	history := resp.(*ReleaseHistory)
+	if len(history.Items) != 2 || !history.Items[0].First || history.Items[1].First {
+		t.Errorf("unexpected items: %+v", history.Items)
+	}
+	if history.Summary.Count != 1 {
+		t.Errorf("expected 1 release, got %d", history.Summary.Count)
+	}
+
+	data, err := json.Marshal(history)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"leadDays":`) {
+		t.Errorf("unexpected JSON: %s", data)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/tours/"+tour.GetID()+"/releases.svg?chart=lead", nil)
+	w := httptest.NewRecorder()
+	a.GetTourReleasesChart(w, r, &tour)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/svg+xml" {
+		t.Errorf("unexpected response %d %q: %s", w.Code, w.Header().Get("Content-Type"), w.Body.String())
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/tours/"+tour.GetID()+"/releases.svg?chart=invalid", nil)
+	w = httptest.NewRecorder()
+	a.GetTourReleasesChart(w, r, &tour)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
@@ -0,0 +1,47 @@
+const escapeHTML = (s) => s.replace(/[&<>"']/g, (c) => "&#" + c.charCodeAt(0) + ";");
+
+// showNotification shows a toast in the top right that closes when clicked or after a few seconds
+function showNotification(html, status) {
+    let container = document.querySelector(".uk-notification");
+    if (!container) {
+        container = document.createElement("div");
+        container.className = "uk-notification";
+        document.body.appendChild(container);
+    }
+
+    const message = document.createElement("div");
+    message.className = "uk-notification-message uk-notification-message-" + status;
+    message.innerHTML = html;
+    message.onclick = () => message.remove();
+    container.appendChild(message);
+    setTimeout(() => message.remove(), 5000);
+}
+
+function liveUpdates(handlers) {
+    const status = document.getElementById("live-status");
+    const setStatus = (text) => {
+        if (status) {
+            status.textContent = text;
+        }
+    };
+
+    const source = new EventSource("/events");
+    source.onopen = () => setStatus("Live");
+    source.onerror = () => setStatus("Reconnecting...");
+    source.addEventListener("status", (e) => {
+        const s = JSON.parse(e.data);
+        if (s.nextRun) {
+            setStatus("Live, next poll at " + new Date(s.nextRun).toLocaleTimeString());
+        }
+    });
+    source.addEventListener("notification", (e) => {
+        const n = JSON.parse(e.data);
+        showNotification(
+            "<strong>" + escapeHTML(n.title) + "</strong><br>" + escapeHTML(n.message).replaceAll("\n", "<br>"),
+            n.urgent ? "danger" : "primary",
+        );
+    });
+    for (const [type, handle] of Object.entries(handlers)) {
+        source.addEventListener(type, (e) => handle(JSON.parse(e.data)));
+    }
+}
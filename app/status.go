@@ -0,0 +1,269 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"walks-of-italy/notify"
+	"walks-of-italy/tours"
+
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+)
+
+const deadManCheckInterval = time.Minute
+
+// TourStatus is the polling status for a single tour. NextRun is not set for paused tours
+type TourStatus struct {
+	TourID              uuid.UUID  `json:"tourId"`
+	Name                string     `json:"name"`
+	Paused              bool       `json:"paused"`
+	LastPoll            *time.Time `json:"lastPoll,omitempty"`
+	LastSuccess         *time.Time `json:"lastSuccess,omitempty"`
+	NextRun             *time.Time `json:"nextRun,omitempty"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	LastError           string     `json:"lastError,omitempty"`
+}
+
+// WatchStatus is the response for the /status endpoint
+type WatchStatus struct {
+	StartedAt   *time.Time   `json:"startedAt,omitempty"`
+	LastSuccess *time.Time   `json:"lastSuccess,omitempty"`
+	NextRun     *time.Time   `json:"nextRun,omitempty"`
+	Tours       []TourStatus `json:"tours"`
+}
+
+func (*WatchStatus) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// watchStatus records the results of polls so the status can be checked from the API
+type watchStatus struct {
+	lock        sync.RWMutex
+	startedAt   time.Time
+	lastSuccess time.Time
+	nextRun     time.Time
+	tours       map[uuid.UUID]*TourStatus
+}
+
+func newWatchStatus() *watchStatus {
+	return &watchStatus{tours: map[uuid.UUID]*TourStatus{}}
+}
+
+func (s *watchStatus) start(now time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.startedAt = now
+}
+
+func (s *watchStatus) setNextRun(next time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.nextRun = next
+}
+
+// tour returns the status for a tour, creating it if needed. The lock must be held
+func (s *watchStatus) tour(tour tours.TourDetail) *TourStatus {
+	status, ok := s.tours[tour.ProductID]
+	if !ok {
+		status = &TourStatus{TourID: tour.ProductID}
+		s.tours[tour.ProductID] = status
+	}
+
+	status.Name = tour.Name
+	status.Paused = tour.Paused
+	return status
+}
+
+// setPaused updates whether a tour is paused so paused tours are not shown with a next run
+func (s *watchStatus) setPaused(tour tours.TourDetail) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.tour(tour)
+}
+
+// remove drops the status for a tour that was deleted
+func (s *watchStatus) remove(id uuid.UUID) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.tours, id)
+}
+
+// retain drops the status for tours that aren't in the current list, which catches deleted tours
+// that were being polled when they were removed
+func (s *watchStatus) retain(current []*tours.TourDetail) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	ids := map[uuid.UUID]bool{}
+	for _, td := range current {
+		ids[td.ProductID] = true
+	}
+	for id := range s.tours {
+		if !ids[id] {
+			delete(s.tours, id)
+		}
+	}
+}
+
+// record stores the result of polling a tour
+func (s *watchStatus) record(tour tours.TourDetail, now time.Time, err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	status := s.tour(tour)
+	status.LastPoll = &now
+	if err != nil {
+		status.ConsecutiveFailures++
+		status.LastError = err.Error()
+		return
+	}
+
+	status.LastSuccess = &now
+	status.ConsecutiveFailures = 0
+	status.LastError = ""
+	s.lastSuccess = now
+}
+
+// polled returns true once the watch loop has completed its first poll and scheduled the next one
+func (s *watchStatus) polled() bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return !s.nextRun.IsZero()
+}
+
+// sinceSuccess returns the time since the last successful poll, or since the watch started if there
+// hasn't been a successful poll. It returns false if the watch hasn't started
+func (s *watchStatus) sinceSuccess(now time.Time) (time.Duration, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.startedAt.IsZero() {
+		return 0, false
+	}
+	if s.lastSuccess.After(s.startedAt) {
+		return now.Sub(s.lastSuccess), true
+	}
+	return now.Sub(s.startedAt), true
+}
+
+func (s *watchStatus) snapshot() *WatchStatus {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	result := &WatchStatus{
+		StartedAt:   optionalTime(s.startedAt),
+		LastSuccess: optionalTime(s.lastSuccess),
+		NextRun:     optionalTime(s.nextRun),
+		Tours:       []TourStatus{},
+	}
+	for _, status := range s.tours {
+		tour := *status
+		if !tour.Paused {
+			tour.NextRun = result.NextRun
+		}
+		result.Tours = append(result.Tours, tour)
+	}
+	slices.SortFunc(result.Tours, func(a, b TourStatus) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return result
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// runDeadManSwitch sends an urgent notification when there hasn't been a successful poll within the
+// configured duration, and another when polling recovers
+func (a *App) runDeadManSwitch(ctx context.Context) {
+	ticker := time.NewTicker(min(deadManCheckInterval, a.deadManSwitch))
+	defer ticker.Stop()
+
+	alerted := false
+	for {
+		select {
+		case t := <-ticker.C:
+			since, ok := a.status.sinceSuccess(t)
+			if !ok {
+				continue
+			}
+
+			var n *notify.Notification
+			switch {
+			case since > a.deadManSwitch && !alerted:
+				alerted = true
+				n = &notify.Notification{
+					Urgent:  true,
+					Title:   "Tour polling is not working",
+					Message: fmt.Sprintf("No successful polls in %s", since.Round(time.Second)),
+				}
+			case since <= a.deadManSwitch && alerted:
+				alerted = false
+				n = &notify.Notification{
+					Urgent:  true,
+					Title:   "Tour polling recovered",
+					Message: "Tours are being polled successfully again",
+				}
+			}
+			if n == nil {
+				continue
+			}
+
+			a.logger.Warn(n.Title, "since_success", since.String())
+			if a.nc == nil {
+				continue
+			}
+			err := a.nc.Send(ctx, *n)
+			if err != nil {
+				a.logger.Error("error sending notification", "err", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+type healthResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+func (*healthResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// GetHealth responds successfully as long as the server is running
+func (a *App) GetHealth(w http.ResponseWriter, r *http.Request) render.Renderer {
+	return &healthResponse{Status: "ok"}
+}
+
+// GetReady responds successfully when the database is reachable and the watch loop has polled
+func (a *App) GetReady(w http.ResponseWriter, r *http.Request) render.Renderer {
+	err := a.sc.Ping(r.Context())
+	if err != nil {
+		render.Status(r, http.StatusServiceUnavailable)
+		return &healthResponse{Status: "unavailable", Error: fmt.Sprintf("error connecting to database: %v", err)}
+	}
+
+	if !a.status.polled() {
+		render.Status(r, http.StatusServiceUnavailable)
+		return &healthResponse{Status: "unavailable", Error: "waiting for first poll"}
+	}
+
+	return &healthResponse{Status: "ok"}
+}
+
+// GetStatus shows the polling status for each tour
+func (a *App) GetStatus(w http.ResponseWriter, r *http.Request) render.Renderer {
+	return a.status.snapshot()
+}
@@ -0,0 +1,96 @@
+package app
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"walks-of-italy/tours"
+
+	"github.com/google/uuid"
+)
+
+func TestWatchStatus(t *testing.T) {
+	start := time.Date(2025, time.May, 1, 9, 0, 0, 0, time.UTC)
+	a := tours.TourDetail{Name: "A", ProductID: uuid.New()}
+	b := tours.TourDetail{Name: "B", ProductID: uuid.New()}
+
+	s := newWatchStatus()
+	_, ok := s.sinceSuccess(start)
+	if ok {
+		t.Error("expected no status before starting")
+	}
+
+	s.start(start)
+	s.record(b, start.Add(time.Minute), errors.New("timeout"))
+	s.record(b, start.Add(2*time.Minute), errors.New("timeout"))
+	s.record(a, start.Add(2*time.Minute), nil)
+
+	if s.polled() {
+		t.Error("expected not ready before the next run is scheduled")
+	}
+	s.setNextRun(start.Add(3 * time.Minute))
+	if !s.polled() {
+		t.Error("expected ready after the first poll")
+	}
+
+	since, ok := s.sinceSuccess(start.Add(10 * time.Minute))
+	if !ok || since != 8*time.Minute {
+		t.Errorf("unexpected time since success: %s", since)
+	}
+
+	status := s.snapshot()
+	if len(status.Tours) != 2 || status.Tours[0].Name != "A" || status.Tours[1].Name != "B" {
+		t.Fatalf("unexpected tours: %+v", status.Tours)
+	}
+	if status.Tours[0].ConsecutiveFailures != 0 || status.Tours[0].LastSuccess == nil {
+		t.Errorf("unexpected status for A: %+v", status.Tours[0])
+	}
+	if status.Tours[1].ConsecutiveFailures != 2 || status.Tours[1].LastError != "timeout" || status.Tours[1].LastSuccess != nil {
+		t.Errorf("unexpected status for B: %+v", status.Tours[1])
+	}
+
+	if status.Tours[0].NextRun == nil || !status.Tours[0].NextRun.Equal(start.Add(3*time.Minute)) {
+		t.Errorf("expected A to have the next run: %+v", status.Tours[0])
+	}
+
+	s.record(b, start.Add(3*time.Minute), nil)
+	status = s.snapshot()
+	if status.Tours[1].ConsecutiveFailures != 0 || status.Tours[1].LastError != "" {
+		t.Errorf("expected B to recover: %+v", status.Tours[1])
+	}
+
+	b.Paused = true
+	s.setPaused(b)
+	status = s.snapshot()
+	if !status.Tours[1].Paused || status.Tours[1].NextRun != nil || status.Tours[1].LastSuccess == nil {
+		t.Errorf("expected B to be paused without a next run: %+v", status.Tours[1])
+	}
+	if status.Tours[0].NextRun == nil {
+		t.Errorf("expected A to still have a next run: %+v", status.Tours[0])
+	}
+}
+
+func TestWatchStatusRemove(t *testing.T) {
+	now := time.Date(2025, time.May, 1, 9, 0, 0, 0, time.UTC)
+	a := tours.TourDetail{Name: "A", ProductID: uuid.New()}
+	b := tours.TourDetail{Name: "B", ProductID: uuid.New()}
+	c := tours.TourDetail{Name: "C", ProductID: uuid.New()}
+
+	s := newWatchStatus()
+	for _, td := range []tours.TourDetail{a, b, c} {
+		s.record(td, now, nil)
+	}
+
+	s.remove(a.ProductID)
+	status := s.snapshot()
+	if len(status.Tours) != 2 || status.Tours[0].Name != "B" {
+		t.Fatalf("expected A to be removed: %+v", status.Tours)
+	}
+
+	s.retain([]*tours.TourDetail{&c})
+	status = s.snapshot()
+	if len(status.Tours) != 1 || status.Tours[0].Name != "C" {
+		t.Errorf("expected only C to be kept: %+v", status.Tours)
+	}
+}
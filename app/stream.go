@@ -0,0 +1,330 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"walks-of-italy/notify"
+	"walks-of-italy/tours"
+
+	"github.com/calvinmclean/babyapi"
+)
+
+const (
+	// StreamEventStatus has the WatchStatus after each poll
+	StreamEventStatus = "status"
+	// StreamEventNotification has a NotificationEvent for each notification that is sent
+	StreamEventNotification = "notification"
+
+	// streamBuffer is how many events are kept for a slow subscriber before new events are dropped
+	streamBuffer = 64
+	// streamKeepAlive is how often a comment is sent so proxies don't close idle connections
+	streamKeepAlive = 30 * time.Second
+)
+
+// NotificationEvent is the data for a notification event in the stream
+type NotificationEvent struct {
+	Time    time.Time `json:"time"`
+	Title   string    `json:"title"`
+	Message string    `json:"message"`
+	Urgent  bool      `json:"urgent"`
+	URL     string    `json:"url,omitempty"`
+}
+
+// EventStream sends events from the watch loop to subscribers of the /events endpoint. Each tour
+// event uses its EventType as the event name
+type EventStream struct {
+	lock        sync.RWMutex
+	subscribers map[chan *babyapi.ServerSentEvent]struct{}
+	done        chan struct{}
+	closeOnce   sync.Once
+}
+
+func NewEventStream() *EventStream {
+	return &EventStream{
+		subscribers: map[chan *babyapi.ServerSentEvent]struct{}{},
+		done:        make(chan struct{}),
+	}
+}
+
+// Close ends all of the subscribers' streams so the server can shut down
+func (s *EventStream) Close() {
+	s.closeOnce.Do(func() { close(s.done) })
+}
+
+// Subscribe returns a channel of events and a function to unsubscribe
+func (s *EventStream) Subscribe() (<-chan *babyapi.ServerSentEvent, func()) {
+	events := make(chan *babyapi.ServerSentEvent, streamBuffer)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.subscribers[events] = struct{}{}
+
+	return events, func() {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		delete(s.subscribers, events)
+	}
+}
+
+func (s *EventStream) subscriberCount() int {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return len(s.subscribers)
+}
+
+// Send encodes the data as JSON and sends it to all subscribers. It doesn't block, so subscribers
+// that aren't keeping up miss events instead of slowing down the watch loop
+func (s *EventStream) Send(event string, data any) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("error encoding event data: %w", err)
+	}
+	sse := &babyapi.ServerSentEvent{Event: event, Data: string(encoded)}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	for events := range s.subscribers {
+		select {
+		case events <- sse:
+		default:
+		}
+	}
+	return nil
+}
+
+// streamNotifier sends an event for each notification that is delivered successfully, or that is
+// stored when it wraps the Outbox
+type streamNotifier struct {
+	notify.Notifier
+	stream *EventStream
+}
+
+func (n streamNotifier) Send(ctx context.Context, notification notify.Notification) error {
+	err := n.Notifier.Send(ctx, notification)
+	if err != nil {
+		return err
+	}
+
+	return n.stream.Send(StreamEventNotification, NotificationEvent{
+		Time:    time.Now(),
+		Title:   notification.Title,
+		Message: notification.Message,
+		Urgent:  notification.Urgent,
+		URL:     notification.URL,
+	})
+}
+
+// publishStatus sends the current watch status to the event stream
+func (a *App) publishStatus() {
+	err := a.stream.Send(StreamEventStatus, a.status.snapshot())
+	if err != nil {
+		a.logger.Error("error sending status event", "err", err)
+	}
+}
+
+// GetEvents streams events from the watch loop. Use the type query parameter, like
+// type=new_latest_date,status, to only receive some event types. The current status is sent first
+func (a *App) GetEvents(w http.ResponseWriter, r *http.Request) {
+	var types []string
+	if t := r.URL.Query().Get("type"); t != "" {
+		types = strings.Split(t, ",")
+	}
+	wanted := func(event string) bool {
+		return len(types) == 0 || slices.Contains(types, event)
+	}
+
+	events, unsubscribe := a.stream.Subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.WriteHeader(http.StatusOK)
+
+	if wanted(StreamEventStatus) {
+		data, err := json.Marshal(a.status.snapshot())
+		if err == nil {
+			(&babyapi.ServerSentEvent{Event: StreamEventStatus, Data: string(data)}).Write(w)
+		}
+	}
+
+	ticker := time.NewTicker(streamKeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case e := <-events:
+			if wanted(e.Event) {
+				e.Write(w)
+			}
+		case <-ticker.C:
+			_, _ = io.WriteString(w, ": keep-alive\n\n")
+			_ = http.NewResponseController(w).Flush()
+		case <-r.Context().Done():
+			return
+		case <-a.stream.done:
+			return
+		}
+	}
+}
+
+// ReadEventStream reads server-sent events and calls the function with each event's name and data.
+// It returns when the stream ends or the function returns an error
+func ReadEventStream(r io.Reader, handle func(event, data string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var event string
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if len(data) > 0 {
+				if event == "" {
+					event = "message"
+				}
+				err := handle(event, strings.Join(data, "\n"))
+				if err != nil {
+					return err
+				}
+			}
+			event, data = "", nil
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+
+	return scanner.Err()
+}
+
+// formatEvent describes an event from the stream on one line for the tail command
+func formatEvent(event, data string) (string, error) {
+	switch event {
+	case StreamEventStatus:
+		var status WatchStatus
+		err := json.Unmarshal([]byte(data), &status)
+		if err != nil {
+			return "", fmt.Errorf("error decoding status: %w", err)
+		}
+
+		failing := 0
+		for _, t := range status.Tours {
+			if t.ConsecutiveFailures > 0 {
+				failing++
+			}
+		}
+		line := fmt.Sprintf("status: %d tours, %d failing", len(status.Tours), failing)
+		if status.NextRun != nil {
+			line += ", next poll " + status.NextRun.Local().Format(time.TimeOnly)
+		}
+		return line, nil
+	case StreamEventNotification:
+		var n NotificationEvent
+		err := json.Unmarshal([]byte(data), &n)
+		if err != nil {
+			return "", fmt.Errorf("error decoding notification: %w", err)
+		}
+		return fmt.Sprintf("notification: %s: %s", n.Title, strings.ReplaceAll(n.Message, "\n", ", ")), nil
+	}
+
+	var e tours.Event
+	err := json.Unmarshal([]byte(data), &e)
+	if err != nil {
+		return "", fmt.Errorf("error decoding event: %w", err)
+	}
+
+	line := fmt.Sprintf("%s: %s", e.Type, e.TourName)
+	if e.Date != nil {
+		line += " " + e.Date.Format("2006-01-02 15:04")
+	}
+	switch {
+	case e.Change != nil && e.Change.Type == tours.ChangePriceChanged:
+		line += fmt.Sprintf(" (%s -> %s)", tours.FormatPrice(e.Change.PreviousPrice), e.Change.Availability.AdultPrice())
+	case e.Change != nil:
+		line += fmt.Sprintf(" (%d -> %d vacancies)", e.Change.PreviousVacancies, e.Change.Vacancies)
+	case e.Error != "":
+		line += ": " + e.Error
+	}
+	return line, nil
+}
+
+// TailEvents prints events from the server's event stream until the context is done. It reconnects
+// if the connection is lost. If raw is true, the event JSON is printed instead of a description
+func TailEvents(ctx context.Context, w io.Writer, url, apiKey string, types []string, raw bool) error {
+	if len(types) > 0 {
+		url += "?type=" + strings.Join(types, ",")
+	}
+
+	for {
+		retry, err := tailEvents(ctx, w, url, apiKey, raw)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if !retry {
+			return err
+		}
+		if err != nil {
+			fmt.Fprintf(w, "error reading events, reconnecting: %v\n", err)
+		}
+
+		select {
+		case <-time.After(5 * time.Second):
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// tailEvents reads events until the connection ends. It returns false if it shouldn't be retried
+// because the request isn't valid
+func tailEvents(ctx context.Context, w io.Writer, url, apiKey string, raw bool) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+	if err != nil {
+		return false, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("error connecting: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		// client errors like a missing API key won't be fixed by retrying
+		retry := resp.StatusCode >= http.StatusInternalServerError
+		return retry, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	return true, ReadEventStream(resp.Body, func(event, data string) error {
+		line := data
+		if !raw {
+			line, err = formatEvent(event, data)
+			if err != nil {
+				return err
+			}
+		}
+		_, err = fmt.Fprintf(w, "%s %s\n", time.Now().Format(time.TimeOnly), line)
+		return err
+	})
+}
@@ -0,0 +1,106 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"walks-of-italy/storage"
+	"walks-of-italy/tours"
+
+	"github.com/google/uuid"
+)
+
+func TestReadEventStream(t *testing.T) {
+	input := "event: status\ndata: {\"tours\":[]}\n\n: keep-alive\n\ndata: line 1\ndata: line 2\n\n"
+
+	var events, data []string
+	err := ReadEventStream(strings.NewReader(input), func(event, d string) error {
+		events = append(events, event)
+		data = append(data, d)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(events, ",") != "status,message" {
+		t.Errorf("unexpected events: %v", events)
+	}
+	if data[0] != `{"tours":[]}` || data[1] != "line 1\nline 2" {
+		t.Errorf("unexpected data: %q", data)
+	}
+}
+
+func TestGetEvents(t *testing.T) {
+	sc, err := storage.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sc.Close()
+
+	a := New("", "", sc, nil)
+	server := httptest.NewServer(http.HandlerFunc(a.GetEvents))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"?type=status,new_latest_date", http.NoBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Errorf("unexpected content type: %q", resp.Header.Get("Content-Type"))
+	}
+
+	tour := tours.TourDetail{Name: "Tour", ProductID: uuid.New()}
+	now := time.Now()
+	go func() {
+		// wait for the subscriber before publishing
+		for a.stream.subscriberCount() == 0 {
+			time.Sleep(10 * time.Millisecond)
+		}
+		a.publish(ctx,
+			tours.NewChangeEvent(tour, tours.Change{Type: tours.ChangeSoldOut, Slot: now}, now),
+			tours.NewLatestDateEvent(tour, tours.AvailabilityDetail{LocalDateTimeStart: now}, now),
+		)
+	}()
+
+	var lines []string
+	errDone := errors.New("done")
+	err = ReadEventStream(resp.Body, func(event, data string) error {
+		line, err := formatEvent(event, data)
+		if err != nil {
+			return err
+		}
+		lines = append(lines, line)
+		if event == string(tours.EventNewLatestDate) {
+			return errDone
+		}
+		return nil
+	})
+	if !errors.Is(err, errDone) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the sold out event is filtered out
+	expected := []string{
+		"status: 0 tours, 0 failing",
+		"new_latest_date: Tour " + now.Format("2006-01-02 15:04"),
+	}
+	if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected events:\n%s", strings.Join(lines, "\n"))
+	}
+}
@@ -0,0 +1,312 @@
+package app
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"walks-of-italy/storage/db"
+	"walks-of-italy/tours"
+
+	"github.com/calvinmclean/babyapi"
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+)
+
+// Format is a response format for endpoints that support content negotiation
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatCSV  Format = "csv"
+	FormatHTML Format = "html"
+	FormatText Format = "text"
+)
+
+var formats = []Format{FormatJSON, FormatCSV, FormatHTML, FormatText}
+
+// ContentType is the media type used in Accept and Content-Type headers
+func (f Format) ContentType() string {
+	switch f {
+	case FormatJSON:
+		return "application/json"
+	case FormatCSV:
+		return "text/csv"
+	case FormatHTML:
+		return "text/html"
+	default:
+		return "text/plain"
+	}
+}
+
+// negotiateFormat gets the response format from the format query parameter, or the first supported
+// type in the Accept header. The fallback is used if neither of them has a supported format
+func negotiateFormat(r *http.Request, fallback Format) (Format, error) {
+	if f := r.URL.Query().Get("format"); f != "" {
+		format := Format(f)
+		if !slices.Contains(formats, format) {
+			return "", fmt.Errorf("invalid format %q: expected one of json, csv, html, text", f)
+		}
+		return format, nil
+	}
+
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, _ := strings.Cut(accept, ";")
+		mediaType = strings.TrimSpace(mediaType)
+		for _, format := range formats {
+			if format.ContentType() == mediaType {
+				return format, nil
+			}
+		}
+	}
+
+	return fallback, nil
+}
+
+// LatestSummary is the response for the summary of all tours' latest dates
+type LatestSummary struct {
+	Items []LatestSummaryItem `json:"items"`
+}
+
+// LatestSummaryItem is a tour's latest date and when it was first seen. Prediction is omitted if
+// there aren't enough releases to predict the next one
+type LatestSummaryItem struct {
+	TourID     uuid.UUID                `json:"tourId"`
+	Name       string                   `json:"name"`
+	Link       string                   `json:"link"`
+	LatestDate time.Time                `json:"latestDate"`
+	RecordedAt time.Time                `json:"recordedAt"`
+	Prediction *tours.ReleasePrediction `json:"prediction,omitempty"`
+}
+
+// TourDates is the response for the summary of a tour's dates for the next year
+type TourDates struct {
+	TourID uuid.UUID  `json:"tourId"`
+	Name   string     `json:"name"`
+	Start  tours.Date `json:"start"`
+	End    tours.Date `json:"end"`
+	Items  []TourDate `json:"items"`
+}
+
+// TourDate is a single slot. AdultPrice is in cents
+type TourDate struct {
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	Available  bool      `json:"available"`
+	Vacancies  int       `json:"vacancies"`
+	AdultPrice int       `json:"adultPrice"`
+}
+
+// Price formats the adult price in USD
+func (d TourDate) Price() string {
+	return tours.FormatPrice(d.AdultPrice)
+}
+
+// tourSummary is a tour's latest date and predicted next release for the summary page
+type tourSummary struct {
+	db.GetAllLatestAvailabilitiesRow
+	Prediction *tours.ReleasePrediction
+}
+
+// ReleaseInterval formats the predicted time between releases in days, or hours if it's less than
+// two days
+func (s tourSummary) ReleaseInterval() string {
+	if s.Prediction == nil {
+		return ""
+	}
+	interval := s.Prediction.Interval.Duration
+	if interval < 48*time.Hour {
+		return fmt.Sprintf("%.0f hours", interval.Hours())
+	}
+	return fmt.Sprintf("%.0f days", interval.Hours()/24)
+}
+
+// SummarizeLatestAvailabilities shows each tour's latest date and predicted next release. It's an
+// HTML page by default, but also supports JSON, CSV, and text using the Accept header or format
+// query parameter
+func (a *App) SummarizeLatestAvailabilities(w http.ResponseWriter, r *http.Request) render.Renderer {
+	format, err := negotiateFormat(r, FormatHTML)
+	if err != nil {
+		return babyapi.ErrInvalidRequest(err)
+	}
+
+	availabilities, err := a.sc.GetAllLatestAvailabilities(r.Context())
+	if err != nil {
+		return babyapi.ErrInvalidRequest(fmt.Errorf("error getting availabilities: %w", err))
+	}
+
+	summaries := []tourSummary{}
+	for _, availability := range availabilities {
+		prediction, err := a.predictRelease(r.Context(), availability.Uuid, time.Now())
+		if err != nil {
+			return babyapi.InternalServerError(err)
+		}
+		summaries = append(summaries, tourSummary{availability, prediction})
+	}
+
+	setFormatHeaders(w, format)
+	switch format {
+	case FormatJSON:
+		render.JSON(w, r, latestSummary(summaries))
+	case FormatCSV:
+		err = latestSummaryCSV(w, summaries)
+	case FormatText:
+		err = latestSummaryText(w, availabilities)
+	default:
+		a.renderPage(w, http.StatusOK, "summary", summaries)
+	}
+	if err != nil {
+		a.logger.Error("error writing summary", "format", format, "err", err)
+	}
+
+	return nil
+}
+
+// SummarizeTourDates shows a tour's slots for the next year, using cached availability when it's
+// fresh. It's a text table by default, but also supports JSON, CSV, and HTML using the Accept header
+// or format query parameter
+func (a *App) SummarizeTourDates(w http.ResponseWriter, r *http.Request, td *tours.TourDetail) {
+	format, err := negotiateFormat(r, FormatText)
+	if err != nil {
+		_ = render.Render(w, r, babyapi.ErrInvalidRequest(err))
+		return
+	}
+
+	start := tours.DateFromTime(time.Now().UTC())
+	end := start.Add(1, 0, 0)
+	availability, _, err := a.cachedAvailability(r.Context(), *td, start, end)
+	if err != nil {
+		_ = render.Render(w, r, babyapi.ErrInvalidRequest(fmt.Errorf("error getting availability: %w", err)))
+		return
+	}
+
+	err = a.writeTourDates(w, r, format, newTourDates(td, start, end, availability), availability)
+	if err != nil {
+		a.logger.Error("error writing summary", "tour_id", td.ProductID, "format", format, "err", err)
+	}
+}
+
+func setFormatHeaders(w http.ResponseWriter, format Format) {
+	w.Header().Set("Vary", "Accept")
+	if format != FormatJSON {
+		w.Header().Set("Content-Type", format.ContentType()+"; charset=utf-8")
+	}
+}
+
+func latestSummary(summaries []tourSummary) *LatestSummary {
+	result := &LatestSummary{Items: []LatestSummaryItem{}}
+	for _, s := range summaries {
+		result.Items = append(result.Items, LatestSummaryItem{
+			TourID:     s.Uuid,
+			Name:       s.Name,
+			Link:       s.Link,
+			LatestDate: s.AvailabilityDate,
+			RecordedAt: s.RecordedAt,
+			Prediction: s.Prediction,
+		})
+	}
+	return result
+}
+
+func latestSummaryCSV(w io.Writer, summaries []tourSummary) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"tour_id", "name", "link", "latest_date", "recorded_at", "next_release_start", "next_release_end", "confidence"})
+	for _, s := range summaries {
+		record := []string{
+			s.Uuid.String(),
+			s.Name,
+			s.Link,
+			s.AvailabilityDate.Format(time.DateOnly),
+			s.RecordedAt.Format(time.RFC3339),
+			"", "", "",
+		}
+		if s.Prediction != nil {
+			record[5] = s.Prediction.Start.Format(time.RFC3339)
+			record[6] = s.Prediction.End.Format(time.RFC3339)
+			record[7] = string(s.Prediction.Confidence)
+		}
+		_ = cw.Write(record)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// latestSummaryText writes a table of the latest dates, which is also used by the CLI
+func latestSummaryText(w io.Writer, availabilities []db.GetAllLatestAvailabilitiesRow) error {
+	var sb strings.Builder
+	sb.WriteString(`
+Tour Name                                                   | Available Date | Opened At
+------------------------------------------------------------|----------------|----------------
+`)
+	for _, a := range availabilities {
+		fmt.Fprintf(&sb, "%s | %s     | %s\n", truncate(a.Name, 59), a.AvailabilityDate.Format("2006-01-02"), a.RecordedAt.Format("2006-01-02 15:04:05"))
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// truncate pads or shortens the string to the length
+func truncate(s string, length int) string {
+	if len(s) <= length {
+		return s + strings.Repeat(" ", length-len(s))
+	}
+	return s[:length-3] + "..."
+}
+
+func newTourDates(td *tours.TourDetail, start, end tours.Date, availability tours.Availabilities) *TourDates {
+	return &TourDates{
+		TourID: td.ProductID,
+		Name:   td.Name,
+		Start:  start,
+		End:    end,
+		Items:  tourDateItems(availability),
+	}
+}
+
+func tourDateItems(availability tours.Availabilities) []TourDate {
+	result := []TourDate{}
+	for _, a := range availability {
+		result = append(result, TourDate{
+			Start:      a.LocalDateTimeStart,
+			End:        a.LocalDateTimeEnd,
+			Available:  a.Available,
+			Vacancies:  a.Vacancies,
+			AdultPrice: a.AdultRetail(),
+		})
+	}
+	return result
+}
+
+func (a *App) writeTourDates(w http.ResponseWriter, r *http.Request, format Format, dates *TourDates, availability tours.Availabilities) error {
+	setFormatHeaders(w, format)
+	switch format {
+	case FormatJSON:
+		render.JSON(w, r, dates)
+		return nil
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		_ = cw.Write([]string{"start", "end", "available", "vacancies", "adult_price"})
+		for _, d := range dates.Items {
+			_ = cw.Write([]string{
+				d.Start.Format(time.RFC3339),
+				d.End.Format(time.RFC3339),
+				strconv.FormatBool(d.Available),
+				strconv.Itoa(d.Vacancies),
+				strconv.Itoa(d.AdultPrice),
+			})
+		}
+		cw.Flush()
+		return cw.Error()
+	case FormatHTML:
+		a.renderPage(w, http.StatusOK, "tour_dates", dates)
+		return nil
+	default:
+		return availability.PrettySummary(w)
+	}
+}
@@ -0,0 +1,196 @@
+package app
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"walks-of-italy/storage"
+	"walks-of-italy/storage/db"
+	"walks-of-italy/tours"
+
+	"github.com/calvinmclean/babyapi"
+	"github.com/google/uuid"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		accept   string
+		expected Format
+		err      bool
+	}{
+		{"Default", "/", "", FormatHTML, false},
+		{"AnyType", "/", "*/*", FormatHTML, false},
+		{"Accept", "/", "application/json", FormatJSON, false},
+		{"FirstSupported", "/", "application/xml, text/csv;q=0.9, text/plain", FormatCSV, false},
+		{"Browser", "/", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", FormatHTML, false},
+		{"QueryOverridesAccept", "/?format=text", "application/json", FormatText, false},
+		{"InvalidQuery", "/?format=xml", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			r.Header.Set("Accept", tt.accept)
+
+			format, err := negotiateFormat(r, FormatHTML)
+			if (err != nil) != tt.err {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if format != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, format)
+			}
+		})
+	}
+}
+
+func TestSummarizeLatestAvailabilities(t *testing.T) {
+	ctx := context.Background()
+
+	sc, err := storage.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sc.Close()
+
+	tour := tours.TourDetail{Name: "Vatican, Sistine Chapel", Link: "https://example.com/vatican", ProductID: uuid.New()}
+	err = sc.Set(ctx, &tour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sc.AddLatestAvailability(ctx, db.AddLatestAvailabilityParams{
+		TourUuid:         tour.ProductID,
+		AvailabilityDate: time.Date(2026, time.March, 31, 0, 0, 0, 0, time.UTC),
+		RawData:          "[]",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := New("", "", sc, nil)
+	handler := babyapi.Handler(a.SummarizeLatestAvailabilities)
+
+	get := func(t *testing.T, path, accept string) *httptest.ResponseRecorder {
+		t.Helper()
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		handler(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("unexpected status %d: %s", w.Code, w.Body.String())
+		}
+		return w
+	}
+
+	t.Run("JSON", func(t *testing.T) {
+		w := get(t, "/tours/summary", "application/json")
+
+		var summary LatestSummary
+		err := json.Unmarshal(w.Body.Bytes(), &summary)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(summary.Items) != 1 || summary.Items[0].TourID != tour.ProductID || summary.Items[0].Prediction != nil {
+			t.Errorf("unexpected summary: %+v", summary)
+		}
+		if !strings.Contains(w.Body.String(), `"latestDate":"2026-03-31T00:00:00Z"`) {
+			t.Errorf("unexpected JSON: %s", w.Body.String())
+		}
+	})
+
+	t.Run("CSV", func(t *testing.T) {
+		w := get(t, "/tours/summary?format=csv", "")
+		if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/csv") {
+			t.Errorf("unexpected content type %q", w.Header().Get("Content-Type"))
+		}
+
+		records, err := csv.NewReader(w.Body).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != 2 || records[1][1] != tour.Name || records[1][3] != "2026-03-31" {
+			t.Errorf("unexpected records: %v", records)
+		}
+	})
+
+	t.Run("Text", func(t *testing.T) {
+		w := get(t, "/tours/summary", "text/plain")
+		if !strings.Contains(w.Body.String(), "Vatican, Sistine Chapel") || !strings.Contains(w.Body.String(), "| 2026-03-31") {
+			t.Errorf("unexpected text: %s", w.Body.String())
+		}
+	})
+
+	t.Run("HTML", func(t *testing.T) {
+		w := get(t, "/tours/summary", "")
+		if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") || !strings.Contains(w.Body.String(), "<!doctype html>") {
+			t.Errorf("unexpected HTML response %q", w.Header().Get("Content-Type"))
+		}
+	})
+}
+
+func TestWriteTourDates(t *testing.T) {
+	start := time.Date(2025, time.November, 3, 9, 0, 0, 0, time.UTC)
+	availability := tours.Availabilities{
+		{
+			LocalDateTimeStart: start,
+			LocalDateTimeEnd:   start.Add(3 * time.Hour),
+			Available:          true,
+			Vacancies:          4,
+			UnitPricing:        []tours.UnitPricing{{UnitType: "ADULT", Retail: 9900}},
+		},
+		{LocalDateTimeStart: start.Add(24 * time.Hour)},
+	}
+	td := &tours.TourDetail{Name: "Vatican", ProductID: uuid.New()}
+	dates := newTourDates(td, tours.NewDate(2025, time.November, 1), tours.NewDate(2026, time.November, 1), availability)
+
+	tests := []struct {
+		format      Format
+		contentType string
+		contains    string
+	}{
+		{FormatJSON, "application/json", `"adultPrice":9900`},
+		{FormatCSV, "text/csv", "2025-11-03T09:00:00Z,2025-11-03T12:00:00Z,true,4,9900"},
+		{FormatHTML, "text/html", "<td>$99.00</td>"},
+		{FormatText, "text/plain", "$99.00 | 4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			w := httptest.NewRecorder()
+			err := (&App{templates: &pageTemplates{}}).writeTourDates(w, httptest.NewRequest(http.MethodGet, "/", nil), tt.format, dates, availability)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(w.Header().Get("Content-Type"), tt.contentType) {
+				t.Errorf("expected content type %q, got %q", tt.contentType, w.Header().Get("Content-Type"))
+			}
+			if !strings.Contains(w.Body.String(), tt.contains) {
+				t.Errorf("expected response to contain %q: %s", tt.contains, w.Body.String())
+			}
+		})
+	}
+
+	t.Run("TemplateError", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, "pages", "tour_dates.html"), `{{ define "content" }}<p>partial output</p>{{ .Missing }}{{ end }}`)
+
+		a := (&App{templates: &pageTemplates{}, logger: *slog.Default()}).WithTemplates(dir)
+		w := httptest.NewRecorder()
+		err := a.writeTourDates(w, httptest.NewRequest(http.MethodGet, "/", nil), FormatHTML, dates, availability)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if w.Code != http.StatusInternalServerError || strings.Contains(w.Body.String(), "partial output") {
+			t.Errorf("expected only an internal server error, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+}
@@ -0,0 +1,243 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"walks-of-italy/config"
+	"walks-of-italy/storage"
+	"walks-of-italy/tours"
+
+	"github.com/google/uuid"
+)
+
+const tourSyncInterval = 10 * time.Second
+
+// ErrEmptySync is returned when a sync would delete every tour because there are no desired tours
+var ErrEmptySync = errors.New("refusing to delete all tours because there are no tours to sync: allow an empty sync to do this")
+
+// MissingTours is what to do with stored tours that are not in the sync file
+type MissingTours string
+
+const (
+	MissingKeep   MissingTours = "keep"
+	MissingPause  MissingTours = "pause"
+	MissingDelete MissingTours = "delete"
+)
+
+func (m MissingTours) Validate() error {
+	switch m {
+	case MissingKeep, MissingPause, MissingDelete:
+		return nil
+	default:
+		return fmt.Errorf("invalid missing tours action %q: expected one of keep, pause, delete", m)
+	}
+}
+
+// SyncAction is how a tour is changed by a sync
+type SyncAction string
+
+const (
+	SyncCreate SyncAction = "create"
+	SyncUpdate SyncAction = "update"
+	SyncPause  SyncAction = "pause"
+	SyncDelete SyncAction = "delete"
+)
+
+// TourSyncChange is a change that a sync makes, or would make for a dry run. Fields lists the
+// fields that are different for updates
+type TourSyncChange struct {
+	Action SyncAction
+	Tour   *tours.TourDetail
+	Fields []string
+}
+
+func (c TourSyncChange) String() string {
+	symbol := map[SyncAction]string{
+		SyncCreate: "+",
+		SyncUpdate: "~",
+		SyncPause:  "!",
+		SyncDelete: "-",
+	}[c.Action]
+
+	s := fmt.Sprintf("%s %s %q (%s)", symbol, c.Action, c.Tour.Name, c.Tour.ProductID)
+	if len(c.Fields) > 0 {
+		s += ": " + strings.Join(c.Fields, ", ")
+	}
+	return s
+}
+
+// PlanTourSync compares the stored tours with the desired tours and returns the changes needed to make
+// them match. Creates and updates are ordered like the desired tours, followed by missing tours
+func PlanTourSync(existing, desired []*tours.TourDetail, missing MissingTours) []TourSyncChange {
+	stored := map[uuid.UUID]*tours.TourDetail{}
+	for _, td := range existing {
+		stored[td.ProductID] = td
+	}
+
+	var changes []TourSyncChange
+	for _, td := range desired {
+		current, ok := stored[td.ProductID]
+		delete(stored, td.ProductID)
+		if !ok {
+			changes = append(changes, TourSyncChange{Action: SyncCreate, Tour: td})
+			continue
+		}
+
+		fields := changedFields(current, td)
+		if len(fields) > 0 {
+			changes = append(changes, TourSyncChange{Action: SyncUpdate, Tour: td, Fields: fields})
+		}
+	}
+
+	for _, td := range existing {
+		if _, ok := stored[td.ProductID]; !ok {
+			continue
+		}
+
+		switch {
+		case missing == MissingDelete:
+			changes = append(changes, TourSyncChange{Action: SyncDelete, Tour: td})
+		case missing == MissingPause && !td.Paused:
+			paused := *td
+			paused.Paused = true
+			changes = append(changes, TourSyncChange{Action: SyncPause, Tour: &paused})
+		}
+	}
+
+	return changes
+}
+
+func changedFields(current, desired *tours.TourDetail) []string {
+	var fields []string
+	add := func(name string, changed bool) {
+		if changed {
+			fields = append(fields, name)
+		}
+	}
+
+	add("name", current.Name != desired.Name)
+	add("link", current.Link != desired.Link)
+	add("apiUrl", current.ApiUrl != desired.ApiUrl)
+	add("lastMinute", current.LastMinute != desired.LastMinute)
+	add("notifications", current.Notifications != desired.Notifications)
+	add("paused", current.Paused != desired.Paused)
+	add("tags", !slices.Equal(current.Tags, desired.Tags))
+
+	return fields
+}
+
+// SyncTours makes the stored tours match the desired tours. Nothing is changed if dryRun is true.
+// It returns the changes that were made. Deleting missing tours with no desired tours is refused
+// unless allowEmpty is true, since an empty or truncated file would delete every tour
+func SyncTours(ctx context.Context, sc *storage.Client, desired []*tours.TourDetail, missing MissingTours, allowEmpty, dryRun bool) ([]TourSyncChange, error) {
+	err := missing.Validate()
+	if err != nil {
+		return nil, err
+	}
+	if missing == MissingDelete && len(desired) == 0 && !allowEmpty {
+		return nil, ErrEmptySync
+	}
+
+	existing, err := sc.GetAll(ctx, url.Values{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting tours: %w", err)
+	}
+
+	changes := PlanTourSync(existing, desired, missing)
+	if dryRun {
+		return changes, nil
+	}
+
+	for _, c := range changes {
+		switch c.Action {
+		case SyncDelete:
+			err = sc.Delete(ctx, c.Tour.GetID())
+		default:
+			err = sc.Set(ctx, c.Tour)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error applying %s for tour %q: %w", c.Action, c.Tour.Name, err)
+		}
+	}
+
+	return changes, nil
+}
+
+// LoadTourFile reads tours from a YAML file. It uses the same format as the config file, so the tours
+// from a full config file can be synced
+func LoadTourFile(filename string) ([]*tours.TourDetail, error) {
+	cfg, err := config.Load(filename)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.TourDetails(), nil
+}
+
+// runTourSync syncs tours from the file whenever its contents change. Invalid files are logged and
+// skipped so a bad commit doesn't remove tours
+func (a *App) runTourSync(ctx context.Context) {
+	var lastHash []byte
+	sync := func() {
+		f, err := os.Open(a.syncFile)
+		if err != nil {
+			a.logger.Error("error opening tour sync file", "file", a.syncFile, "err", err)
+			return
+		}
+		defer f.Close()
+
+		h := sha256.New()
+		_, err = io.Copy(h, f)
+		if err != nil {
+			a.logger.Error("error reading tour sync file", "file", a.syncFile, "err", err)
+			return
+		}
+		hash := h.Sum(nil)
+		if bytes.Equal(hash, lastHash) {
+			return
+		}
+
+		desired, err := LoadTourFile(a.syncFile)
+		if err != nil {
+			a.logger.Error("error loading tour sync file", "file", a.syncFile, "err", err)
+			return
+		}
+
+		changes, err := SyncTours(ctx, a.sc, desired, a.syncMissing, a.syncAllowEmpty, false)
+		if err != nil {
+			a.logger.Error("error syncing tours", "file", a.syncFile, "err", err)
+			return
+		}
+		lastHash = hash
+
+		for _, c := range changes {
+			if c.Action == SyncDelete {
+				a.status.remove(c.Tour.ProductID)
+			}
+			a.logger.Info("synced tour", "action", c.Action, "tour_id", c.Tour.ProductID, "name", c.Tour.Name, "fields", c.Fields)
+		}
+	}
+
+	sync()
+
+	ticker := time.NewTicker(tourSyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			sync()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"walks-of-italy/storage"
+	"walks-of-italy/tours"
+
+	"github.com/google/uuid"
+)
+
+func TestSyncTours(t *testing.T) {
+	ctx := context.Background()
+
+	sc, err := storage.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sc.Close()
+
+	unchanged := &tours.TourDetail{Name: "Unchanged", ProductID: uuid.New()}
+	updated := &tours.TourDetail{Name: "Old Name", ProductID: uuid.New()}
+	missing := &tours.TourDetail{Name: "Missing", ProductID: uuid.New()}
+	for _, td := range []*tours.TourDetail{unchanged, updated, missing} {
+		err = sc.Set(ctx, td)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	created := &tours.TourDetail{Name: "Created", ProductID: uuid.New()}
+	desired := []*tours.TourDetail{
+		{Name: "Unchanged", ProductID: unchanged.ProductID},
+		{Name: "New Name", ProductID: updated.ProductID, Paused: true},
+		created,
+	}
+
+	t.Run("DryRun", func(t *testing.T) {
+		changes, err := SyncTours(ctx, sc, desired, MissingDelete, false, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := []string{
+			`~ update "New Name" (` + updated.ProductID.String() + `): name, paused`,
+			`+ create "Created" (` + created.ProductID.String() + `)`,
+			`- delete "Missing" (` + missing.ProductID.String() + `)`,
+		}
+		if len(changes) != len(expected) {
+			t.Fatalf("expected %d changes, got %v", len(expected), changes)
+		}
+		for i, c := range changes {
+			if c.String() != expected[i] {
+				t.Errorf("expected %q, got %q", expected[i], c.String())
+			}
+		}
+
+		all, err := sc.GetAll(ctx, url.Values{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(all) != 3 {
+			t.Errorf("expected dry run to not change tours")
+		}
+	})
+
+	t.Run("Pause", func(t *testing.T) {
+		_, err := SyncTours(ctx, sc, desired, MissingPause, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		td, err := sc.Get(ctx, missing.GetID())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !td.Paused {
+			t.Errorf("expected missing tour to be paused")
+		}
+
+		td, err = sc.Get(ctx, updated.GetID())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if td.Name != "New Name" || !td.Paused {
+			t.Errorf("unexpected updated tour: %+v", td)
+		}
+
+		changes, err := SyncTours(ctx, sc, desired, MissingPause, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(changes) != 0 {
+			t.Errorf("expected no changes after sync, got %v", changes)
+		}
+	})
+
+	t.Run("EmptyDelete", func(t *testing.T) {
+		_, err := SyncTours(ctx, sc, nil, MissingDelete, false, false)
+		if !errors.Is(err, ErrEmptySync) {
+			t.Fatalf("expected empty sync error, got %v", err)
+		}
+
+		all, err := sc.GetAll(ctx, url.Values{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(all) != 4 {
+			t.Errorf("expected no tours to be deleted, got %d", len(all))
+		}
+
+		changes, err := SyncTours(ctx, sc, nil, MissingDelete, true, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(changes) != 4 {
+			t.Errorf("expected all tours to be deleted when allowed, got %v", changes)
+		}
+	})
+
+	t.Run("InvalidMissing", func(t *testing.T) {
+		_, err := SyncTours(ctx, sc, desired, "archive", false, true)
+		if err == nil {
+			t.Errorf("expected error")
+		}
+	})
+}
@@ -1,34 +1,142 @@
 package app
 
-const toursSummaryHTML = `<!doctype html>
-<html lang="en">
-    <head>
-        <meta charset="UTF-8" />
-        <title>Tours Availability Summary</title>
-        <link
-            rel="stylesheet"
-            href="https://cdn.jsdelivr.net/npm/uikit@3.23.7/dist/css/uikit.min.css"
-        />
-
-        <script src="https://cdn.jsdelivr.net/npm/uikit@3.23.7/dist/js/uikit.min.js"></script>
-        <script src="https://cdn.jsdelivr.net/npm/uikit@3.23.7/dist/js/uikit-icons.min.js"></script>
-    </head>
-    <body>
-    	{{ range . -}}
-        <div class="uk-container uk-margin-top uk-margin-bottom">
-            <div
-                class="uk-card uk-card-default uk-card-body uk-margin-auto"
-            >
-                <h3 class="uk-card-title"><a href={{ .Link }}>{{ .Name }}</a></h3>
-                <p class="uk-text-meta">{{ .Uuid }}</p>
-                <ul class="uk-list uk-list-divider">
-                    <li>
-                        <strong>Latest Tour Date:</strong> {{ .AvailabilityDate.Format "Mon, 02 Jan 2006 15:04:05 MST" }}
-                    </li>
-                    <li><strong>Recorded At:</strong> {{ .RecordedAt.Format "Mon, 02 Jan 2006 15:04:05 MST" }}</li>
-                </ul>
-            </div>
-        </div>
-        {{ end }}
-    </body>
-</html>`
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// staticFiles are served from /static. They are all in the repo so pages work without internet access
+//
+//go:embed static
+var staticFiles embed.FS
+
+// templateFiles has a layout, partials, and a file for each page. Pages define "title" and
+// "content", and can define "head" and "scripts" to add to the layout
+//
+//go:embed templates
+var templateFiles embed.FS
+
+const layoutTemplate = "layout.html"
+
+// pageTemplates parses pages from the embedded templates. If dir is set, its files replace embedded
+// files with the same path, so pages can be customized without rebuilding. Templates are parsed for
+// each page so changes show up right away
+type pageTemplates struct {
+	dir string
+}
+
+// WithTemplates uses templates from the directory instead of the embedded ones with the same path,
+// like pages/summary.html or partials/assets.html. Other templates are still embedded
+func (a *App) WithTemplates(dir string) *App {
+	a.templates = &pageTemplates{dir}
+	return a
+}
+
+// staticHandler serves the embedded static files
+func staticHandler() http.Handler {
+	files, err := fs.Sub(staticFiles, "static")
+	if err != nil {
+		panic(err)
+	}
+	return http.StripPrefix("/static/", http.FileServerFS(files))
+}
+
+// page parses the layout and partials with the page. Executing the result renders the full page
+func (t *pageTemplates) page(name string) (*template.Template, error) {
+	partials, err := t.partials()
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl := template.New(layoutTemplate)
+	for _, filename := range slices.Concat([]string{layoutTemplate}, partials, []string{"pages/" + name + ".html"}) {
+		data, err := t.readFile(filename)
+		if err != nil {
+			return nil, fmt.Errorf("error reading template %q: %w", filename, err)
+		}
+
+		next := tmpl
+		if filename != layoutTemplate {
+			next = tmpl.New(filename)
+		}
+		_, err = next.Parse(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("error parsing template %q: %w", filename, err)
+		}
+	}
+
+	return tmpl, nil
+}
+
+// execute renders the page
+func (t *pageTemplates) execute(w io.Writer, name string, data any) error {
+	tmpl, err := t.page(name)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(w, data)
+}
+
+// partials lists the embedded partials and any new ones in the directory
+func (t *pageTemplates) partials() ([]string, error) {
+	result, err := fs.Glob(templateFiles, "templates/partials/*.html")
+	if err != nil {
+		return nil, err
+	}
+	for i, filename := range result {
+		result[i] = strings.TrimPrefix(filename, "templates/")
+	}
+
+	if t.dir == "" {
+		return result, nil
+	}
+
+	overrides, err := filepath.Glob(filepath.Join(t.dir, "partials", "*.html"))
+	if err != nil {
+		return nil, err
+	}
+	for _, filename := range overrides {
+		name := path.Join("partials", filepath.Base(filename))
+		if !slices.Contains(result, name) {
+			result = append(result, name)
+		}
+	}
+
+	return result, nil
+}
+
+func (t *pageTemplates) readFile(name string) ([]byte, error) {
+	if t.dir != "" {
+		data, err := os.ReadFile(filepath.Join(t.dir, filepath.FromSlash(name)))
+		if !errors.Is(err, fs.ErrNotExist) {
+			return data, err
+		}
+	}
+	return templateFiles.ReadFile(path.Join("templates", name))
+}
+
+// renderPage renders the full page before writing it, so template errors can still be a 500
+func (a *App) renderPage(w http.ResponseWriter, status int, name string, data any) {
+	var buf bytes.Buffer
+	err := a.templates.execute(&buf, name, data)
+	if err != nil {
+		a.logger.Error("error rendering page", "page", name, "err", err)
+		http.Error(w, "error rendering page", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = buf.WriteTo(w)
+}
@@ -0,0 +1,155 @@
+package app
+
+import (
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestPageTemplates(t *testing.T) {
+	t.Run("ParseAllPages", func(t *testing.T) {
+		pages, err := fs.Glob(templateFiles, "templates/pages/*.html")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pages) == 0 {
+			t.Fatal("expected embedded pages")
+		}
+
+		for _, page := range pages {
+			name := strings.TrimSuffix(path.Base(page), ".html")
+			_, err := (&pageTemplates{}).page(name)
+			if err != nil {
+				t.Errorf("error parsing %q: %v", name, err)
+			}
+		}
+	})
+
+	t.Run("Layout", func(t *testing.T) {
+		a := &App{templates: &pageTemplates{}}
+		w := httptest.NewRecorder()
+		a.renderPage(w, http.StatusUnauthorized, "login", loginPage{Error: "Invalid username or password"})
+
+		body := w.Body.String()
+		if w.Code != http.StatusUnauthorized || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+			t.Fatalf("unexpected response %d %q", w.Code, w.Header().Get("Content-Type"))
+		}
+		for _, expected := range []string{"<title>Log In</title>", `href="/static/css/ui.css"`, "Invalid username or password"} {
+			if !strings.Contains(body, expected) {
+				t.Errorf("expected page to contain %q:\n%s", expected, body)
+			}
+		}
+		if strings.Contains(body, "https://") {
+			t.Errorf("expected page to only use local assets:\n%s", body)
+		}
+	})
+
+	t.Run("Override", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, "pages", "login.html"), `{{ define "title" }}Sign In{{ end }}{{ define "content" }}{{ template "banner" }}{{ .Error }}{{ end }}`)
+		writeFile(t, filepath.Join(dir, "partials", "banner.html"), `{{ define "banner" }}<h1>Walks</h1>{{ end }}`)
+		writeFile(t, filepath.Join(dir, "partials", "assets.html"), `{{ define "assets" }}<link rel="stylesheet" href="/static/custom.css" />{{ end }}`)
+
+		a := &App{templates: &pageTemplates{}}
+		a.WithTemplates(dir)
+		w := httptest.NewRecorder()
+		a.renderPage(w, http.StatusOK, "login", loginPage{Error: "bad password"})
+
+		body := w.Body.String()
+		for _, expected := range []string{"<title>Sign In</title>", "<h1>Walks</h1>bad password", "/static/custom.css"} {
+			if !strings.Contains(body, expected) {
+				t.Errorf("expected page to contain %q:\n%s", expected, body)
+			}
+		}
+		if strings.Contains(body, "ui.css") {
+			t.Errorf("expected assets partial to be replaced:\n%s", body)
+		}
+
+		// pages that aren't in the directory are still embedded
+		_, err := a.templates.page("calendar")
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("InvalidOverride", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, "pages", "login.html"), `{{ define "content" }}{{ .Missing }}{{ end }}`)
+
+		a := (&App{logger: *slog.Default()}).WithTemplates(dir)
+		w := httptest.NewRecorder()
+		a.renderPage(w, http.StatusOK, "login", loginPage{})
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("expected internal server error, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+}
+
+func TestPageAssets(t *testing.T) {
+	pages, err := fs.Glob(templateFiles, "templates/pages/*.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// collect the static files referenced by the layout, partials, and pages
+	assets := map[string]bool{}
+	assetPattern := regexp.MustCompile(`(?:href|src)="(/static/[^"]+)"`)
+	for _, page := range pages {
+		tmpl, err := (&pageTemplates{}).page(strings.TrimSuffix(path.Base(page), ".html"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, tt := range tmpl.Templates() {
+			if tt.Tree == nil {
+				continue
+			}
+			for _, match := range assetPattern.FindAllStringSubmatch(tt.Tree.Root.String(), -1) {
+				assets[match[1]] = true
+			}
+		}
+	}
+	if len(assets) == 0 {
+		t.Fatal("expected pages to use static assets")
+	}
+
+	for asset := range assets {
+		t.Run(asset, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			staticHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, asset, nil))
+			if w.Code != http.StatusOK {
+				t.Errorf("unexpected response %d for %q", w.Code, asset)
+			}
+		})
+	}
+}
+
+func TestStaticHandler(t *testing.T) {
+	w := httptest.NewRecorder()
+	staticHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/static/js/live-updates.js", nil))
+
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/javascript") {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(w.Body.String(), "function liveUpdates(handlers)") {
+		t.Errorf("unexpected body: %s", w.Body.String())
+	}
+}
+
+func writeFile(t *testing.T, filename, data string) {
+	t.Helper()
+	err := os.MkdirAll(filepath.Dir(filename), 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filename, []byte(data), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
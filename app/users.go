@@ -0,0 +1,215 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"walks-of-italy/notify"
+	"walks-of-italy/tours"
+
+	"github.com/calvinmclean/babyapi"
+	"github.com/google/uuid"
+)
+
+// UserNotifierConfig has the server settings for notification backends that users can receive
+// notifications from. Users only set their own recipient, like a Pushover user key or email address
+type UserNotifierConfig struct {
+	PushoverAppToken string
+
+	SMTPAddr     string
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+
+	NtfyURL string
+}
+
+// Notifier creates a Notifier for each of the user's settings that has a matching backend. It returns
+// nil if the user has no usable settings
+func (c UserNotifierConfig) Notifier(u tours.User) (notify.Notifier, error) {
+	var notifiers notify.Multi
+	var errs []error
+
+	if u.Notifier.PushoverRecipient != "" && c.PushoverAppToken != "" {
+		n, err := notify.NewPushover(c.PushoverAppToken, u.Notifier.PushoverRecipient)
+		errs = append(errs, err)
+		if err == nil {
+			notifiers = append(notifiers, n)
+		}
+	}
+	if u.Notifier.Email != "" && c.SMTPAddr != "" {
+		n, err := notify.NewSMTP(c.SMTPAddr, c.SMTPUsername, c.SMTPPassword, c.SMTPFrom, []string{u.Notifier.Email})
+		errs = append(errs, err)
+		if err == nil {
+			notifiers = append(notifiers, n)
+		}
+	}
+	if u.Notifier.NtfyTopic != "" {
+		n, err := notify.NewNtfy(c.NtfyURL, u.Notifier.NtfyTopic, "")
+		errs = append(errs, err)
+		if err == nil {
+			notifiers = append(notifiers, n)
+		}
+	}
+
+	err := errors.Join(errs...)
+	if err != nil {
+		return nil, fmt.Errorf("error creating notifiers for user %q: %w", u.Username, err)
+	}
+	if len(notifiers) == 0 {
+		return nil, nil
+	}
+	return notifiers, nil
+}
+
+// WithUsers sends notifications to users that are subscribed to the tour or a matching rule, in addition
+// to the server's notifiers
+func (a *App) WithUsers(config UserNotifierConfig) *App {
+	a.userNotifiers = &config
+	return a
+}
+
+// sendTourNotification sends the notification to the server's notifiers and to each user that is
+// subscribed to the tour or one of the rules that matched
+func (a *App) sendTourNotification(ctx context.Context, tour tours.TourDetail, ruleIDs []uuid.UUID, n notify.Notification) {
+	if a.nc != nil {
+		err := a.nc.Send(ctx, n)
+		if err != nil {
+			a.logger.Error("error sending notification", "err", err)
+		}
+	}
+
+	if a.userNotifiers == nil {
+		return
+	}
+
+	err := a.notifySubscribers(ctx, tour.ProductID, ruleIDs, n)
+	if err != nil {
+		a.logger.Error("error sending notification to subscribers", "tour_id", tour.ProductID, "err", err)
+	}
+}
+
+// notifySubscribers sends the notification once to each user with a matching subscription. Like the
+// server's notifications, it goes through the user's Pipeline and is stored in the Outbox when they
+// are used. Errors for one user don't prevent sending to the others
+func (a *App) notifySubscribers(ctx context.Context, tourID uuid.UUID, ruleIDs []uuid.UUID, n notify.Notification) error {
+	subscriptions, err := a.sc.Users().Subscriptions(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting subscriptions: %w", err)
+	}
+
+	notified := map[uuid.UUID]bool{}
+	var errs []error
+	for _, s := range subscriptions {
+		if notified[s.UserID] || !s.Matches(tourID, ruleIDs) {
+			continue
+		}
+		notified[s.UserID] = true
+
+		u, err := a.sc.Users().Get(ctx, s.UserID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error getting user %q: %w", s.UserID, err))
+			continue
+		}
+
+		nc, err := a.userNotifiers.Notifier(*u)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if nc == nil {
+			continue
+		}
+
+		err = a.subscriberNotifier(u.ID).Send(ctx, n)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error sending to user %q: %w", u.Username, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// subscriberNotifier returns the Notifier for a user. It is set up like the server's Notifier, so
+// notifications go through a Pipeline for each user and then the Outbox, if they are used
+func (a *App) subscriberNotifier(userID uuid.UUID) notify.Notifier {
+	var next notify.Notifier = userNotifier{a, userID}
+	if a.outbox != nil {
+		next = a.outbox.ForUser(userID)
+	}
+	if a.pipelineConfig == nil {
+		return next
+	}
+
+	a.userPipelinesLock.Lock()
+	defer a.userPipelinesLock.Unlock()
+
+	p, ok := a.userPipelines[userID]
+	if !ok {
+		p = NewPipeline(next, *a.pipelineConfig, a.logger.With("user_id", userID))
+		a.userPipelines[userID] = p
+	}
+	return p
+}
+
+// runUserPipelines periodically flushes notifications held by each user's Pipeline until the
+// context is cancelled
+func (a *App) runUserPipelines(ctx context.Context) {
+	ticker := time.NewTicker(a.pipelineConfig.flushInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			a.userPipelinesLock.Lock()
+			pipelines := slices.Collect(maps.Values(a.userPipelines))
+			a.userPipelinesLock.Unlock()
+
+			for _, p := range pipelines {
+				err := p.Flush(ctx)
+				if err != nil {
+					a.logger.Error("error sending digest notification to user", "err", err)
+				}
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// notifierForUser creates a Notifier from the user's current settings. It returns nil if the user
+// doesn't exist or has no usable settings
+func (a *App) notifierForUser(ctx context.Context, userID uuid.UUID) (notify.Notifier, error) {
+	if a.userNotifiers == nil {
+		return nil, nil
+	}
+
+	u, err := a.sc.Users().Get(ctx, userID)
+	if errors.Is(err, babyapi.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting user %q: %w", userID, err)
+	}
+
+	return a.userNotifiers.Notifier(*u)
+}
+
+// userNotifier sends to a user without the Outbox. The user's notifiers are created when sending so
+// notifications held by the Pipeline use the latest settings
+type userNotifier struct {
+	a      *App
+	userID uuid.UUID
+}
+
+func (n userNotifier) Send(ctx context.Context, notification notify.Notification) error {
+	nc, err := n.a.notifierForUser(ctx, n.userID)
+	if err != nil || nc == nil {
+		return err
+	}
+	return nc.Send(ctx, notification)
+}
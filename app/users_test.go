@@ -0,0 +1,279 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"walks-of-italy/notify"
+	"walks-of-italy/storage"
+	"walks-of-italy/tours"
+
+	"github.com/google/uuid"
+)
+
+func TestNotifySubscribers(t *testing.T) {
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var topics []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		topics = append(topics, strings.TrimPrefix(r.URL.Path, "/"))
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	sc, err := storage.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sc.Close()
+
+	tour := tours.TourDetail{Name: "Tour", ProductID: uuid.New()}
+	rule := &tours.AlertRule{ID: uuid.New(), ChangeType: tours.ChangeReopened}
+
+	// alice subscribes to the tour and the rule, but should only be notified once
+	// bob subscribes to the rule, carol subscribes to another tour, and dave has no notifier settings
+	subscriptions := map[string][]tours.Subscription{
+		"alice": {{TourID: tour.ProductID}, {RuleID: rule.ID}},
+		"bob":   {{RuleID: rule.ID}},
+		"carol": {{TourID: uuid.New()}},
+		"dave":  {{TourID: tour.ProductID}},
+	}
+	for username, subs := range subscriptions {
+		u, err := tours.NewUser(username, "password")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if username != "dave" {
+			u.Notifier.NtfyTopic = username
+		}
+		err = sc.Users().Set(ctx, u)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, s := range subs {
+			s.ID = uuid.New()
+			s.UserID = u.ID
+			err = sc.Users().AddSubscription(ctx, &s)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	serverNotifier := &recordingNotifier{}
+	a := New("", "", sc, serverNotifier).WithUsers(UserNotifierConfig{NtfyURL: server.URL})
+
+	a.sendTourNotification(ctx, tour, []uuid.UUID{rule.ID}, notify.Notification{Title: "Reopened", Message: "Tour"})
+
+	if len(serverNotifier.sent) != 1 {
+		t.Errorf("expected server notification, got %d", len(serverNotifier.sent))
+	}
+
+	slices.Sort(topics)
+	if !slices.Equal(topics, []string{"alice", "bob"}) {
+		t.Errorf("unexpected user notifications: %v", topics)
+	}
+}
+
+func TestNotifySubscribersOutbox(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, time.May, 1, 9, 0, 0, 0, time.UTC)
+
+	var mu sync.Mutex
+	statusCodes := []int{http.StatusServiceUnavailable, http.StatusOK}
+	var received int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		received++
+		w.WriteHeader(statusCodes[0])
+		statusCodes = statusCodes[1:]
+	}))
+	defer server.Close()
+
+	sc, err := storage.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sc.Close()
+
+	tour := tours.TourDetail{Name: "Tour", ProductID: uuid.New()}
+	u, err := tours.NewUser("alice", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.Notifier.NtfyTopic = "alice"
+	err = sc.Users().Set(ctx, u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sc.Users().AddSubscription(ctx, &tours.Subscription{ID: uuid.New(), UserID: u.ID, TourID: tour.ProductID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := New("", "", sc, nil).
+		WithOutbox().
+		WithPipeline(PipelineConfig{DedupeWindow: time.Hour}).
+		WithUsers(UserNotifierConfig{NtfyURL: server.URL})
+	a.outbox.now = func() time.Time { return now }
+
+	// the duplicate is dropped by the user's Pipeline
+	n := notify.Notification{Title: "Reopened", Message: "Tour"}
+	a.sendTourNotification(ctx, tour, nil, n)
+	a.sendTourNotification(ctx, tour, nil, n)
+
+	stored, err := sc.ListNotifications(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 1 || stored[0].UserUuid != u.ID || stored[0].Status != NotificationPending {
+		t.Fatalf("expected one pending notification for the user, got %+v", stored)
+	}
+
+	now = now.Add(backoff(1))
+	err = a.outbox.retry(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err = sc.ListNotifications(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if received != 2 || stored[0].Status != NotificationSent || stored[0].Attempts != 2 {
+		t.Errorf("expected the user's notification to be retried, got %d requests and %+v", received, stored[0])
+	}
+
+	// notifications are canceled if the user removes their settings before they are sent
+	u.Notifier.NtfyTopic = ""
+	err = sc.Users().Set(ctx, u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = a.outbox.ForUser(u.ID).Send(ctx, notify.Notification{Title: "Sold Out", Message: "Tour"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err = sc.ListNotifications(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored[0].Status != NotificationCanceled || received != 2 {
+		t.Errorf("expected the notification to be canceled, got %+v", stored[0])
+	}
+}
+
+func TestLoginSession(t *testing.T) {
+	ctx := context.Background()
+
+	sc, err := storage.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sc.Close()
+
+	u, err := tours.NewUser("alice", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sc.Users().Set(ctx, u)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := New("", "", sc, nil)
+
+	login := func(password string) *httptest.ResponseRecorder {
+		form := url.Values{"username": {"alice"}, "password": {password}}
+		r := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		a.PostLogin(w, r)
+		return w
+	}
+
+	account := func(cookies []*http.Cookie) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/account", nil)
+		for _, c := range cookies {
+			r.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		a.sessionMiddleware(http.HandlerFunc(a.GetAccount)).ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("WrongPassword", func(t *testing.T) {
+		w := login("wrong password")
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("expected unauthorized, got %d", w.Code)
+		}
+	})
+
+	t.Run("NoSession", func(t *testing.T) {
+		w := account(nil)
+		if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/login" {
+			t.Errorf("expected redirect to login, got %d %q", w.Code, w.Header().Get("Location"))
+		}
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		w := login("password")
+		if w.Code != http.StatusSeeOther {
+			t.Fatalf("expected redirect, got %d", w.Code)
+		}
+
+		w = account(w.Result().Cookies())
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected account page, got %d", w.Code)
+		}
+		if !strings.Contains(w.Body.String(), "alice") {
+			t.Errorf("expected username in account page")
+		}
+	})
+
+	t.Run("UnknownUser", func(t *testing.T) {
+		form := url.Values{"username": {"bob"}, "password": {"password"}}
+		r := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.RemoteAddr = "192.0.2.2:1234"
+		w := httptest.NewRecorder()
+		a.PostLogin(w, r)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("expected unauthorized, got %d", w.Code)
+		}
+	})
+
+	t.Run("RateLimited", func(t *testing.T) {
+		for range maxLoginFailures {
+			w := login("wrong password")
+			if w.Code != http.StatusUnauthorized {
+				t.Fatalf("expected unauthorized, got %d", w.Code)
+			}
+		}
+
+		w := login("password")
+		if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+			t.Errorf("expected too many requests, got %d", w.Code)
+		}
+
+		now := time.Now().Add(loginFailureWindow)
+		a.loginLimiter.now = func() time.Time { return now }
+		w = login("password")
+		if w.Code != http.StatusSeeOther {
+			t.Errorf("expected login after the window, got %d", w.Code)
+		}
+	})
+}
@@ -0,0 +1,337 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"walks-of-italy/storage"
+	"walks-of-italy/storage/db"
+	"walks-of-italy/tours"
+
+	"github.com/calvinmclean/babyapi"
+	"github.com/go-chi/render"
+)
+
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+
+	// SignatureHeader contains the hex-encoded HMAC-SHA256 of "<timestamp>.<body>", using the webhook's
+	// secret as the key, in the format "sha256=<signature>"
+	SignatureHeader = "X-Walks-Signature"
+	// TimestampHeader is the Unix time that the request was signed. Receivers should reject old
+	// timestamps so a captured request can't be replayed
+	TimestampHeader = "X-Walks-Timestamp"
+	EventTypeHeader = "X-Walks-Event"
+	EventIDHeader   = "X-Walks-Delivery"
+
+	webhookMaxAttempts = 10
+	webhookTimeout     = 10 * time.Second
+)
+
+// WebhookDispatcher delivers events to matching webhooks. Each delivery is stored and then sent by
+// Run, so publishing never waits on a receiver. Failures are retried with backoff and responses are
+// kept in the webhook's delivery log
+type WebhookDispatcher struct {
+	sc     *storage.Client
+	client *http.Client
+	logger *slog.Logger
+	now    func() time.Time
+
+	// queued wakes up Run when new deliveries are stored
+	queued chan struct{}
+}
+
+func NewWebhookDispatcher(sc *storage.Client, logger *slog.Logger) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		sc:     sc,
+		client: &http.Client{Timeout: webhookTimeout},
+		logger: logger,
+		now:    func() time.Time { return time.Now().UTC() },
+		queued: make(chan struct{}, 1),
+	}
+}
+
+// SignPayload returns the signature used in the SignatureHeader for the timestamp from the
+// TimestampHeader. Receivers can use this to verify requests
+func SignPayload(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Publish stores a delivery for each webhook that matches the event. They are sent by Run
+func (d *WebhookDispatcher) Publish(ctx context.Context, e tours.Event) error {
+	webhooks, err := d.sc.Webhooks().GetAll(ctx, url.Values{})
+	if err != nil {
+		return fmt.Errorf("error getting webhooks: %w", err)
+	}
+
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("error marshalling event: %w", err)
+	}
+
+	var errs []error
+	for _, wh := range webhooks {
+		if !wh.Matches(e) {
+			continue
+		}
+
+		now := d.now()
+		delivery, err := d.sc.AddWebhookDelivery(ctx, db.AddWebhookDeliveryParams{
+			WebhookUuid:   wh.ID,
+			EventUuid:     e.ID,
+			EventType:     string(e.Type),
+			Payload:       string(payload),
+			Status:        DeliveryPending,
+			CreatedAt:     now,
+			NextAttemptAt: now,
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error storing delivery for webhook %q: %w", wh.ID, err))
+			continue
+		}
+		d.logger.Debug("queued webhook delivery", "webhook_id", wh.ID, "delivery_id", delivery.ID)
+
+		select {
+		case d.queued <- struct{}{}:
+		default:
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Run sends new deliveries and retries failed ones until the context is cancelled
+func (d *WebhookDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(outboxRetryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-d.queued:
+		case <-ctx.Done():
+			return
+		}
+
+		err := d.sendDue(ctx)
+		if err != nil {
+			d.logger.Error("error sending webhook deliveries", "err", err)
+		}
+	}
+}
+
+// sendDue sends the deliveries that are due, which are new ones and failed ones whose backoff has passed
+func (d *WebhookDispatcher) sendDue(ctx context.Context) error {
+	due, err := d.sc.ListDueWebhookDeliveries(ctx, d.now())
+	if err != nil {
+		return fmt.Errorf("error getting pending deliveries: %w", err)
+	}
+
+	for _, delivery := range due {
+		wh, err := d.sc.Webhooks().Get(ctx, delivery.WebhookUuid.String())
+		if errors.Is(err, babyapi.ErrNotFound) {
+			err = d.sc.UpdateWebhookDeliveryAttempt(ctx, db.UpdateWebhookDeliveryAttemptParams{
+				ID:            delivery.ID,
+				Status:        DeliveryFailed,
+				Attempts:      delivery.Attempts,
+				ResponseCode:  delivery.ResponseCode,
+				LastError:     "webhook was deleted",
+				NextAttemptAt: delivery.NextAttemptAt,
+			})
+			if err != nil {
+				return fmt.Errorf("error updating delivery %d: %w", delivery.ID, err)
+			}
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("error getting webhook %q: %w", delivery.WebhookUuid, err)
+		}
+
+		d.deliver(ctx, *wh, delivery)
+	}
+
+	return nil
+}
+
+// deliver sends the stored payload to the webhook and records the attempt. Deliveries are marked as
+// failed after reaching the maximum number of attempts
+func (d *WebhookDispatcher) deliver(ctx context.Context, wh tours.Webhook, delivery db.WebhookDelivery) {
+	attempts := delivery.Attempts + 1
+	logger := d.logger.With("webhook_id", wh.ID, "delivery_id", delivery.ID, "attempts", attempts)
+
+	params := db.UpdateWebhookDeliveryAttemptParams{
+		ID:            delivery.ID,
+		Status:        DeliveryDelivered,
+		Attempts:      attempts,
+		NextAttemptAt: delivery.NextAttemptAt,
+	}
+
+	code, err := d.post(ctx, wh, delivery)
+	params.ResponseCode = int64(code)
+	switch {
+	case err != nil && attempts >= webhookMaxAttempts:
+		logger.Error("error delivering webhook, giving up", "err", err)
+		params.Status = DeliveryFailed
+		params.LastError = err.Error()
+	case err != nil:
+		logger.Error("error delivering webhook, will retry", "err", err)
+		params.Status = DeliveryPending
+		params.LastError = err.Error()
+		params.NextAttemptAt = d.now().Add(backoff(int(attempts)))
+	default:
+		params.DeliveredAt = sql.NullTime{Time: d.now(), Valid: true}
+	}
+
+	err = d.sc.UpdateWebhookDeliveryAttempt(ctx, params)
+	if err != nil {
+		logger.Error("error updating webhook delivery", "err", err)
+	}
+}
+
+// post sends the request and returns the response code, which is 0 if there was no response
+func (d *WebhookDispatcher) post(ctx context.Context, wh tours.Webhook, delivery db.WebhookDelivery) (int, error) {
+	payload := []byte(delivery.Payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wh.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "walks-of-italy")
+	req.Header.Set(EventTypeHeader, delivery.EventType)
+	req.Header.Set(EventIDHeader, delivery.EventUuid.String())
+	timestamp := strconv.FormatInt(d.now().Unix(), 10)
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, SignPayload(wh.Secret, timestamp, payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("error sending request: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status: %s", resp.Status)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// WebhookDeliveryRecord is an entry in a webhook's delivery log
+type WebhookDeliveryRecord struct {
+	ID            int64           `json:"id"`
+	EventID       string          `json:"eventId"`
+	EventType     tours.EventType `json:"eventType"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	ResponseCode  int             `json:"responseCode,omitempty"`
+	LastError     string          `json:"lastError,omitempty"`
+	CreatedAt     time.Time       `json:"createdAt"`
+	NextAttemptAt *time.Time      `json:"nextAttemptAt,omitempty"`
+	DeliveredAt   *time.Time      `json:"deliveredAt,omitempty"`
+}
+
+// WebhookDeliveryList is the response for a webhook's delivery log
+type WebhookDeliveryList struct {
+	Items []WebhookDeliveryRecord `json:"items"`
+}
+
+func (*WebhookDeliveryList) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// keepWebhookSecret keeps the stored secret when a webhook is replaced without one. A secret is
+// generated if the webhook doesn't exist yet
+func (a *App) keepWebhookSecret(w http.ResponseWriter, r *http.Request, wh *tours.Webhook) *babyapi.ErrResponse {
+	if r.Method == http.MethodPost || wh.Secret != "" {
+		return nil
+	}
+
+	existing, err := a.sc.Webhooks().Get(r.Context(), wh.GetID())
+	switch {
+	case errors.Is(err, babyapi.ErrNotFound):
+		err = wh.GenerateSecret()
+		if err != nil {
+			return babyapi.InternalServerError(err)
+		}
+	case err != nil:
+		return babyapi.InternalServerError(fmt.Errorf("error getting webhook: %w", err))
+	default:
+		wh.Secret = existing.Secret
+	}
+	return nil
+}
+
+// RotateWebhookSecret replaces a webhook's secret and responds with the new one
+func (a *App) RotateWebhookSecret(w http.ResponseWriter, r *http.Request, wh *tours.Webhook) (render.Renderer, *babyapi.ErrResponse) {
+	err := wh.GenerateSecret()
+	if err != nil {
+		return nil, babyapi.InternalServerError(err)
+	}
+
+	err = a.sc.Webhooks().Set(r.Context(), wh)
+	if err != nil {
+		return nil, babyapi.InternalServerError(fmt.Errorf("error storing webhook: %w", err))
+	}
+
+	return wh, nil
+}
+
+// GetWebhookDeliveries lists the most recent deliveries for a webhook. It accepts an optional limit query parameter
+func (a *App) GetWebhookDeliveries(w http.ResponseWriter, r *http.Request, wh *tours.Webhook) (render.Renderer, *babyapi.ErrResponse) {
+	limit, httpErr := queryLimit(r)
+	if httpErr != nil {
+		return nil, httpErr
+	}
+
+	rows, err := a.sc.ListWebhookDeliveries(r.Context(), db.ListWebhookDeliveriesParams{
+		WebhookUuid: wh.ID,
+		Limit:       int64(limit),
+	})
+	if err != nil {
+		return nil, babyapi.InternalServerError(fmt.Errorf("error getting deliveries: %w", err))
+	}
+
+	result := &WebhookDeliveryList{Items: []WebhookDeliveryRecord{}}
+	for _, row := range rows {
+		record := WebhookDeliveryRecord{
+			ID:           row.ID,
+			EventID:      row.EventUuid.String(),
+			EventType:    tours.EventType(row.EventType),
+			Status:       row.Status,
+			Attempts:     int(row.Attempts),
+			ResponseCode: int(row.ResponseCode),
+			LastError:    row.LastError,
+			CreatedAt:    row.CreatedAt,
+		}
+		if row.Status == DeliveryPending {
+			record.NextAttemptAt = &row.NextAttemptAt
+		}
+		if row.DeliveredAt.Valid {
+			record.DeliveredAt = &row.DeliveredAt.Time
+		}
+
+		result.Items = append(result.Items, record)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,175 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"walks-of-italy/storage"
+	"walks-of-italy/storage/db"
+	"walks-of-italy/tours"
+
+	"github.com/google/uuid"
+)
+
+func TestWebhookDispatcher(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, time.May, 1, 3, 0, 0, 0, time.UTC)
+
+	var received []tours.Event
+	statusCodes := []int{http.StatusServiceUnavailable, http.StatusOK}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp := r.Header.Get(TimestampHeader)
+		if timestamp != strconv.FormatInt(now.Unix(), 10) || r.Header.Get(SignatureHeader) != SignPayload("secret", timestamp, body) {
+			t.Errorf("invalid signature: %q", r.Header.Get(SignatureHeader))
+		}
+
+		var e tours.Event
+		err := json.Unmarshal(body, &e)
+		if err != nil {
+			t.Errorf("invalid body: %v", err)
+		}
+		received = append(received, e)
+
+		w.WriteHeader(statusCodes[0])
+		statusCodes = statusCodes[1:]
+	}))
+	defer server.Close()
+
+	sc, err := storage.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sc.Close()
+
+	tour := tours.TourDetail{Name: "Tour", ProductID: uuid.New()}
+	wh := &tours.Webhook{
+		ID:         uuid.New(),
+		URL:        server.URL,
+		Secret:     "secret",
+		TourID:     tour.ProductID,
+		EventTypes: []tours.EventType{tours.EventType(tours.ChangeReopened)},
+	}
+	err = sc.Webhooks().Set(ctx, wh)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewWebhookDispatcher(sc, slog.Default())
+	d.now = func() time.Time { return now }
+
+	slot := now.Add(48 * time.Hour)
+	for _, e := range []tours.Event{
+		tours.NewChangeEvent(tour, tours.Change{Type: tours.ChangeReopened, Slot: slot, Vacancies: 2}, now),
+		tours.NewChangeEvent(tour, tours.Change{Type: tours.ChangeSoldOut, Slot: slot}, now),
+	} {
+		err = d.Publish(ctx, e)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(received) != 0 {
+		t.Fatalf("expected publishing to only store the delivery, got %+v", received)
+	}
+
+	for range 2 {
+		err = d.sendDue(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		now = now.Add(backoff(1))
+	}
+
+	if len(received) != 2 || received[0].ID != received[1].ID {
+		t.Fatalf("expected the same event to be sent twice, got %+v", received)
+	}
+	if received[0].Version != tours.EventVersion || received[0].Type != tours.EventType(tours.ChangeReopened) || !received[0].Date.Equal(slot) {
+		t.Errorf("unexpected event: %+v", received[0])
+	}
+
+	deliveries, err := sc.ListWebhookDeliveries(ctx, db.ListWebhookDeliveriesParams{WebhookUuid: wh.ID, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 {
+		t.Fatalf("expected 1 delivery, got %d", len(deliveries))
+	}
+	if deliveries[0].Status != DeliveryDelivered || deliveries[0].Attempts != 2 || deliveries[0].ResponseCode != http.StatusOK {
+		t.Errorf("unexpected delivery: %+v", deliveries[0])
+	}
+}
+
+func TestWebhookSecret(t *testing.T) {
+	ctx := context.Background()
+	sc, err := storage.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sc.Close()
+
+	a := New("", "", sc, nil)
+	router, err := a.webhooksAPI.
+		AddCustomIDRoute(http.MethodPost, "/rotate-secret", a.webhooksAPI.GetRequestedResourceAndDo(a.RotateWebhookSecret)).
+		Router()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	do := func(method, path, body string) tours.Webhook {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, r)
+		if w.Code >= http.StatusBadRequest {
+			t.Fatalf("unexpected response %d: %s", w.Code, w.Body.String())
+		}
+
+		var wh tours.Webhook
+		err := json.Unmarshal(w.Body.Bytes(), &wh)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return wh
+	}
+
+	created := do(http.MethodPost, "/webhooks", `{"URL": "https://example.com/hook"}`)
+	if created.Secret == "" {
+		t.Fatal("expected a generated secret")
+	}
+	payload := []byte(`{"type":"reopened"}`)
+	signature := SignPayload(created.Secret, "1746090000", payload)
+
+	updated := do(http.MethodPut, "/webhooks/"+created.GetID(), `{"ID": "`+created.GetID()+`", "URL": "https://example.com/other"}`)
+	stored, err := sc.Webhooks().Get(ctx, created.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.URL != "https://example.com/other" || SignPayload(stored.Secret, "1746090000", payload) != signature || updated.Secret != created.Secret {
+		t.Errorf("expected the secret to be kept on update, got %+v", stored)
+	}
+
+	rotated := do(http.MethodPost, "/webhooks/"+created.GetID()+"/rotate-secret", "")
+	stored, err = sc.Webhooks().Get(ctx, created.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated.Secret == "" || rotated.Secret == created.Secret || stored.Secret != rotated.Secret {
+		t.Errorf("expected a new stored secret, got %q and %q", rotated.Secret, stored.Secret)
+	}
+
+	fetched := do(http.MethodGet, "/webhooks/"+created.GetID(), "")
+	if fetched.Secret != "" {
+		t.Errorf("expected the secret to be hidden, got %q", fetched.Secret)
+	}
+}
@@ -2,31 +2,106 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"log/slog"
 	"net/url"
 	"os"
+	"strings"
 	"time"
 
 	"walks-of-italy/ai"
 	"walks-of-italy/app"
+	"walks-of-italy/config"
+	"walks-of-italy/notify"
 	"walks-of-italy/storage"
 	"walks-of-italy/tours"
+	"walks-of-italy/tracing"
 
+	"github.com/calvinmclean/babyapi"
 	"github.com/google/uuid"
 	"github.com/urfave/cli/v2"
 )
 
 func main() {
 	var debug bool
-	var dbFilename, pushoverAppToken, pushoverRecipientToken, addr, ventrataToken, walksToken, model, dataFile, tourID string
-	var watchInterval time.Duration
+	var nf notifierFlags
+	var pipelineConfig app.PipelineConfig
+	var hookConfig app.HookConfig
+	var traceConfig tracing.Config
+	var shutdownTracing func(context.Context) error
+	var cfg *config.Config
+	var hooks cli.StringSlice
+	var syncFile, syncMissing string
+	var dryRun, requireAPIKey, allowUnauthenticated, syncAllowEmpty bool
+	var apiKeyScope string
+	var password string
+	var tailURL, tailAPIKey string
+	var tailTypes cli.StringSlice
+	var tailJSON bool
+	var configFile, dbFilename, addr, templatesDir, ventrataToken, walksToken, model, dataFile, tourID string
+	var watchInterval, deadManSwitch, releasePolling time.Duration
 	var searchStart, searchEnd cli.Timestamp
+	var searchOption, searchMaxPrice, searchWeekday, searchAfter, searchBefore string
+	var searchMinVacancies, searchParty int
+	// before loads the config file, if there is one, and sets up tracing. It runs after a command's
+	// flags are parsed so the config file can fill in any that weren't set
+	before := func(ctx *cli.Context) error {
+		if configFile != "" {
+			var err error
+			cfg, err = config.Load(configFile)
+			if err != nil {
+				return err
+			}
+
+			fromConfig(ctx, "db", &dbFilename, cfg.DB)
+			fromConfig(ctx, "debug", &debug, cfg.Debug)
+			fromConfig(ctx, "ventrata-token", &ventrataToken, cfg.Tokens.Ventrata)
+			fromConfig(ctx, "walks-token", &walksToken, cfg.Tokens.Walks)
+			fromConfig(ctx, "addr", &addr, cfg.Server.Addr)
+			fromConfig(ctx, "require-api-key", &requireAPIKey, cfg.Server.RequireAPIKey)
+			fromConfig(ctx, "allow-unauthenticated", &allowUnauthenticated, cfg.Server.AllowUnauthenticated)
+			fromConfig(ctx, "templates", &templatesDir, cfg.Server.Templates)
+			fromConfig(ctx, "interval", &watchInterval, cfg.Watch.Interval)
+			fromConfig(ctx, "dead-man-switch", &deadManSwitch, cfg.Watch.DeadManSwitch)
+			fromConfig(ctx, "release-polling", &releasePolling, cfg.Watch.ReleasePolling)
+			fromConfig(ctx, "trace-exporter", &traceConfig.Exporter, cfg.Tracing.Exporter)
+			fromConfig(ctx, "trace-file", &traceConfig.File, cfg.Tracing.File)
+			fromConfig(ctx, "otlp-endpoint", &traceConfig.Endpoint, cfg.Tracing.Endpoint)
+			fromConfig(ctx, "otlp-insecure", &traceConfig.Insecure, cfg.Tracing.Insecure)
+			fromConfig(ctx, "dedupe-window", &pipelineConfig.DedupeWindow, cfg.Notifications.DedupeWindow)
+			fromConfig(ctx, "quiet-hours", &pipelineConfig.QuietHours, cfg.Notifications.QuietHours)
+			fromConfig(ctx, "digest-interval", &pipelineConfig.DigestInterval, cfg.Notifications.DigestInterval)
+			fromConfig(ctx, "hook-timeout", &hookConfig.Timeout, cfg.Hooks.Timeout)
+			fromConfig(ctx, "hook-concurrency", &hookConfig.Concurrency, cfg.Hooks.Concurrency)
+
+			nf.config = cfg.Notifications.Notifiers
+			for _, h := range cfg.Hooks.Commands {
+				hookConfig.Hooks = append(hookConfig.Hooks, app.Hook{EventType: h.Event, Command: h.Command})
+			}
+		}
+
+		var err error
+		shutdownTracing, err = tracing.Setup(ctx.Context, traceConfig)
+		if err != nil {
+			return fmt.Errorf("error setting up tracing: %w", err)
+		}
+		return nil
+	}
+
 	app := &cli.App{
 		Name: "walks-of-italy",
 		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        "config",
+				Usage:       "YAML config file. Flags and environment variables override its values",
+				Destination: &configFile,
+				EnvVars:     []string{"CONFIG"},
+				TakesFile:   true,
+			},
 			&cli.BoolFlag{
 				Name:        "debug",
 				Usage:       "enable debug logs",
@@ -44,15 +119,197 @@ func main() {
 			&cli.StringFlag{
 				Name:        "pushover-app-token",
 				Usage:       "App token for Pushover notifications",
-				Destination: &pushoverAppToken,
+				Destination: &nf.pushoverAppToken,
 				EnvVars:     []string{"PUSHOVER_APP_TOKEN"},
 			},
 			&cli.StringFlag{
 				Name:        "pushover-recipient-token",
 				Usage:       "Recipient token for Pushover notifications",
-				Destination: &pushoverRecipientToken,
+				Destination: &nf.pushoverRecipientToken,
 				EnvVars:     []string{"PUSHOVER_RECIPIENT_TOKEN"},
 			},
+			&cli.StringFlag{
+				Name:        "smtp-addr",
+				Usage:       "SMTP server host:port for email notifications",
+				Destination: &nf.smtpAddr,
+				EnvVars:     []string{"SMTP_ADDR"},
+			},
+			&cli.StringFlag{
+				Name:        "smtp-username",
+				Usage:       "Username for SMTP authentication",
+				Destination: &nf.smtpUsername,
+				EnvVars:     []string{"SMTP_USERNAME"},
+			},
+			&cli.StringFlag{
+				Name:        "smtp-password",
+				Usage:       "Password for SMTP authentication",
+				Destination: &nf.smtpPassword,
+				EnvVars:     []string{"SMTP_PASSWORD"},
+			},
+			&cli.StringFlag{
+				Name:        "smtp-from",
+				Usage:       "From address for email notifications",
+				Destination: &nf.smtpFrom,
+				EnvVars:     []string{"SMTP_FROM"},
+			},
+			&cli.StringSliceFlag{
+				Name:        "smtp-to",
+				Usage:       "Recipient addresses for email notifications",
+				Destination: &nf.smtpTo,
+				EnvVars:     []string{"SMTP_TO"},
+			},
+			&cli.StringFlag{
+				Name:        "ntfy-url",
+				Usage:       "ntfy server URL",
+				Destination: &nf.ntfyURL,
+				EnvVars:     []string{"NTFY_URL"},
+				Value:       "https://ntfy.sh",
+			},
+			&cli.StringFlag{
+				Name:        "ntfy-topic",
+				Usage:       "ntfy topic for notifications",
+				Destination: &nf.ntfyTopic,
+				EnvVars:     []string{"NTFY_TOPIC"},
+			},
+			&cli.StringFlag{
+				Name:        "ntfy-token",
+				Usage:       "Access token for protected ntfy topics",
+				Destination: &nf.ntfyToken,
+				EnvVars:     []string{"NTFY_TOKEN"},
+			},
+			&cli.StringFlag{
+				Name:        "gotify-url",
+				Usage:       "Gotify server URL",
+				Destination: &nf.gotifyURL,
+				EnvVars:     []string{"GOTIFY_URL"},
+			},
+			&cli.StringFlag{
+				Name:        "gotify-token",
+				Usage:       "Gotify application token",
+				Destination: &nf.gotifyToken,
+				EnvVars:     []string{"GOTIFY_TOKEN"},
+			},
+			&cli.StringFlag{
+				Name:        "slack-webhook-url",
+				Usage:       "Slack-compatible incoming webhook URL",
+				Destination: &nf.slackWebhookURL,
+				EnvVars:     []string{"SLACK_WEBHOOK_URL"},
+			},
+			&cli.StringFlag{
+				Name:        "matrix-homeserver",
+				Usage:       "Matrix homeserver URL",
+				Destination: &nf.matrixHomeserver,
+				EnvVars:     []string{"MATRIX_HOMESERVER"},
+			},
+			&cli.StringFlag{
+				Name:        "matrix-access-token",
+				Usage:       "Access token for the Matrix user sending notifications",
+				Destination: &nf.matrixAccessToken,
+				EnvVars:     []string{"MATRIX_ACCESS_TOKEN"},
+			},
+			&cli.StringFlag{
+				Name:        "matrix-room-id",
+				Usage:       "Matrix room ID to send notifications to",
+				Destination: &nf.matrixRoomID,
+				EnvVars:     []string{"MATRIX_ROOM_ID"},
+			},
+			&cli.DurationFlag{
+				Name:        "dedupe-window",
+				Usage:       "Drop notifications identical to one sent within this window",
+				Destination: &pipelineConfig.DedupeWindow,
+				EnvVars:     []string{"DEDUPE_WINDOW"},
+				Value:       time.Hour,
+			},
+			&cli.GenericFlag{
+				Name:    "quiet-hours",
+				Usage:   "Hold non-urgent notifications during these hours, like 22:00-07:00",
+				Value:   &pipelineConfig.QuietHours,
+				EnvVars: []string{"QUIET_HOURS"},
+			},
+			&cli.DurationFlag{
+				Name:        "digest-interval",
+				Usage:       "Batch non-urgent notifications into a digest sent on this interval. Disabled if zero",
+				Destination: &pipelineConfig.DigestInterval,
+				EnvVars:     []string{"DIGEST_INTERVAL"},
+			},
+			&cli.DurationFlag{
+				Name:        "dead-man-switch",
+				Usage:       "Send an urgent notification if no tours are polled successfully within this duration. Disabled if zero",
+				Destination: &deadManSwitch,
+				EnvVars:     []string{"DEAD_MAN_SWITCH"},
+			},
+			&cli.DurationFlag{
+				Name:        "release-polling",
+				Usage:       "Poll tours on this interval while they are in their predicted release window. Disabled if zero",
+				Destination: &releasePolling,
+				EnvVars:     []string{"RELEASE_POLLING"},
+			},
+			&cli.StringSliceFlag{
+				Name:        "hook",
+				Usage:       "Run a command when an event occurs, like new_latest_date=./notify.sh. The event JSON is passed on stdin",
+				Destination: &hooks,
+				EnvVars:     []string{"HOOKS"},
+			},
+			&cli.DurationFlag{
+				Name:        "hook-timeout",
+				Usage:       "Maximum time a hook can run before it is killed",
+				Destination: &hookConfig.Timeout,
+				EnvVars:     []string{"HOOK_TIMEOUT"},
+				Value:       30 * time.Second,
+			},
+			&cli.IntFlag{
+				Name:        "hook-concurrency",
+				Usage:       "Maximum number of hooks to run at the same time",
+				Destination: &hookConfig.Concurrency,
+				EnvVars:     []string{"HOOK_CONCURRENCY"},
+				Value:       4,
+			},
+			&cli.StringFlag{
+				Name:        "trace-exporter",
+				Usage:       "Exporter for OpenTelemetry traces: none, stdout, file, or otlp",
+				Destination: &traceConfig.Exporter,
+				EnvVars:     []string{"TRACE_EXPORTER"},
+				Value:       tracing.ExporterNone,
+			},
+			&cli.StringFlag{
+				Name:        "trace-file",
+				Usage:       "Filename to write traces to when using the file exporter",
+				Destination: &traceConfig.File,
+				EnvVars:     []string{"TRACE_FILE"},
+				TakesFile:   true,
+			},
+			&cli.StringFlag{
+				Name:        "otlp-endpoint",
+				Usage:       "Host and port of an OTLP HTTP receiver. Defaults to the OTEL_EXPORTER_OTLP_* environment variables",
+				Destination: &traceConfig.Endpoint,
+				EnvVars:     []string{"OTLP_ENDPOINT"},
+			},
+			&cli.BoolFlag{
+				Name:        "otlp-insecure",
+				Usage:       "Disable TLS for the OTLP exporter",
+				Destination: &traceConfig.Insecure,
+				EnvVars:     []string{"OTLP_INSECURE"},
+			},
+			&cli.StringFlag{
+				Name:        "sync-file",
+				Usage:       "YAML file of tours to keep the database in sync with. It is checked for changes while watching",
+				Destination: &syncFile,
+				EnvVars:     []string{"SYNC_FILE"},
+				TakesFile:   true,
+			},
+			&cli.StringFlag{
+				Name:        "sync-missing",
+				Usage:       "What to do with tours that aren't in the sync file: keep, pause, or delete",
+				Destination: &syncMissing,
+				EnvVars:     []string{"SYNC_MISSING"},
+				Value:       string(app.MissingKeep),
+			},
+			&cli.BoolFlag{
+				Name:        "sync-allow-empty",
+				Usage:       "Allow a sync file without tours to delete all tours when --sync-missing is delete",
+				Destination: &syncAllowEmpty,
+				EnvVars:     []string{"SYNC_ALLOW_EMPTY"},
+			},
 			&cli.StringFlag{
 				Name:        "ventrata-token",
 				Usage:       "Access token for Ventrata booking API",
@@ -66,11 +323,20 @@ func main() {
 				EnvVars:     []string{"WALKS_TOKEN"},
 			},
 		},
+		After: func(ctx *cli.Context) error {
+			if shutdownTracing == nil {
+				return nil
+			}
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			return shutdownTracing(shutdownCtx)
+		},
 		DefaultCommand: "watch",
 		Commands: []*cli.Command{
 			{
-				Name:  "watch",
-				Usage: "Watch for new tour availabilities",
+				Name:   "watch",
+				Before: before,
+				Usage:  "Watch for new tour availabilities",
 				Flags: []cli.Flag{
 					&cli.DurationFlag{
 						Name:        "interval",
@@ -81,7 +347,7 @@ func main() {
 					},
 				},
 				Action: func(ctx *cli.Context) error {
-					app, sc, err := setupApp(addr, dbFilename, pushoverAppToken, pushoverRecipientToken, ventrataToken, debug)
+					app, sc, err := setupApp(ctx.Context, cfg, addr, dbFilename, nf, pipelineConfig, hookConfig, hooks.Value(), deadManSwitch, releasePolling, syncFile, app.MissingTours(syncMissing), syncAllowEmpty, ventrataToken, debug)
 					if err != nil {
 						return fmt.Errorf("error creating app: %w", err)
 					}
@@ -90,10 +356,11 @@ func main() {
 				},
 			},
 			{
-				Name:  "update",
-				Usage: "Update latest availabilities",
+				Name:   "update",
+				Before: before,
+				Usage:  "Update latest availabilities",
 				Action: func(ctx *cli.Context) error {
-					app, sc, err := setupApp(addr, dbFilename, pushoverAppToken, pushoverRecipientToken, ventrataToken, debug)
+					app, sc, err := setupApp(ctx.Context, cfg, addr, dbFilename, nf, pipelineConfig, hookConfig, hooks.Value(), deadManSwitch, releasePolling, syncFile, app.MissingTours(syncMissing), syncAllowEmpty, ventrataToken, debug)
 					if err != nil {
 						return fmt.Errorf("error creating app: %w", err)
 					}
@@ -104,7 +371,7 @@ func main() {
 						return fmt.Errorf("error getting tours: %w", err)
 					}
 
-					err = app.UpdateLatestAvailabilities(ctx.Context, allTours, nil)
+					err = app.UpdateLatestAvailabilities(ctx.Context, allTours, nil, nil)
 					if err != nil {
 						return fmt.Errorf("error updating availability: %w", err)
 					}
@@ -118,8 +385,9 @@ func main() {
 				},
 			},
 			{
-				Name:  "details",
-				Usage: "Print details from details API",
+				Name:   "details",
+				Before: before,
+				Usage:  "Print details from details API",
 				Action: func(ctx *cli.Context) error {
 					sc, err := storage.New(dbFilename)
 					if err != nil {
@@ -147,8 +415,9 @@ func main() {
 				},
 			},
 			{
-				Name:  "chat",
-				Usage: "Chat with an AI model about the tour dates",
+				Name:   "chat",
+				Before: before,
+				Usage:  "Chat with an AI model about the tour dates",
 				Flags: []cli.Flag{
 					&cli.StringFlag{
 						Name:        "model",
@@ -173,8 +442,9 @@ func main() {
 				},
 			},
 			{
-				Name:  "search",
-				Usage: "Search for availability of a specified tour in a date range",
+				Name:   "search",
+				Before: before,
+				Usage:  "Search for availability of a specified tour in a date range",
 				Flags: []cli.Flag{
 					&cli.StringFlag{
 						Name:        "tour-id",
@@ -196,6 +466,42 @@ func main() {
 						Layout:      time.DateOnly,
 						Required:    true,
 					},
+					&cli.StringFlag{
+						Name:        "option",
+						Usage:       "product option to get availability for",
+						Destination: &searchOption,
+						Value:       tours.DefaultOptionID,
+					},
+					&cli.IntFlag{
+						Name:        "min-vacancies",
+						Usage:       "only show slots with at least this many vacancies",
+						Destination: &searchMinVacancies,
+					},
+					&cli.IntFlag{
+						Name:        "party",
+						Usage:       "only show slots with enough vacancies for a group of this size",
+						Destination: &searchParty,
+					},
+					&cli.StringFlag{
+						Name:        "max-price",
+						Usage:       "only show slots where the adult price is at most this many USD, like 99.50",
+						Destination: &searchMaxPrice,
+					},
+					&cli.StringFlag{
+						Name:        "weekday",
+						Usage:       "only show slots on these comma-separated weekdays, like sat,sun",
+						Destination: &searchWeekday,
+					},
+					&cli.StringFlag{
+						Name:        "after",
+						Usage:       "only show slots that start at or after this time, like 09:00",
+						Destination: &searchAfter,
+					},
+					&cli.StringFlag{
+						Name:        "before",
+						Usage:       "only show slots that start before this time, like 14:00",
+						Destination: &searchBefore,
+					},
 				},
 				Action: func(ctx *cli.Context) error {
 					tourUUID, err := uuid.Parse(tourID)
@@ -203,17 +509,22 @@ func main() {
 						return err
 					}
 
+					filter, err := tours.ParseAvailabilityFilter(searchMinVacancies, searchParty, searchMaxPrice, searchWeekday, searchAfter, searchBefore)
+					if err != nil {
+						return err
+					}
+
 					tour := tours.TourDetail{
 						Name:      "User-provided tour ID",
 						ProductID: tourUUID,
 					}
 
-					availability, err := tour.GetAvailability(ctx.Context, ventrataToken, tours.DateFromTime(*searchStart.Value()), tours.DateFromTime(*searchEnd.Value()))
+					availability, err := tour.GetOptionAvailability(ctx.Context, ventrataToken, searchOption, tours.DateFromTime(*searchStart.Value()), tours.DateFromTime(*searchEnd.Value()))
 					if err != nil {
 						return fmt.Errorf("error getting availability: %w", err)
 					}
 
-					err = availability.PrettySummary(os.Stdout)
+					err = filter.Apply(availability).PrettySummary(os.Stdout)
 					if err != nil {
 						return fmt.Errorf("error printing summary: %w", err)
 					}
@@ -222,8 +533,9 @@ func main() {
 				},
 			},
 			{
-				Name:  "serve",
-				Usage: "Run server with API and UI. Also watches for new availability",
+				Name:   "serve",
+				Before: before,
+				Usage:  "Run server with API and UI. Also watches for new availability",
 				Flags: []cli.Flag{
 					&cli.DurationFlag{
 						Name:        "interval",
@@ -236,23 +548,394 @@ func main() {
 						Name:        "addr",
 						Usage:       "address to serve on",
 						Destination: &addr,
-						Value:       ":7077",
+						Value:       "localhost:7077",
 						EnvVars:     []string{"ADDR"},
 					},
+					&cli.BoolFlag{
+						Name:        "require-api-key",
+						Usage:       "require an API key for API requests. Create keys with the api-key command",
+						Destination: &requireAPIKey,
+						EnvVars:     []string{"REQUIRE_API_KEY"},
+					},
+					&cli.BoolFlag{
+						Name:        "allow-unauthenticated",
+						Usage:       "serve without API keys on an address that isn't loopback, so anyone who can reach the server can use the API",
+						Destination: &allowUnauthenticated,
+						EnvVars:     []string{"ALLOW_UNAUTHENTICATED"},
+					},
+					&cli.StringFlag{
+						Name:        "templates",
+						Usage:       "directory of templates that replace the built-in ones with the same path, like pages/summary.html",
+						Destination: &templatesDir,
+						EnvVars:     []string{"TEMPLATES"},
+						TakesFile:   true,
+					},
 				},
 				Action: func(ctx *cli.Context) error {
-					app, sc, err := setupApp(addr, dbFilename, pushoverAppToken, pushoverRecipientToken, ventrataToken, debug)
+					if templatesDir != "" {
+						_, err := os.Stat(templatesDir)
+						if err != nil {
+							return fmt.Errorf("error checking templates directory: %w", err)
+						}
+					}
+
+					app, sc, err := setupApp(ctx.Context, cfg, addr, dbFilename, nf, pipelineConfig, hookConfig, hooks.Value(), deadManSwitch, releasePolling, syncFile, app.MissingTours(syncMissing), syncAllowEmpty, ventrataToken, debug)
 					if err != nil {
 						return fmt.Errorf("error creating app: %w", err)
 					}
 					defer sc.Close()
 
-					return app.Run(ctx.Context, watchInterval)
+					return app.WithAPIKeys(requireAPIKey).WithUnauthenticatedAccess(allowUnauthenticated).WithTemplates(templatesDir).Run(ctx.Context, watchInterval)
 				},
 			},
 			{
-				Name:  "load",
-				Usage: "Load data from a JSON file into the DB",
+				Name:   "sync",
+				Usage:  "Make the tours in the DB match a YAML file",
+				Before: before,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:        "data",
+						Usage:       "YAML file of tours, in the same format as the config file",
+						Destination: &dataFile,
+						TakesFile:   true,
+						Required:    true,
+					},
+					&cli.StringFlag{
+						Name:        "missing",
+						Usage:       "What to do with tours that aren't in the file: keep, pause, or delete",
+						Destination: &syncMissing,
+						Value:       string(app.MissingKeep),
+					},
+					&cli.BoolFlag{
+						Name:        "allow-empty",
+						Usage:       "Allow a file without tours to delete all tours when --missing is delete",
+						Destination: &syncAllowEmpty,
+					},
+					&cli.BoolFlag{
+						Name:        "dry-run",
+						Usage:       "Show the changes without making them",
+						Destination: &dryRun,
+					},
+				},
+				Action: func(ctx *cli.Context) error {
+					sc, err := storage.New(dbFilename)
+					if err != nil {
+						return fmt.Errorf("error creating db client: %w", err)
+					}
+					defer sc.Close()
+
+					desired, err := app.LoadTourFile(dataFile)
+					if err != nil {
+						return err
+					}
+
+					changes, err := app.SyncTours(ctx.Context, sc, desired, app.MissingTours(syncMissing), syncAllowEmpty, dryRun)
+					if err != nil {
+						return fmt.Errorf("error syncing tours: %w", err)
+					}
+
+					if len(changes) == 0 {
+						fmt.Println("tours are up to date")
+						return nil
+					}
+					for _, c := range changes {
+						fmt.Println(c)
+					}
+					if dryRun {
+						fmt.Println("dry run: no changes were made")
+					}
+
+					return nil
+				},
+			},
+			{
+				Name:  "config",
+				Usage: "Work with the config file",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "validate",
+						Usage:     "Check the config file for errors",
+						ArgsUsage: "[FILE]",
+						Action: func(ctx *cli.Context) error {
+							filename := configFile
+							if ctx.Args().Present() {
+								filename = ctx.Args().First()
+							}
+							if filename == "" {
+								return errors.New("a config file is required")
+							}
+
+							_, err := config.Load(filename)
+							if err != nil {
+								return err
+							}
+
+							fmt.Printf("%s is valid\n", filename)
+							return nil
+						},
+					},
+				},
+			},
+			{
+				Name:  "user",
+				Usage: "Manage user accounts for the web UI",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "add",
+						Usage:     "Create a user",
+						ArgsUsage: "USERNAME",
+						Before:    before,
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:        "password",
+								Usage:       "password for the new user",
+								EnvVars:     []string{"USER_PASSWORD"},
+								Destination: &password,
+								Required:    true,
+							},
+						},
+						Action: func(ctx *cli.Context) error {
+							sc, err := storage.New(dbFilename)
+							if err != nil {
+								return fmt.Errorf("error creating db client: %w", err)
+							}
+							defer sc.Close()
+
+							u, err := tours.NewUser(ctx.Args().First(), password)
+							if err != nil {
+								return err
+							}
+
+							_, err = sc.Users().GetByUsername(ctx.Context, u.Username)
+							if err == nil {
+								return fmt.Errorf("user %q already exists", u.Username)
+							}
+							if !errors.Is(err, babyapi.ErrNotFound) {
+								return fmt.Errorf("error getting user: %w", err)
+							}
+
+							err = sc.Users().Set(ctx.Context, u)
+							if err != nil {
+								return fmt.Errorf("error creating user: %w", err)
+							}
+
+							fmt.Printf("created user %q\n", u.Username)
+							return nil
+						},
+					},
+					{
+						Name:   "list",
+						Usage:  "List users",
+						Before: before,
+						Action: func(ctx *cli.Context) error {
+							sc, err := storage.New(dbFilename)
+							if err != nil {
+								return fmt.Errorf("error creating db client: %w", err)
+							}
+							defer sc.Close()
+
+							users, err := sc.Users().GetAll(ctx.Context)
+							if err != nil {
+								return fmt.Errorf("error getting users: %w", err)
+							}
+
+							for _, u := range users {
+								fmt.Printf("%s\t%s\tcreated %s\n", u.ID, u.Username, u.CreatedAt.Format(time.DateOnly))
+							}
+							return nil
+						},
+					},
+					{
+						Name:      "delete",
+						Usage:     "Delete a user along with their sessions and subscriptions",
+						ArgsUsage: "USERNAME",
+						Before:    before,
+						Action: func(ctx *cli.Context) error {
+							sc, err := storage.New(dbFilename)
+							if err != nil {
+								return fmt.Errorf("error creating db client: %w", err)
+							}
+							defer sc.Close()
+
+							u, err := sc.Users().GetByUsername(ctx.Context, ctx.Args().First())
+							if err != nil {
+								return fmt.Errorf("error getting user: %w", err)
+							}
+
+							err = sc.Users().Delete(ctx.Context, u.ID)
+							if err != nil {
+								return fmt.Errorf("error deleting user: %w", err)
+							}
+
+							fmt.Printf("deleted user %q\n", u.Username)
+							return nil
+						},
+					},
+					{
+						Name:      "passwd",
+						Usage:     "Change a user's password",
+						ArgsUsage: "USERNAME",
+						Before:    before,
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:        "password",
+								Usage:       "new password",
+								EnvVars:     []string{"USER_PASSWORD"},
+								Destination: &password,
+								Required:    true,
+							},
+						},
+						Action: func(ctx *cli.Context) error {
+							sc, err := storage.New(dbFilename)
+							if err != nil {
+								return fmt.Errorf("error creating db client: %w", err)
+							}
+							defer sc.Close()
+
+							u, err := sc.Users().GetByUsername(ctx.Context, ctx.Args().First())
+							if err != nil {
+								return fmt.Errorf("error getting user: %w", err)
+							}
+
+							err = u.SetPassword(password)
+							if err != nil {
+								return err
+							}
+
+							err = sc.Users().Set(ctx.Context, u)
+							if err != nil {
+								return fmt.Errorf("error updating user: %w", err)
+							}
+
+							fmt.Printf("updated password for %q\n", u.Username)
+							return nil
+						},
+					},
+				},
+			},
+			{
+				Name:  "api-key",
+				Usage: "Manage API keys for the REST API",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "create",
+						Usage:     "Create an API key. The key is only shown once",
+						ArgsUsage: "NAME",
+						Before:    before,
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:        "scope",
+								Usage:       "scope for the key: read or admin",
+								Destination: &apiKeyScope,
+								Value:       string(tours.ScopeRead),
+							},
+						},
+						Action: func(ctx *cli.Context) error {
+							sc, err := storage.New(dbFilename)
+							if err != nil {
+								return fmt.Errorf("error creating db client: %w", err)
+							}
+							defer sc.Close()
+
+							apiKey, key, err := tours.NewAPIKey(ctx.Args().First(), tours.Scope(apiKeyScope))
+							if err != nil {
+								return err
+							}
+
+							err = sc.APIKeys().Add(ctx.Context, apiKey)
+							if err != nil {
+								return fmt.Errorf("error storing API key: %w", err)
+							}
+
+							fmt.Println(key)
+							return nil
+						},
+					},
+					{
+						Name:   "list",
+						Usage:  "List API keys",
+						Before: before,
+						Action: func(ctx *cli.Context) error {
+							sc, err := storage.New(dbFilename)
+							if err != nil {
+								return fmt.Errorf("error creating db client: %w", err)
+							}
+							defer sc.Close()
+
+							apiKeys, err := sc.APIKeys().GetAll(ctx.Context)
+							if err != nil {
+								return fmt.Errorf("error getting API keys: %w", err)
+							}
+
+							for _, k := range apiKeys {
+								fmt.Printf("%s\t%s\t%s\tcreated %s\n", k.ID, k.Name, k.Scope, k.CreatedAt.Format(time.DateOnly))
+							}
+							return nil
+						},
+					},
+					{
+						Name:      "delete",
+						Usage:     "Delete an API key",
+						ArgsUsage: "ID",
+						Before:    before,
+						Action: func(ctx *cli.Context) error {
+							id, err := uuid.Parse(ctx.Args().First())
+							if err != nil {
+								return fmt.Errorf("invalid API key ID: %w", err)
+							}
+
+							sc, err := storage.New(dbFilename)
+							if err != nil {
+								return fmt.Errorf("error creating db client: %w", err)
+							}
+							defer sc.Close()
+
+							err = sc.APIKeys().Delete(ctx.Context, id)
+							if err != nil {
+								return fmt.Errorf("error deleting API key: %w", err)
+							}
+
+							return nil
+						},
+					},
+				},
+			},
+			{
+				Name:  "tail",
+				Usage: "Print events from a running server as they happen",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:        "url",
+						Usage:       "URL of the server",
+						Destination: &tailURL,
+						Value:       "http://localhost:7077",
+						EnvVars:     []string{"WALKS_URL"},
+					},
+					&cli.StringFlag{
+						Name:        "api-key",
+						Usage:       "API key for servers that require one",
+						Destination: &tailAPIKey,
+						EnvVars:     []string{"WALKS_API_KEY"},
+					},
+					&cli.StringSliceFlag{
+						Name:        "type",
+						Usage:       "only print these event types, like new_latest_date or status",
+						Destination: &tailTypes,
+					},
+					&cli.BoolFlag{
+						Name:        "json",
+						Usage:       "print the event JSON instead of a description",
+						Destination: &tailJSON,
+					},
+				},
+				Action: func(ctx *cli.Context) error {
+					return app.TailEvents(ctx.Context, os.Stdout, strings.TrimSuffix(tailURL, "/")+"/events", tailAPIKey, tailTypes.Value(), tailJSON)
+				},
+			},
+			{
+				Name:   "load",
+				Before: before,
+				Usage:  "Load data from a JSON file into the DB",
 				Flags: []cli.Flag{
 					&cli.StringFlag{
 						Name:        "data",
@@ -301,21 +984,61 @@ func main() {
 	}
 }
 
-func setupApp(addr, dbFilename, pushoverAppToken, pushoverRecipientToken, accessToken string, debug bool) (*app.App, *storage.Client, error) {
+func setupApp(
+	ctx context.Context,
+	cfg *config.Config,
+	addr, dbFilename string,
+	nf notifierFlags,
+	pipelineConfig app.PipelineConfig,
+	hookConfig app.HookConfig,
+	hooks []string,
+	deadManSwitch time.Duration,
+	releasePolling time.Duration,
+	syncFile string,
+	syncMissing app.MissingTours,
+	syncAllowEmpty bool,
+	accessToken string,
+	debug bool,
+) (*app.App, *storage.Client, error) {
 	sc, err := storage.New(dbFilename)
 	if err != nil {
 		return nil, nil, fmt.Errorf("error creating db client: %w", err)
 	}
 
-	var nc *app.NotifyClient
-	if pushoverAppToken != "" && pushoverRecipientToken != "" {
-		nc, err = app.NewNotifyClient(pushoverAppToken, pushoverRecipientToken)
+	err = syncMissing.Validate()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if cfg != nil {
+		err = loadConfigResources(ctx, sc, cfg)
 		if err != nil {
-			return nil, nil, fmt.Errorf("error creating notify client: %w", err)
+			return nil, nil, err
 		}
 	}
 
-	app := app.New(addr, accessToken, sc, nc)
+	nc, pushover, err := nf.notifier()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error creating notify client: %w", err)
+	}
+
+	for _, h := range hooks {
+		hook, err := app.ParseHook(h)
+		if err != nil {
+			return nil, nil, err
+		}
+		hookConfig.Hooks = append(hookConfig.Hooks, hook)
+	}
+
+	app := app.New(addr, accessToken, sc, nc).
+		WithOutbox().
+		WithPipeline(pipelineConfig).
+		WithPushoverReceipts(pushover).
+		WithHooks(hookConfig).
+		WithDeadManSwitch(deadManSwitch).
+		WithReleasePolling(releasePolling).
+		WithTourSync(syncFile, syncMissing, syncAllowEmpty).
+		WithUsers(nf.userNotifierConfig())
 
 	if debug {
 		slog.SetLogLoggerLevel(slog.LevelDebug)
@@ -323,3 +1046,153 @@ func setupApp(addr, dbFilename, pushoverAppToken, pushoverRecipientToken, access
 
 	return app, sc, nil
 }
+
+type notifierFlags struct {
+	pushoverAppToken, pushoverRecipientToken          string
+	smtpAddr, smtpUsername, smtpPassword, smtpFrom    string
+	smtpTo                                            cli.StringSlice
+	ntfyURL, ntfyTopic, ntfyToken                     string
+	gotifyURL, gotifyToken                            string
+	slackWebhookURL                                   string
+	matrixHomeserver, matrixAccessToken, matrixRoomID string
+
+	// config has additional notifiers from the config file
+	config []config.Notifier
+}
+
+// notifier creates a Notifier for all configured backends. The Pushover notifier is also returned
+// separately, if configured, so its receipts can be tracked
+func (nf notifierFlags) notifier() (notify.Notifier, *notify.Pushover, error) {
+	var notifiers notify.Multi
+	var pushover *notify.Pushover
+
+	add := func(name string, n notify.Notifier, err error) error {
+		if err != nil {
+			return fmt.Errorf("error creating %s notifier: %w", name, err)
+		}
+		notifiers = append(notifiers, n)
+		return nil
+	}
+
+	var errs []error
+	if nf.pushoverAppToken != "" && nf.pushoverRecipientToken != "" {
+		n, err := notify.NewPushover(nf.pushoverAppToken, nf.pushoverRecipientToken)
+		errs = append(errs, add("pushover", n, err))
+		pushover = n
+	}
+	if nf.smtpAddr != "" {
+		n, err := notify.NewSMTP(nf.smtpAddr, nf.smtpUsername, nf.smtpPassword, nf.smtpFrom, nf.smtpTo.Value())
+		errs = append(errs, add("smtp", n, err))
+	}
+	if nf.ntfyTopic != "" {
+		n, err := notify.NewNtfy(nf.ntfyURL, nf.ntfyTopic, nf.ntfyToken)
+		errs = append(errs, add("ntfy", n, err))
+	}
+	if nf.gotifyURL != "" {
+		n, err := notify.NewGotify(nf.gotifyURL, nf.gotifyToken)
+		errs = append(errs, add("gotify", n, err))
+	}
+	if nf.slackWebhookURL != "" {
+		n, err := notify.NewSlack(nf.slackWebhookURL)
+		errs = append(errs, add("slack", n, err))
+	}
+	if nf.matrixHomeserver != "" {
+		n, err := notify.NewMatrix(nf.matrixHomeserver, nf.matrixAccessToken, nf.matrixRoomID)
+		errs = append(errs, add("matrix", n, err))
+	}
+
+	for i, c := range nf.config {
+		n, err := c.New()
+		errs = append(errs, add(fmt.Sprintf("%s (config notifier %d)", c.Type, i), n, err))
+		if p, ok := n.(*notify.Pushover); ok && pushover == nil {
+			pushover = p
+		}
+	}
+
+	err := errors.Join(errs...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(notifiers) == 0 {
+		return nil, nil, nil
+	}
+
+	return notifiers, pushover, nil
+}
+
+// userNotifierConfig uses the server's notifier settings for sending to users, who each set their own
+// recipient. Settings from flags are used first, then the first notifier of each type in the config file
+func (nf notifierFlags) userNotifierConfig() app.UserNotifierConfig {
+	result := app.UserNotifierConfig{
+		PushoverAppToken: nf.pushoverAppToken,
+		SMTPAddr:         nf.smtpAddr,
+		SMTPUsername:     nf.smtpUsername,
+		SMTPPassword:     nf.smtpPassword,
+		SMTPFrom:         nf.smtpFrom,
+		NtfyURL:          nf.ntfyURL,
+	}
+
+	for _, c := range nf.config {
+		switch {
+		case c.Type == "pushover" && result.PushoverAppToken == "":
+			result.PushoverAppToken = c.AppToken
+		case c.Type == "smtp" && result.SMTPAddr == "":
+			result.SMTPAddr = c.Addr
+			result.SMTPUsername = c.Username
+			result.SMTPPassword = c.Password
+			result.SMTPFrom = c.From
+		case c.Type == "ntfy" && result.NtfyURL == "":
+			result.NtfyURL = c.URL
+		}
+	}
+
+	return result
+}
+
+// fromConfig sets the destination to the value from the config file unless the flag was set on the
+// command line or with an environment variable. Empty values in the config file are ignored
+func fromConfig[T comparable](ctx *cli.Context, flag string, dest *T, value T) {
+	var zero T
+	if value == zero || ctx.IsSet(flag) {
+		return
+	}
+	*dest = value
+}
+
+// loadConfigResources creates the tours and alert rules from the config file that don't exist yet.
+// Existing ones are left alone so changes made in the UI or API aren't undone on every start. Use
+// sync to make the file the source of truth instead
+func loadConfigResources(ctx context.Context, sc *storage.Client, cfg *config.Config) error {
+	for _, td := range cfg.TourDetails() {
+		_, err := sc.Get(ctx, td.GetID())
+		switch {
+		case err == nil:
+			continue
+		case !errors.Is(err, sql.ErrNoRows):
+			return fmt.Errorf("error getting tour %q: %w", td.Name, err)
+		}
+
+		err = sc.Set(ctx, td)
+		if err != nil {
+			return fmt.Errorf("error storing tour %q from config: %w", td.Name, err)
+		}
+	}
+
+	for _, rule := range cfg.AlertRules() {
+		_, err := sc.Rules().Get(ctx, rule.GetID())
+		switch {
+		case err == nil:
+			continue
+		case !errors.Is(err, babyapi.ErrNotFound):
+			return fmt.Errorf("error getting rule: %w", err)
+		}
+
+		err = sc.Rules().Set(ctx, rule)
+		if err != nil {
+			return fmt.Errorf("error storing rule from config: %w", err)
+		}
+	}
+
+	return nil
+}
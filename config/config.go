@@ -0,0 +1,383 @@
+// Package config loads the application's settings from a YAML file. Everything that can be set with
+// flags can be set in the file, along with things that can't be expressed as flags like multiple
+// notifiers of the same type, tours, and alert rules
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"walks-of-italy/notify"
+	"walks-of-italy/tours"
+	"walks-of-italy/tracing"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+)
+
+// ruleNamespace is used to create stable IDs for rules from the config file so they are updated
+// instead of duplicated each time the file is loaded
+var ruleNamespace = uuid.MustParse("6f0c7a4e-1f0b-4a53-9d53-2d0b0c6c3f7e")
+
+type Config struct {
+	DB            string        `yaml:"db"`
+	Debug         bool          `yaml:"debug"`
+	Tokens        Tokens        `yaml:"tokens"`
+	Server        Server        `yaml:"server"`
+	Watch         Watch         `yaml:"watch"`
+	Tracing       Tracing       `yaml:"tracing"`
+	Notifications Notifications `yaml:"notifications"`
+	Hooks         Hooks         `yaml:"hooks"`
+	Tours         []Tour        `yaml:"tours"`
+	Rules         []Rule        `yaml:"rules"`
+
+	// root is the parsed document, which is used to find line numbers for validation errors
+	root *yaml.Node
+}
+
+type Tokens struct {
+	Ventrata string `yaml:"ventrata"`
+	Walks    string `yaml:"walks"`
+}
+
+type Server struct {
+	Addr          string `yaml:"addr"`
+	RequireAPIKey bool   `yaml:"requireApiKey"`
+	// AllowUnauthenticated allows serving without API keys on an address that isn't loopback
+	AllowUnauthenticated bool `yaml:"allowUnauthenticated"`
+	// Templates is a directory of templates that replace the built-in ones
+	Templates string `yaml:"templates"`
+}
+
+type Watch struct {
+	Interval       time.Duration `yaml:"interval"`
+	DeadManSwitch  time.Duration `yaml:"deadManSwitch"`
+	ReleasePolling time.Duration `yaml:"releasePolling"`
+}
+
+type Tracing struct {
+	Exporter string `yaml:"exporter"`
+	File     string `yaml:"file"`
+	Endpoint string `yaml:"endpoint"`
+	Insecure bool   `yaml:"insecure"`
+}
+
+type Notifications struct {
+	DedupeWindow   time.Duration    `yaml:"dedupeWindow"`
+	QuietHours     tours.QuietHours `yaml:"quietHours"`
+	DigestInterval time.Duration    `yaml:"digestInterval"`
+	Notifiers      []Notifier       `yaml:"notifiers"`
+}
+
+// Notifier configures one notification backend. Only the fields for its Type are used
+type Notifier struct {
+	Type string `yaml:"type"`
+
+	// Pushover
+	AppToken       string `yaml:"appToken"`
+	RecipientToken string `yaml:"recipientToken"`
+
+	// SMTP
+	Addr     string   `yaml:"addr"`
+	Username string   `yaml:"username"`
+	Password string   `yaml:"password"`
+	From     string   `yaml:"from"`
+	To       []string `yaml:"to"`
+
+	// ntfy and Gotify
+	URL   string `yaml:"url"`
+	Topic string `yaml:"topic"`
+	Token string `yaml:"token"`
+
+	// Slack
+	WebhookURL string `yaml:"webhookUrl"`
+
+	// Matrix
+	Homeserver  string `yaml:"homeserver"`
+	AccessToken string `yaml:"accessToken"`
+	RoomID      string `yaml:"roomId"`
+}
+
+type Hooks struct {
+	Timeout     time.Duration `yaml:"timeout"`
+	Concurrency int           `yaml:"concurrency"`
+	Commands    []Hook        `yaml:"commands"`
+}
+
+type Hook struct {
+	Event   tours.EventType `yaml:"event"`
+	Command string          `yaml:"command"`
+}
+
+type Tour struct {
+	Name          string                     `yaml:"name"`
+	Link          string                     `yaml:"link"`
+	APIURL        string                     `yaml:"apiUrl"`
+	ProductID     uuid.UUID                  `yaml:"productId"`
+	LastMinute    tours.LastMinuteSettings   `yaml:"lastMinute"`
+	Notifications tours.NotificationSettings `yaml:"notifications"`
+	Paused        bool                       `yaml:"paused"`
+	Tags          []string                   `yaml:"tags"`
+}
+
+type Rule struct {
+	TourID     uuid.UUID        `yaml:"tourId"`
+	ChangeType tours.ChangeType `yaml:"changeType"`
+	Urgent     bool             `yaml:"urgent"`
+}
+
+// Load reads and validates the config file
+func Load(filename string) (*Config, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error opening config file: %w", err)
+	}
+	defer f.Close()
+
+	c, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %q: %w", filename, err)
+	}
+
+	return c, nil
+}
+
+// Parse reads and validates a config. Unknown fields are not allowed so typos are caught
+func Parse(r io.Reader) (*Config, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config: %w", err)
+	}
+
+	var root yaml.Node
+	err = yaml.Unmarshal(data, &root)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Config{root: &root}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	err = dec.Decode(c)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	err = c.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// FieldError is a validation error for a field in the config file
+type FieldError struct {
+	Line  int
+	Field string
+	Err   error
+}
+
+func (e FieldError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %v", e.Field, e.Err)
+	}
+	return fmt.Sprintf("line %d: %s: %v", e.Line, e.Field, e.Err)
+}
+
+func (e FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Validate checks all of the settings and returns an error for each invalid field
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(err error, path ...any) {
+		if err != nil {
+			errs = append(errs, c.fieldError(err, path...))
+		}
+	}
+
+	check(nonNegative(c.Watch.Interval), "watch", "interval")
+	check(nonNegative(c.Watch.DeadManSwitch), "watch", "deadManSwitch")
+	check(nonNegative(c.Watch.ReleasePolling), "watch", "releasePolling")
+	check(nonNegative(c.Notifications.DedupeWindow), "notifications", "dedupeWindow")
+	check(nonNegative(c.Notifications.DigestInterval), "notifications", "digestInterval")
+	check(nonNegative(c.Hooks.Timeout), "hooks", "timeout")
+	if c.Hooks.Concurrency < 0 {
+		check(errors.New("cannot be negative"), "hooks", "concurrency")
+	}
+
+	switch c.Tracing.Exporter {
+	case "", tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterFile, tracing.ExporterOTLP:
+	default:
+		check(fmt.Errorf("invalid exporter %q: expected one of none, stdout, file, otlp", c.Tracing.Exporter), "tracing", "exporter")
+	}
+	if c.Tracing.Exporter == tracing.ExporterFile && c.Tracing.File == "" {
+		check(errors.New("a filename is required for the file exporter"), "tracing", "file")
+	}
+
+	for i, n := range c.Notifications.Notifiers {
+		_, err := n.New()
+		check(err, "notifications", "notifiers", i)
+	}
+
+	for i, h := range c.Hooks.Commands {
+		check(h.Event.Validate(), "hooks", "commands", i, "event")
+		if strings.TrimSpace(h.Command) == "" {
+			check(errors.New("missing required command"), "hooks", "commands", i, "command")
+		}
+	}
+
+	productIDs := map[uuid.UUID]bool{}
+	for i, t := range c.Tours {
+		if t.Name == "" {
+			check(errors.New("missing required name"), "tours", i)
+		}
+		switch {
+		case t.ProductID == uuid.Nil:
+			check(errors.New("missing required productId"), "tours", i)
+		case productIDs[t.ProductID]:
+			check(fmt.Errorf("duplicate productId %q", t.ProductID), "tours", i, "productId")
+		}
+		productIDs[t.ProductID] = true
+
+		check(t.LastMinute.Validate(), "tours", i, "lastMinute")
+		check(t.Notifications.Validate(), "tours", i, "notifications")
+	}
+
+	for i, r := range c.Rules {
+		check(r.ChangeType.Validate(), "rules", i, "changeType")
+	}
+
+	return errors.Join(errs...)
+}
+
+func nonNegative(d time.Duration) error {
+	if d < 0 {
+		return errors.New("cannot be negative")
+	}
+	return nil
+}
+
+// fieldError creates a FieldError for the field at the path, which is made up of mapping keys and
+// sequence indexes
+func (c *Config) fieldError(err error, path ...any) FieldError {
+	var field strings.Builder
+	for _, p := range path {
+		switch p := p.(type) {
+		case int:
+			field.WriteString("[" + strconv.Itoa(p) + "]")
+		default:
+			if field.Len() > 0 {
+				field.WriteString(".")
+			}
+			fmt.Fprint(&field, p)
+		}
+	}
+
+	return FieldError{Line: c.line(path...), Field: field.String(), Err: err}
+}
+
+// line finds the line of the node at the path. If the full path doesn't exist, like when a field is
+// omitted, the line of the closest parent is used
+func (c *Config) line(path ...any) int {
+	if c.root == nil {
+		return 0
+	}
+
+	node := c.root
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+
+	line := node.Line
+	for _, p := range path {
+		var next *yaml.Node
+		switch p := p.(type) {
+		case int:
+			if node.Kind == yaml.SequenceNode && p < len(node.Content) {
+				next = node.Content[p]
+			}
+		case string:
+			if node.Kind != yaml.MappingNode {
+				break
+			}
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == p {
+					next = node.Content[i+1]
+					break
+				}
+			}
+		}
+		if next == nil {
+			break
+		}
+		node = next
+		line = node.Line
+	}
+
+	return line
+}
+
+// New creates the notifier for the Type
+func (n Notifier) New() (notify.Notifier, error) {
+	switch n.Type {
+	case "pushover":
+		return notify.NewPushover(n.AppToken, n.RecipientToken)
+	case "smtp":
+		return notify.NewSMTP(n.Addr, n.Username, n.Password, n.From, n.To)
+	case "ntfy":
+		return notify.NewNtfy(n.URL, n.Topic, n.Token)
+	case "gotify":
+		return notify.NewGotify(n.URL, n.Token)
+	case "slack":
+		return notify.NewSlack(n.WebhookURL)
+	case "matrix":
+		return notify.NewMatrix(n.Homeserver, n.AccessToken, n.RoomID)
+	case "":
+		return nil, errors.New("missing required type")
+	default:
+		return nil, fmt.Errorf("invalid type %q: expected one of pushover, smtp, ntfy, gotify, slack, matrix", n.Type)
+	}
+}
+
+// TourDetails converts the tours to the stored type
+func (c *Config) TourDetails() []*tours.TourDetail {
+	result := make([]*tours.TourDetail, 0, len(c.Tours))
+	for _, t := range c.Tours {
+		result = append(result, &tours.TourDetail{
+			Name:          t.Name,
+			Link:          t.Link,
+			ApiUrl:        t.APIURL,
+			ProductID:     t.ProductID,
+			LastMinute:    t.LastMinute,
+			Notifications: t.Notifications,
+			Paused:        t.Paused,
+			Tags:          t.Tags,
+		})
+	}
+	return result
+}
+
+// AlertRules converts the rules to the stored type. IDs are derived from the rule's tour and change
+// type so loading the same file again updates the existing rules
+func (c *Config) AlertRules() []*tours.AlertRule {
+	result := make([]*tours.AlertRule, 0, len(c.Rules))
+	for _, r := range c.Rules {
+		result = append(result, &tours.AlertRule{
+			ID:         uuid.NewSHA1(ruleNamespace, []byte(r.TourID.String()+"/"+string(r.ChangeType))),
+			TourID:     r.TourID,
+			ChangeType: r.ChangeType,
+			Urgent:     r.Urgent,
+		})
+	}
+	return result
+}
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"walks-of-italy/tours"
+)
+
+func TestLoadExample(t *testing.T) {
+	c, err := Load("../example-config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Watch.Interval != time.Minute {
+		t.Errorf("unexpected interval: %s", c.Watch.Interval)
+	}
+	if c.Notifications.QuietHours.String() != "22:00-07:00" {
+		t.Errorf("unexpected quiet hours: %s", c.Notifications.QuietHours)
+	}
+	if len(c.Notifications.Notifiers) != 2 {
+		t.Errorf("expected 2 notifiers, got %d", len(c.Notifications.Notifiers))
+	}
+
+	tds := c.TourDetails()
+	if len(tds) != 1 || tds[0].LastMinute.Window.Duration != 72*time.Hour || !tds[0].Notifications.Emergency() {
+		t.Errorf("unexpected tours: %+v", tds)
+	}
+
+	rules := c.AlertRules()
+	if len(rules) != 2 || rules[0].ChangeType != tours.ChangeReopened || !rules[0].Urgent {
+		t.Errorf("unexpected rules: %+v", rules)
+	}
+
+	again, err := Load("../example-config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.AlertRules()[0].ID != rules[0].ID {
+		t.Errorf("expected stable rule IDs")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			"UnknownField",
+			"db: test.db\nintervl: 1m\n",
+			[]string{"line 2: field intervl not found"},
+		},
+		{
+			"InvalidDuration",
+			"watch:\n  interval: soon\n",
+			[]string{"line 2:"},
+		},
+		{
+			"Validation",
+			`watch:
+  interval: -1m
+notifications:
+  notifiers:
+    - type: pushover
+      appToken: token
+    - type: carrier-pigeon
+tours:
+  - name: Tour
+rules:
+  - changeType: teleported
+`,
+			[]string{
+				"line 2: watch.interval: cannot be negative",
+				"line 5: notifications.notifiers[0]: missing required recipient_token",
+				"line 7: notifications.notifiers[1]: invalid type \"carrier-pigeon\"",
+				"line 9: tours[0]: missing required productId",
+				"line 11: rules[0].changeType: invalid change type",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.input))
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			for _, expected := range tt.expected {
+				if !strings.Contains(err.Error(), expected) {
+					t.Errorf("expected %q in error:\n%v", expected, err)
+				}
+			}
+		})
+	}
+}
+
+func TestFieldErrorUnwrap(t *testing.T) {
+	_, err := Parse(strings.NewReader("hooks:\n  concurrency: -1\n"))
+
+	var fieldErr FieldError
+	if !errors.As(err, &fieldErr) || fieldErr.Line != 2 || fieldErr.Field != "hooks.concurrency" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+// Package metrics defines the Prometheus metrics that are collected throughout the application
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "walks_of_italy"
+
+var (
+	VentrataRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "ventrata_request_duration_seconds",
+		Help:      "Duration of Ventrata availability requests by tour and response status",
+		Buckets:   prometheus.ExponentialBuckets(0.05, 2, 10),
+	}, []string{"tour_id", "status"})
+
+	PollDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "poll_duration_seconds",
+		Help:      "Duration of polling all tours in the watch loop",
+		Buckets:   prometheus.ExponentialBuckets(0.1, 2, 10),
+	})
+
+	NewDates = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "new_dates_total",
+		Help:      "Number of new furthest-out dates detected by tour",
+	}, []string{"tour_id"})
+
+	AvailabilityChanges = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "availability_changes_total",
+		Help:      "Number of availability changes detected by tour and change type",
+	}, []string{"tour_id", "type"})
+
+	NotificationsSent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notifications_sent_total",
+		Help:      "Number of notifications delivered by notifier",
+	}, []string{"notifier"})
+
+	NotificationFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notification_failures_total",
+		Help:      "Number of failed notification delivery attempts by notifier",
+	}, []string{"notifier"})
+
+	DBQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Duration of database queries by query name",
+		Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 8),
+	}, []string{"query"})
+
+	AIToolCalls = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ai_tool_calls_total",
+		Help:      "Number of AI tool calls by tool and result",
+	}, []string{"tool", "result"})
+
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Number of HTTP requests by method, route, and status code",
+	}, []string{"method", "route", "code"})
+
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Duration of HTTP requests by method and route",
+	}, []string{"method", "route"})
+)
+
+// Handler serves the metrics in the Prometheus format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Result is used for labels that describe whether an operation succeeded
+func Result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
+
+// Since observes the duration since start
+func Since(o prometheus.Observer, start time.Time) {
+	o.Observe(time.Since(start).Seconds())
+}
+
+// Middleware records the rate and duration of HTTP requests. The route pattern is used instead of
+// the path so resource IDs don't create new series
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		route := "unknown"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		HTTPRequests.WithLabelValues(r.Method, route, strconv.Itoa(status)).Inc()
+		HTTPRequestDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+	})
+}
@@ -0,0 +1,27 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMiddleware(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Get("/tours/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	for _, id := range []string{"a", "b"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/tours/"+id, nil))
+	}
+
+	count := testutil.ToFloat64(HTTPRequests.WithLabelValues(http.MethodGet, "/tours/{id}", "404"))
+	if count != 2 {
+		t.Errorf("expected 2 requests for route, got %v", count)
+	}
+}
@@ -0,0 +1,52 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Gotify sends notifications to a Gotify server using an application token
+type Gotify struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+var _ Notifier = &Gotify{}
+
+func NewGotify(url, token string) (*Gotify, error) {
+	if url == "" {
+		return nil, errors.New("missing required url")
+	}
+	if token == "" {
+		return nil, errors.New("missing required token")
+	}
+
+	return &Gotify{
+		url:    strings.TrimSuffix(url, "/"),
+		token:  token,
+		client: http.DefaultClient,
+	}, nil
+}
+
+type gotifyMessage struct {
+	Title   string `json:"title"`
+	Message string `json:"message"`
+}
+
+func (c *Gotify) Send(ctx context.Context, n Notification) error {
+	headers := http.Header{}
+	headers.Set("X-Gotify-Key", c.token)
+
+	err := doJSON(ctx, c.client, http.MethodPost, c.url+"/message", headers, gotifyMessage{
+		Title:   n.Title,
+		Message: n.Message,
+	})
+	if err != nil {
+		return fmt.Errorf("error sending gotify message: %w", err)
+	}
+	return nil
+}
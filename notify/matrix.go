@@ -0,0 +1,66 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Matrix sends notifications as text messages to a Matrix room using the client-server API
+type Matrix struct {
+	homeserver  string
+	accessToken string
+	roomID      string
+	client      *http.Client
+}
+
+var _ Notifier = &Matrix{}
+
+func NewMatrix(homeserver, accessToken, roomID string) (*Matrix, error) {
+	if homeserver == "" {
+		return nil, errors.New("missing required homeserver")
+	}
+	if accessToken == "" {
+		return nil, errors.New("missing required access_token")
+	}
+	if roomID == "" {
+		return nil, errors.New("missing required room_id")
+	}
+
+	return &Matrix{
+		homeserver:  strings.TrimSuffix(homeserver, "/"),
+		accessToken: accessToken,
+		roomID:      roomID,
+		client:      http.DefaultClient,
+	}, nil
+}
+
+type matrixMessage struct {
+	MsgType string `json:"msgtype"`
+	Body    string `json:"body"`
+}
+
+func (c *Matrix) Send(ctx context.Context, n Notification) error {
+	// the transaction ID makes the request idempotent, so each notification needs a new one
+	endpoint := fmt.Sprintf(
+		"%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s",
+		c.homeserver, url.PathEscape(c.roomID), uuid.NewString(),
+	)
+
+	headers := http.Header{}
+	headers.Set("Authorization", fmt.Sprintf("Bearer %s", c.accessToken))
+
+	err := doJSON(ctx, c.client, http.MethodPut, endpoint, headers, matrixMessage{
+		MsgType: "m.text",
+		Body:    fmt.Sprintf("%s\n%s", n.Title, n.Message),
+	})
+	if err != nil {
+		return fmt.Errorf("error sending matrix message: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"walks-of-italy/metrics"
+	"walks-of-italy/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Notification is a message to send to a notification backend
+type Notification struct {
+	Title   string
+	Message string
+
+	// Urgent notifications should be delivered immediately instead of being held or batched
+	Urgent bool
+
+	// Optional details for backends that support them. URL is a supplementary link, Priority uses
+	// Pushover's levels from -2 to 2, and Retry and Expire are only used for emergency priority.
+	// Timestamp is when the event was detected, which may be earlier than when it is sent
+	URL       string
+	URLTitle  string
+	Priority  int
+	Sound     string
+	Retry     time.Duration
+	Expire    time.Duration
+	Timestamp time.Time
+}
+
+// Notifier sends notifications to a backend like Pushover, email, or a chat service
+type Notifier interface {
+	Send(context.Context, Notification) error
+}
+
+// Multi sends each notification to all of its notifiers and returns the combined errors
+type Multi []Notifier
+
+var _ Notifier = Multi{}
+
+func (m Multi) Send(ctx context.Context, n Notification) (err error) {
+	ctx, span := tracing.Start(ctx, "notify.Send",
+		attribute.String("notification.title", n.Title),
+		attribute.Bool("notification.urgent", n.Urgent),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	var errs []error
+	for _, backend := range Backends(m) {
+		err := backend.Send(ctx, n)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", backend.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Backend is a single notification backend with a name that identifies it, like "pushover" or
+// "ntfy-2" for the second ntfy backend
+type Backend struct {
+	Name string
+	Notifier
+}
+
+// Send creates a span for sending to the backend so slow backends can be identified
+func (b Backend) Send(ctx context.Context, n Notification) (err error) {
+	ctx, span := tracing.Start(ctx, "notify.SendBackend", attribute.String("notifier", b.Name))
+	defer func() { tracing.End(span, err) }()
+
+	err = b.Notifier.Send(ctx, n)
+	if err != nil {
+		metrics.NotificationFailures.WithLabelValues(b.Name).Inc()
+		return err
+	}
+
+	metrics.NotificationsSent.WithLabelValues(b.Name).Inc()
+	return nil
+}
+
+// Backends lists each backend in the Notifier, flattening Multi. Names are based on the type and
+// are numbered when there is more than one of a type, so they stay the same as long as the order
+// of the configured backends doesn't change
+func Backends(n Notifier) []Backend {
+	var result []Backend
+	counts := map[string]int{}
+
+	var add func(Notifier)
+	add = func(n Notifier) {
+		switch n := n.(type) {
+		case nil:
+		case Multi:
+			for _, notifier := range n {
+				add(notifier)
+			}
+		case Backend:
+			add(n.Notifier)
+		default:
+			name := backendName(n)
+			counts[name]++
+			if counts[name] > 1 {
+				name = fmt.Sprintf("%s-%d", name, counts[name])
+			}
+			result = append(result, Backend{name, n})
+		}
+	}
+	add(n)
+
+	return result
+}
+
+func backendName(n Notifier) string {
+	switch n.(type) {
+	case *Pushover:
+		return "pushover"
+	case *SMTP:
+		return "smtp"
+	case *Ntfy:
+		return "ntfy"
+	case *Gotify:
+		return "gotify"
+	case *Slack:
+		return "slack"
+	case *Matrix:
+		return "matrix"
+	default:
+		return "notifier"
+	}
+}
+
+// doJSON sends the body as JSON and expects a successful response
+func doJSON(ctx context.Context, client *http.Client, method, url string, headers http.Header, body any) error {
+	var buf bytes.Buffer
+	err := json.NewEncoder(&buf).Encode(body)
+	if err != nil {
+		return fmt.Errorf("error encoding request body: %w", err)
+	}
+
+	if headers == nil {
+		headers = http.Header{}
+	}
+	headers.Set("Content-Type", "application/json")
+
+	return do(ctx, client, method, url, headers, &buf)
+}
+
+func do(ctx context.Context, client *http.Client, method, url string, headers http.Header, body io.Reader) error {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+
+	for key, values := range headers {
+		for _, v := range values {
+			req.Header.Add(key, v)
+		}
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error executing request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected response code: %d, body: %q", resp.StatusCode, string(respBody))
+	}
+
+	return nil
+}
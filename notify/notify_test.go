@@ -0,0 +1,431 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"walks-of-italy/metrics"
+
+	"github.com/gregdel/pushover"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+var testNotification = Notification{
+	Title:   "New tour availabilities posted",
+	Message: "Tour: Colosseum\nDate: 2025-11-15",
+}
+
+type receivedRequest struct {
+	method string
+	path   string
+	header http.Header
+	body   string
+}
+
+// newReceiver starts a server that records the last request and responds with the status code
+func newReceiver(t *testing.T, status int) (*httptest.Server, *receivedRequest) {
+	t.Helper()
+
+	var received receivedRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = receivedRequest{r.Method, r.URL.Path, r.Header, string(body)}
+
+		// Pushover requires rate limit headers in the response
+		w.Header().Set("X-Limit-App-Limit", "10000")
+		w.Header().Set("X-Limit-App-Remaining", "9999")
+		w.Header().Set("X-Limit-App-Reset", "1393653600")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"status":1,"request":"abc","receipt":"receipt"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &received
+}
+
+func TestNtfy(t *testing.T) {
+	server, received := newReceiver(t, http.StatusOK)
+
+	n, err := NewNtfy(server.URL, "tours", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = n.Send(context.Background(), testNotification)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if received.path != "/tours" {
+		t.Errorf("unexpected path: %q", received.path)
+	}
+	if received.header.Get("Title") != testNotification.Title {
+		t.Errorf("unexpected title: %q", received.header.Get("Title"))
+	}
+	if received.header.Get("Authorization") != "Bearer secret" {
+		t.Errorf("unexpected authorization: %q", received.header.Get("Authorization"))
+	}
+	if received.body != testNotification.Message {
+		t.Errorf("unexpected body: %q", received.body)
+	}
+}
+
+func TestGotify(t *testing.T) {
+	server, received := newReceiver(t, http.StatusOK)
+
+	n, err := NewGotify(server.URL, "app-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = n.Send(context.Background(), testNotification)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if received.path != "/message" || received.header.Get("X-Gotify-Key") != "app-token" {
+		t.Errorf("unexpected request: %+v", received)
+	}
+
+	var msg gotifyMessage
+	err = json.Unmarshal([]byte(received.body), &msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Title != testNotification.Title || msg.Message != testNotification.Message {
+		t.Errorf("unexpected message: %+v", msg)
+	}
+}
+
+func TestSlack(t *testing.T) {
+	server, received := newReceiver(t, http.StatusOK)
+
+	n, err := NewSlack(server.URL + "/services/hook")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = n.Send(context.Background(), testNotification)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var msg slackMessage
+	err = json.Unmarshal([]byte(received.body), &msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Text != "*New tour availabilities posted*\nTour: Colosseum\nDate: 2025-11-15" {
+		t.Errorf("unexpected text: %q", msg.Text)
+	}
+}
+
+func TestSlackErrorResponse(t *testing.T) {
+	server, _ := newReceiver(t, http.StatusForbidden)
+
+	n, err := NewSlack(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = n.Send(context.Background(), testNotification)
+	if err == nil || !strings.Contains(err.Error(), "unexpected response code: 403") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestMatrix(t *testing.T) {
+	server, received := newReceiver(t, http.StatusOK)
+
+	n, err := NewMatrix(server.URL, "access-token", "!room:example.org")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = n.Send(context.Background(), testNotification)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if received.method != http.MethodPut {
+		t.Errorf("unexpected method: %q", received.method)
+	}
+	if !strings.HasPrefix(received.path, "/_matrix/client/v3/rooms/!room:example.org/send/m.room.message/") {
+		t.Errorf("unexpected path: %q", received.path)
+	}
+	if received.header.Get("Authorization") != "Bearer access-token" {
+		t.Errorf("unexpected authorization: %q", received.header.Get("Authorization"))
+	}
+
+	var msg matrixMessage
+	err = json.Unmarshal([]byte(received.body), &msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.MsgType != "m.text" || !strings.Contains(msg.Body, "Date: 2025-11-15") {
+		t.Errorf("unexpected message: %+v", msg)
+	}
+}
+
+func TestPushover(t *testing.T) {
+	server, received := newReceiver(t, http.StatusOK)
+
+	endpoint := pushover.APIEndpoint
+	pushover.APIEndpoint = server.URL
+	t.Cleanup(func() { pushover.APIEndpoint = endpoint })
+
+	n, err := NewPushover("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", "uuuuuuuuuuuuuuuuuuuuuuuuuuuuuu")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = n.Send(context.Background(), testNotification)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if received.path != "/messages.json" || !strings.Contains(received.body, "title=New+tour+availabilities+posted") {
+		t.Errorf("unexpected request: %+v", received)
+	}
+
+	t.Run("Emergency", func(t *testing.T) {
+		tracker := &recordingTracker{}
+		n.TrackReceipts(tracker)
+
+		emergency := testNotification
+		emergency.Message = "Tour: <Vatican>\nDate: 2025-05-01"
+		emergency.Priority = pushover.PriorityEmergency
+		emergency.Sound = pushover.SoundSiren
+		emergency.URL = "https://www.walksofitaly.com/tour?date=2025-05-01"
+		emergency.Timestamp = time.Unix(1746090000, 0)
+
+		err = n.Send(context.Background(), emergency)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		form, err := url.ParseQuery(received.body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := map[string]string{
+			"message":   "<b>Tour:</b> &lt;Vatican&gt;\n<b>Date:</b> 2025-05-01",
+			"html":      "1",
+			"priority":  "2",
+			"retry":     "60",
+			"expire":    "3600",
+			"sound":     "siren",
+			"url":       emergency.URL,
+			"timestamp": "1746090000",
+		}
+		for key, value := range expected {
+			if form.Get(key) != value {
+				t.Errorf("unexpected %s: %q", key, form.Get(key))
+			}
+		}
+
+		if len(tracker.receipts) != 1 || tracker.receipts[0] != "receipt" {
+			t.Errorf("expected receipt to be tracked, got %v", tracker.receipts)
+		}
+	})
+
+	t.Run("TooLong", func(t *testing.T) {
+		long := testNotification
+		long.Message = strings.Repeat("Tour: Vatican & Sistine Chapel\n", 50)
+
+		err = n.Send(context.Background(), long)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		form, err := url.ParseQuery(received.body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		message := form.Get("message")
+		if utf8.RuneCountInString(message) > pushover.MessageMaxLength || !strings.HasSuffix(message, "…") {
+			t.Errorf("expected message to be truncated: %d", utf8.RuneCountInString(message))
+		}
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err = n.Send(ctx, testNotification)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected canceled error: %v", err)
+		}
+	})
+}
+
+func TestTruncateHTML(t *testing.T) {
+	tests := []struct {
+		message  string
+		max      int
+		expected string
+	}{
+		{"<b>Tour:</b> A", 20, "<b>Tour:</b> A"},
+		{"<b>Tour:</b> Vatican &amp; Sistine Chapel", 20, "<b>Tour:</b> Vatica…"},
+		{"<b>Tour:</b> Vatican &amp; Sistine Chapel", 26, "<b>Tour:</b> Vatican …"},
+		{"<b>Tour:</b> Vatican &amp; Sistine Chapel", 27, "<b>Tour:</b> Vatican &amp;…"},
+		{"<b>Tour name:</b> A", 12, "<b>Tour</b>…"},
+		{"<b>Tour:</b> A", 5, "…"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			result := truncateHTML(tt.message, tt.max)
+			if result != tt.expected {
+				t.Errorf("unexpected result: %q", result)
+			}
+			if utf8.RuneCountInString(result) > tt.max {
+				t.Errorf("result is too long: %d", utf8.RuneCountInString(result))
+			}
+		})
+	}
+}
+
+type recordingTracker struct {
+	receipts []string
+}
+
+func (r *recordingTracker) TrackReceipt(_ context.Context, receipt string, _ Notification) {
+	r.receipts = append(r.receipts, receipt)
+}
+
+func TestMulti(t *testing.T) {
+	okServer, okReceived := newReceiver(t, http.StatusOK)
+	errServer, _ := newReceiver(t, http.StatusInternalServerError)
+
+	ok, _ := NewSlack(okServer.URL)
+	failing, _ := NewSlack(errServer.URL)
+
+	failures := testutil.ToFloat64(metrics.NotificationFailures.WithLabelValues("slack"))
+	sent := testutil.ToFloat64(metrics.NotificationsSent.WithLabelValues("slack-2"))
+
+	err := Multi{failing, ok}.Send(context.Background(), testNotification)
+	if err == nil {
+		t.Error("expected error from failing notifier")
+	}
+	if okReceived.body == "" {
+		t.Error("expected notification to be sent after another notifier failed")
+	}
+
+	if testutil.ToFloat64(metrics.NotificationFailures.WithLabelValues("slack")) != failures+1 {
+		t.Error("expected a failure to be counted for the failing notifier")
+	}
+	if testutil.ToFloat64(metrics.NotificationsSent.WithLabelValues("slack-2")) != sent+1 {
+		t.Error("expected a sent notification to be counted for the other notifier")
+	}
+}
+
+func TestBackends(t *testing.T) {
+	slack, _ := NewSlack("https://example.com/slack")
+	first, _ := NewNtfy("https://ntfy.sh", "first", "")
+	second, _ := NewNtfy("https://ntfy.sh", "second", "")
+
+	backends := Backends(Multi{first, Multi{slack, second}})
+
+	var names []string
+	for _, b := range backends {
+		names = append(names, b.Name)
+	}
+	if strings.Join(names, ",") != "ntfy,slack,ntfy-2" {
+		t.Errorf("unexpected backends: %v", names)
+	}
+	if backends[2].Notifier != second {
+		t.Errorf("expected the second ntfy notifier")
+	}
+}
+
+// smtpSink is a minimal SMTP server that accepts a single message
+func smtpSink(t *testing.T) (string, <-chan string) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	messages := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		tp := textproto.NewConn(conn)
+		_ = tp.PrintfLine("220 localhost ready")
+
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+
+			switch cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0]); cmd {
+			case "EHLO", "HELO":
+				_ = tp.PrintfLine("250 localhost")
+			case "DATA":
+				_ = tp.PrintfLine("354 send data")
+				data, err := io.ReadAll(tp.DotReader())
+				if err != nil {
+					return
+				}
+				messages <- string(data)
+				_ = tp.PrintfLine("250 ok")
+			case "QUIT":
+				_ = tp.PrintfLine("221 bye")
+				return
+			default:
+				_ = tp.PrintfLine("250 ok")
+			}
+		}
+	}()
+
+	return listener.Addr().String(), messages
+}
+
+func TestSMTP(t *testing.T) {
+	addr, messages := smtpSink(t)
+
+	n, err := NewSMTP(addr, "", "", "walks@example.org", []string{"traveler@example.org"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = n.Send(context.Background(), testNotification)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := <-messages
+	reader := textproto.NewReader(bufio.NewReader(strings.NewReader(msg)))
+	header, err := reader.ReadMIMEHeader()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if header.Get("Subject") != testNotification.Title || header.Get("To") != "traveler@example.org" {
+		t.Errorf("unexpected header: %v", header)
+	}
+	if !strings.Contains(msg, "Date: 2025-11-15") {
+		t.Errorf("unexpected message: %q", msg)
+	}
+}
@@ -0,0 +1,53 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const defaultNtfyURL = "https://ntfy.sh"
+
+// Ntfy publishes notifications to a topic on an ntfy server
+type Ntfy struct {
+	url    string
+	topic  string
+	token  string
+	client *http.Client
+}
+
+var _ Notifier = &Ntfy{}
+
+// NewNtfy creates a notifier for the topic. The URL defaults to https://ntfy.sh and the token is
+// only required for protected topics
+func NewNtfy(url, topic, token string) (*Ntfy, error) {
+	if topic == "" {
+		return nil, errors.New("missing required topic")
+	}
+	if url == "" {
+		url = defaultNtfyURL
+	}
+
+	return &Ntfy{
+		url:    strings.TrimSuffix(url, "/"),
+		topic:  topic,
+		token:  token,
+		client: http.DefaultClient,
+	}, nil
+}
+
+func (c *Ntfy) Send(ctx context.Context, n Notification) error {
+	headers := http.Header{}
+	headers.Set("Title", n.Title)
+	if c.token != "" {
+		headers.Set("Authorization", fmt.Sprintf("Bearer %s", c.token))
+	}
+
+	err := do(ctx, c.client, http.MethodPost, fmt.Sprintf("%s/%s", c.url, c.topic), headers, strings.NewReader(n.Message))
+	if err != nil {
+		return fmt.Errorf("error sending ntfy message: %w", err)
+	}
+	return nil
+}
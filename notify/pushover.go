@@ -0,0 +1,240 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gregdel/pushover"
+)
+
+const (
+	defaultEmergencyRetry  = time.Minute
+	defaultEmergencyExpire = time.Hour
+)
+
+// MaxMessageLength is the longest message that every backend accepts. Pushover has the lowest limit
+const MaxMessageLength = pushover.MessageMaxLength
+
+// MessageLength is the length of a message as it is counted against MaxMessageLength, including the
+// formatting that Pushover adds to it
+func MessageLength(message string) int {
+	return utf8.RuneCountInString(htmlMessage(message))
+}
+
+// ReceiptTracker is called with the receipt for each emergency-priority message so it can be checked
+// for acknowledgement
+type ReceiptTracker interface {
+	TrackReceipt(ctx context.Context, receipt string, n Notification)
+}
+
+// Receipt is the status of an emergency-priority message
+type Receipt struct {
+	Acknowledged   bool
+	AcknowledgedBy string
+	AcknowledgedAt *time.Time
+	Expired        bool
+	ExpiresAt      *time.Time
+}
+
+type Pushover struct {
+	app            *pushover.Pushover
+	appToken       string
+	recipientToken string
+	client         *http.Client
+	receipts       ReceiptTracker
+}
+
+var _ Notifier = &Pushover{}
+
+func NewPushover(appToken, recipientToken string) (*Pushover, error) {
+	if appToken == "" {
+		return nil, errors.New("missing required app_token")
+	}
+	if recipientToken == "" {
+		return nil, errors.New("missing required recipient_token")
+	}
+
+	return &Pushover{
+		app:            pushover.New(appToken),
+		appToken:       appToken,
+		recipientToken: recipientToken,
+		client:         http.DefaultClient,
+	}, nil
+}
+
+// TrackReceipts sets the ReceiptTracker for emergency-priority messages
+func (c *Pushover) TrackReceipts(t ReceiptTracker) {
+	c.receipts = t
+}
+
+// Send posts the message itself instead of using the Pushover client, which does not accept a context
+func (c *Pushover) Send(ctx context.Context, n Notification) error {
+	form := url.Values{}
+	form.Set("token", c.appToken)
+	form.Set("user", c.recipientToken)
+	form.Set("title", n.Title)
+	form.Set("message", truncateHTML(htmlMessage(n.Message), MaxMessageLength))
+	form.Set("html", "1")
+	form.Set("priority", strconv.Itoa(n.Priority))
+	if n.URL != "" {
+		form.Set("url", n.URL)
+		form.Set("url_title", n.URLTitle)
+	}
+	if n.Sound != "" {
+		form.Set("sound", n.Sound)
+	}
+	if !n.Timestamp.IsZero() {
+		form.Set("timestamp", strconv.FormatInt(n.Timestamp.Unix(), 10))
+	}
+
+	if n.Priority == pushover.PriorityEmergency {
+		retry := n.Retry
+		if retry == 0 {
+			retry = defaultEmergencyRetry
+		}
+		expire := n.Expire
+		if expire == 0 {
+			expire = defaultEmergencyExpire
+		}
+		form.Set("retry", strconv.FormatFloat(retry.Seconds(), 'f', -1, 64))
+		form.Set("expire", strconv.FormatFloat(expire.Seconds(), 'f', -1, 64))
+	}
+
+	receipt, err := c.post(ctx, form)
+	if err != nil {
+		return fmt.Errorf("error sending pushover message: %w", err)
+	}
+
+	if receipt != "" && c.receipts != nil {
+		c.receipts.TrackReceipt(ctx, receipt, n)
+	}
+
+	return nil
+}
+
+type pushoverResponse struct {
+	Status  int      `json:"status"`
+	Receipt string   `json:"receipt"`
+	Errors  []string `json:"errors"`
+}
+
+// post sends the message form and returns the receipt, which is only set for emergency-priority messages
+func (c *Pushover) post(ctx context.Context, form url.Values) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, pushover.APIEndpoint+"/messages.json", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error executing request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result pushoverResponse
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return "", fmt.Errorf("unexpected response code: %d", resp.StatusCode)
+	}
+	if result.Status != 1 || resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("unexpected response code: %d, errors: %s", resp.StatusCode, strings.Join(result.Errors, ", "))
+	}
+
+	return result.Receipt, nil
+}
+
+// Receipt gets the status of an emergency-priority message
+func (c *Pushover) Receipt(receipt string) (Receipt, error) {
+	details, err := c.app.GetReceiptDetails(receipt)
+	if err != nil {
+		return Receipt{}, fmt.Errorf("error getting pushover receipt: %w", err)
+	}
+
+	return Receipt{
+		Acknowledged:   details.Acknowledged,
+		AcknowledgedBy: details.AcknowledgedBy,
+		AcknowledgedAt: details.AcknowledgedAt,
+		Expired:        details.Expired,
+		ExpiresAt:      details.ExpiresAt,
+	}, nil
+}
+
+// htmlMessage escapes the message and makes the label of "Label: value" lines bold
+func htmlMessage(message string) string {
+	lines := strings.Split(message, "\n")
+	for i, line := range lines {
+		label, value, ok := strings.Cut(line, ": ")
+		if !ok {
+			lines[i] = html.EscapeString(line)
+			continue
+		}
+		lines[i] = fmt.Sprintf("<b>%s:</b> %s", html.EscapeString(label), html.EscapeString(value))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// truncateHTML shortens a message from htmlMessage to at most max runes, ending it with an ellipsis.
+// It only cuts between tags and entities, and closes a bold tag that is left open
+func truncateHTML(message string, max int) string {
+	if utf8.RuneCountInString(message) <= max {
+		return message
+	}
+
+	const ellipsis = "…"
+
+	var (
+		count, cut, tagStart     int
+		bold, cutBold            bool
+		inTag, inEntity, stopped bool
+	)
+	for i, r := range message {
+		if !inTag && !inEntity {
+			closing := 0
+			if bold {
+				closing = len("</b>")
+			}
+			if count+closing+1 > max {
+				stopped = true
+				break
+			}
+			cut, cutBold = i, bold
+		}
+
+		switch {
+		case r == '<':
+			inTag, tagStart = true, i
+		case r == '>' && inTag:
+			inTag = false
+			switch message[tagStart : i+1] {
+			case "<b>":
+				bold = true
+			case "</b>":
+				bold = false
+			}
+		case r == '&':
+			inEntity = true
+		case r == ';' && inEntity:
+			inEntity = false
+		}
+		count++
+	}
+	if !stopped {
+		return message
+	}
+
+	result := message[:cut]
+	if cutBold {
+		result += "</b>"
+	}
+	return result + ellipsis
+}
@@ -0,0 +1,42 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Slack posts notifications to a Slack-compatible incoming webhook. Other services like Mattermost
+// and Discord (with the /slack suffix) accept the same payload
+type Slack struct {
+	webhookURL string
+	client     *http.Client
+}
+
+var _ Notifier = &Slack{}
+
+func NewSlack(webhookURL string) (*Slack, error) {
+	if webhookURL == "" {
+		return nil, errors.New("missing required webhook_url")
+	}
+
+	return &Slack{
+		webhookURL: webhookURL,
+		client:     http.DefaultClient,
+	}, nil
+}
+
+type slackMessage struct {
+	Text string `json:"text"`
+}
+
+func (c *Slack) Send(ctx context.Context, n Notification) error {
+	err := doJSON(ctx, c.client, http.MethodPost, c.webhookURL, nil, slackMessage{
+		Text: fmt.Sprintf("*%s*\n%s", n.Title, n.Message),
+	})
+	if err != nil {
+		return fmt.Errorf("error sending slack message: %w", err)
+	}
+	return nil
+}
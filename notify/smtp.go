@@ -0,0 +1,131 @@
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTP sends notifications as plain-text email. Authentication is only used if a username is set
+type SMTP struct {
+	addr     string
+	username string
+	password string
+	from     string
+	to       []string
+}
+
+var _ Notifier = &SMTP{}
+
+func NewSMTP(addr, username, password, from string, to []string) (*SMTP, error) {
+	if addr == "" {
+		return nil, errors.New("missing required addr")
+	}
+	if from == "" {
+		return nil, errors.New("missing required from")
+	}
+	if len(to) == 0 {
+		return nil, errors.New("missing required to")
+	}
+
+	return &SMTP{
+		addr:     addr,
+		username: username,
+		password: password,
+		from:     from,
+		to:       to,
+	}, nil
+}
+
+func (c *SMTP) Send(ctx context.Context, n Notification) error {
+	err := c.send(ctx, n)
+	if err != nil {
+		return fmt.Errorf("error sending email: %w", err)
+	}
+	return nil
+}
+
+func (c *SMTP) send(ctx context.Context, n Notification) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return fmt.Errorf("error connecting: %w", err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	host, _, err := net.SplitHostPort(c.addr)
+	if err != nil {
+		return fmt.Errorf("invalid addr: %w", err)
+	}
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return fmt.Errorf("error creating client: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		err = client.StartTLS(&tls.Config{ServerName: host})
+		if err != nil {
+			return fmt.Errorf("error starting TLS: %w", err)
+		}
+	}
+
+	if c.username != "" {
+		err = client.Auth(smtp.PlainAuth("", c.username, c.password, host))
+		if err != nil {
+			return fmt.Errorf("error authenticating: %w", err)
+		}
+	}
+
+	err = client.Mail(c.from)
+	if err != nil {
+		return err
+	}
+	for _, to := range c.to {
+		err = client.Rcpt(to)
+		if err != nil {
+			return err
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(c.message(n))
+	if err != nil {
+		return err
+	}
+
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+func (c *SMTP) message(n Notification) []byte {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "From: %s\r\n", c.from)
+	fmt.Fprintf(&sb, "To: %s\r\n", strings.Join(c.to, ", "))
+	fmt.Fprintf(&sb, "Subject: %s\r\n", n.Title)
+	fmt.Fprintf(&sb, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	sb.WriteString("MIME-Version: 1.0\r\n")
+	sb.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
+	sb.WriteString("\r\n")
+	sb.WriteString(strings.ReplaceAll(n.Message, "\n", "\r\n"))
+	sb.WriteString("\r\n")
+	return []byte(sb.String())
+}
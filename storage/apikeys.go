@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"walks-of-italy/storage/db"
+	"walks-of-italy/tours"
+
+	"github.com/calvinmclean/babyapi"
+	"github.com/google/uuid"
+)
+
+// APIKeyClient stores hashed API keys
+type APIKeyClient struct {
+	q *db.Queries
+}
+
+func (c Client) APIKeys() APIKeyClient {
+	return APIKeyClient{c.Queries}
+}
+
+func apiKeyFromDB(k db.ApiKey) *tours.APIKey {
+	return &tours.APIKey{
+		ID:        k.ID,
+		Name:      k.Name,
+		KeyHash:   k.KeyHash,
+		Scope:     tours.Scope(k.Scope),
+		CreatedAt: k.CreatedAt,
+	}
+}
+
+// GetByHash gets the API key with this hash. It returns babyapi.ErrNotFound if there isn't one
+func (c APIKeyClient) GetByHash(ctx context.Context, keyHash string) (*tours.APIKey, error) {
+	k, err := c.q.GetAPIKeyByHash(ctx, keyHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, babyapi.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return apiKeyFromDB(k), nil
+}
+
+func (c APIKeyClient) GetAll(ctx context.Context) ([]*tours.APIKey, error) {
+	results, err := c.q.ListAPIKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*tours.APIKey
+	for _, k := range results {
+		result = append(result, apiKeyFromDB(k))
+	}
+	return result, nil
+}
+
+func (c APIKeyClient) Add(ctx context.Context, k *tours.APIKey) error {
+	return c.q.AddAPIKey(ctx, db.AddAPIKeyParams{
+		ID:        k.ID,
+		Name:      k.Name,
+		KeyHash:   k.KeyHash,
+		Scope:     string(k.Scope),
+		CreatedAt: k.CreatedAt,
+	})
+}
+
+func (c APIKeyClient) Delete(ctx context.Context, id uuid.UUID) error {
+	return c.q.DeleteAPIKey(ctx, id)
+}
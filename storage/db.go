@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/url"
 	"strings"
+	"time"
 
 	"walks-of-italy/storage/db"
 	"walks-of-italy/tours"
@@ -48,22 +49,45 @@ func New(filename string) (*Client, error) {
 	}
 
 	return &Client{
-		db.New(database),
+		db.New(instrumentedDB{database}),
 		database,
 	}, nil
 }
 
+func (c Client) Ping(ctx context.Context) error {
+	return c.db.PingContext(ctx)
+}
+
 func (c Client) Close() {
 	c.db.Close()
 }
 
-func fromDB(tour db.Tour) *tours.TourDetail {
+func fromDB(tour db.Tour) (*tours.TourDetail, error) {
+	var quietHours tours.QuietHours
+	err := quietHours.UnmarshalText([]byte(tour.LastMinuteQuietHours))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing quiet hours for tour %q: %w", tour.Uuid, err)
+	}
+
 	return &tours.TourDetail{
 		Name:      tour.Name,
 		Link:      tour.Link,
 		ApiUrl:    tour.ApiUrl,
 		ProductID: tour.Uuid,
-	}
+		LastMinute: tours.LastMinuteSettings{
+			Window:       tours.Duration{Duration: time.Duration(tour.LastMinuteWindow) * time.Second},
+			MinVacancies: int(tour.LastMinuteMinVacancies),
+			QuietHours:   quietHours,
+		},
+		Notifications: tours.NotificationSettings{
+			Priority: int(tour.NotificationPriority),
+			Sound:    tour.NotificationSound,
+			Retry:    tours.Duration{Duration: time.Duration(tour.NotificationRetry) * time.Second},
+			Expire:   tours.Duration{Duration: time.Duration(tour.NotificationExpire) * time.Second},
+		},
+		Paused: tour.Paused,
+		Tags:   tours.ParseTags(tour.Tags),
+	}, nil
 }
 
 func (c Client) Get(ctx context.Context, id string) (*tours.TourDetail, error) {
@@ -76,7 +100,7 @@ func (c Client) Get(ctx context.Context, id string) (*tours.TourDetail, error) {
 		return nil, err
 	}
 
-	return fromDB(tour), nil
+	return fromDB(tour)
 }
 
 func (c Client) GetAll(ctx context.Context, query url.Values) ([]*tours.TourDetail, error) {
@@ -87,7 +111,11 @@ func (c Client) GetAll(ctx context.Context, query url.Values) ([]*tours.TourDeta
 
 	var result []*tours.TourDetail
 	for _, item := range results {
-		result = append(result, fromDB(item))
+		td, err := fromDB(item)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, td)
 	}
 
 	return result, nil
@@ -95,10 +123,19 @@ func (c Client) GetAll(ctx context.Context, query url.Values) ([]*tours.TourDeta
 
 func (c Client) Set(ctx context.Context, tour *tours.TourDetail) error {
 	return c.Queries.UpsertTour(ctx, db.UpsertTourParams{
-		Uuid:   tour.ProductID,
-		Name:   tour.Name,
-		Link:   tour.Link,
-		ApiUrl: tour.ApiUrl,
+		Uuid:                   tour.ProductID,
+		Name:                   tour.Name,
+		Link:                   tour.Link,
+		ApiUrl:                 tour.ApiUrl,
+		LastMinuteWindow:       int64(tour.LastMinute.Window.Seconds()),
+		LastMinuteMinVacancies: int64(tour.LastMinute.MinVacancies),
+		LastMinuteQuietHours:   tour.LastMinute.QuietHours.String(),
+		NotificationPriority:   int64(tour.Notifications.Priority),
+		NotificationSound:      tour.Notifications.Sound,
+		NotificationRetry:      int64(tour.Notifications.Retry.Seconds()),
+		NotificationExpire:     int64(tour.Notifications.Expire.Seconds()),
+		Paused:                 tour.Paused,
+		Tags:                   strings.Join(tour.Tags, ","),
 	})
 }
 
@@ -126,3 +126,43 @@ func (q *Queries) GetLatestAvailability(ctx context.Context, tourUuid uuid.UUID)
 	)
 	return i, err
 }
+
+const listLatestAvailabilities = `-- name: ListLatestAvailabilities :many
+SELECT
+    recorded_at,
+    availability_date
+FROM
+    latest_availabilities
+WHERE
+    tour_uuid = ?
+ORDER BY
+    recorded_at
+`
+
+type ListLatestAvailabilitiesRow struct {
+	RecordedAt       time.Time
+	AvailabilityDate time.Time
+}
+
+func (q *Queries) ListLatestAvailabilities(ctx context.Context, tourUuid uuid.UUID) ([]ListLatestAvailabilitiesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listLatestAvailabilities, tourUuid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListLatestAvailabilitiesRow
+	for rows.Next() {
+		var i ListLatestAvailabilitiesRow
+		if err := rows.Scan(&i.RecordedAt, &i.AvailabilityDate); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
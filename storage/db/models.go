@@ -5,11 +5,46 @@
 package db
 
 import (
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+type AlertRule struct {
+	ID         uuid.UUID
+	TourUuid   uuid.UUID
+	ChangeType string
+	Urgent     bool
+}
+
+type ApiKey struct {
+	ID        uuid.UUID
+	Name      string
+	KeyHash   string
+	Scope     string
+	CreatedAt time.Time
+}
+
+type AvailabilityChange struct {
+	ID                int64
+	TourUuid          uuid.UUID
+	RecordedAt        time.Time
+	ChangeType        string
+	Slot              time.Time
+	Vacancies         int64
+	PreviousVacancies int64
+	RawData           string
+	PreviousPrice     int64
+}
+
+type AvailabilitySnapshot struct {
+	TourUuid   uuid.UUID
+	RecordedAt time.Time
+	RawData    string
+	WindowEnd  sql.NullTime
+}
+
 type LatestAvailability struct {
 	TourUuid         uuid.UUID
 	RecordedAt       time.Time
@@ -17,9 +52,102 @@ type LatestAvailability struct {
 	RawData          string
 }
 
+type Notification struct {
+	ID            int64
+	Title         string
+	Message       string
+	RawData       string
+	Status        string
+	Attempts      int64
+	LastError     string
+	CreatedAt     time.Time
+	NextAttemptAt time.Time
+	SentAt        sql.NullTime
+	UserUuid      uuid.UUID
+}
+type NotificationDelivery struct {
+	ID             int64
+	NotificationID int64
+	Notifier       string
+	Status         string
+	Attempts       int64
+	LastError      string
+	NextAttemptAt  time.Time
+	SentAt         sql.NullTime
+}
+
+type PushoverReceipt struct {
+	Receipt        string
+	Title          string
+	Message        string
+	CreatedAt      time.Time
+	Acknowledged   bool
+	AcknowledgedBy string
+	AcknowledgedAt sql.NullTime
+	Expired        bool
+	ExpiresAt      sql.NullTime
+	LastCheckedAt  sql.NullTime
+}
+
+type Session struct {
+	TokenHash string
+	UserUuid  uuid.UUID
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+type Subscription struct {
+	ID       uuid.UUID
+	UserUuid uuid.UUID
+	TourUuid uuid.UUID
+	RuleUuid uuid.UUID
+}
+
 type Tour struct {
-	Uuid   uuid.UUID
-	Name   string
-	Link   string
-	ApiUrl string
+	Uuid                   uuid.UUID
+	Name                   string
+	Link                   string
+	ApiUrl                 string
+	LastMinuteWindow       int64
+	LastMinuteMinVacancies int64
+	LastMinuteQuietHours   string
+	NotificationPriority   int64
+	NotificationSound      string
+	NotificationRetry      int64
+	NotificationExpire     int64
+	Paused                 bool
+	Tags                   string
+}
+
+type User struct {
+	ID                uuid.UUID
+	Username          string
+	PasswordHash      string
+	PushoverRecipient string
+	Email             string
+	NtfyTopic         string
+	CreatedAt         time.Time
+}
+
+type Webhook struct {
+	ID         uuid.UUID
+	Url        string
+	Secret     string
+	TourUuid   uuid.UUID
+	EventTypes string
+}
+
+type WebhookDelivery struct {
+	ID            int64
+	WebhookUuid   uuid.UUID
+	EventUuid     uuid.UUID
+	EventType     string
+	Payload       string
+	Status        string
+	Attempts      int64
+	ResponseCode  int64
+	LastError     string
+	CreatedAt     time.Time
+	NextAttemptAt time.Time
+	DeliveredAt   sql.NullTime
 }
@@ -24,7 +24,7 @@ func (q *Queries) DeleteTour(ctx context.Context, argUuid uuid.UUID) error {
 
 const getTour = `-- name: GetTour :one
 SELECT
-    uuid, name, link, api_url
+    uuid, name, link, api_url, last_minute_window, last_minute_min_vacancies, last_minute_quiet_hours, notification_priority, notification_sound, notification_retry, notification_expire, paused, tags
 FROM
     tours
 WHERE
@@ -41,13 +41,22 @@ func (q *Queries) GetTour(ctx context.Context, argUuid uuid.UUID) (Tour, error)
 		&i.Name,
 		&i.Link,
 		&i.ApiUrl,
+		&i.LastMinuteWindow,
+		&i.LastMinuteMinVacancies,
+		&i.LastMinuteQuietHours,
+		&i.NotificationPriority,
+		&i.NotificationSound,
+		&i.NotificationRetry,
+		&i.NotificationExpire,
+		&i.Paused,
+		&i.Tags,
 	)
 	return i, err
 }
 
 const listTours = `-- name: ListTours :many
 SELECT
-    uuid, name, link, api_url
+    uuid, name, link, api_url, last_minute_window, last_minute_min_vacancies, last_minute_quiet_hours, notification_priority, notification_sound, notification_retry, notification_expire, paused, tags
 FROM
     tours
 `
@@ -66,6 +75,15 @@ func (q *Queries) ListTours(ctx context.Context) ([]Tour, error) {
 			&i.Name,
 			&i.Link,
 			&i.ApiUrl,
+			&i.LastMinuteWindow,
+			&i.LastMinuteMinVacancies,
+			&i.LastMinuteQuietHours,
+			&i.NotificationPriority,
+			&i.NotificationSound,
+			&i.NotificationRetry,
+			&i.NotificationExpire,
+			&i.Paused,
+			&i.Tags,
 		); err != nil {
 			return nil, err
 		}
@@ -82,21 +100,53 @@ func (q *Queries) ListTours(ctx context.Context) ([]Tour, error) {
 
 const upsertTour = `-- name: UpsertTour :exec
 INSERT INTO
-    tours (uuid, name, link, api_url)
+    tours (
+        uuid,
+        name,
+        link,
+        api_url,
+        last_minute_window,
+        last_minute_min_vacancies,
+        last_minute_quiet_hours,
+        notification_priority,
+        notification_sound,
+        notification_retry,
+        notification_expire,
+        paused,
+        tags
+    )
 VALUES
-    (?, ?, ?, ?) ON CONFLICT (uuid) DO
+    (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT (uuid) DO
 UPDATE
 SET
     name = EXCLUDED.name,
     link = EXCLUDED.link,
-    api_url = EXCLUDED.api_url
+    api_url = EXCLUDED.api_url,
+    last_minute_window = EXCLUDED.last_minute_window,
+    last_minute_min_vacancies = EXCLUDED.last_minute_min_vacancies,
+    last_minute_quiet_hours = EXCLUDED.last_minute_quiet_hours,
+    notification_priority = EXCLUDED.notification_priority,
+    notification_sound = EXCLUDED.notification_sound,
+    notification_retry = EXCLUDED.notification_retry,
+    notification_expire = EXCLUDED.notification_expire,
+    paused = EXCLUDED.paused,
+    tags = EXCLUDED.tags
 `
 
 type UpsertTourParams struct {
-	Uuid   uuid.UUID
-	Name   string
-	Link   string
-	ApiUrl string
+	Uuid                   uuid.UUID
+	Name                   string
+	Link                   string
+	ApiUrl                 string
+	LastMinuteWindow       int64
+	LastMinuteMinVacancies int64
+	LastMinuteQuietHours   string
+	NotificationPriority   int64
+	NotificationSound      string
+	NotificationRetry      int64
+	NotificationExpire     int64
+	Paused                 bool
+	Tags                   string
 }
 
 func (q *Queries) UpsertTour(ctx context.Context, arg UpsertTourParams) error {
@@ -105,6 +155,15 @@ func (q *Queries) UpsertTour(ctx context.Context, arg UpsertTourParams) error {
 		arg.Name,
 		arg.Link,
 		arg.ApiUrl,
+		arg.LastMinuteWindow,
+		arg.LastMinuteMinVacancies,
+		arg.LastMinuteQuietHours,
+		arg.NotificationPriority,
+		arg.NotificationSound,
+		arg.NotificationRetry,
+		arg.NotificationExpire,
+		arg.Paused,
+		arg.Tags,
 	)
 	return err
 }
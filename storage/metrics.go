@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"walks-of-italy/metrics"
+	"walks-of-italy/storage/db"
+	"walks-of-italy/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// instrumentedDB records the duration of each query and creates a span using the query name from sqlc
+type instrumentedDB struct {
+	*sql.DB
+}
+
+var _ db.DBTX = instrumentedDB{}
+
+func (d instrumentedDB) ExecContext(ctx context.Context, query string, args ...any) (_ sql.Result, err error) {
+	ctx, done := instrumentQuery(ctx, query)
+	defer func() { done(err) }()
+	return d.DB.ExecContext(ctx, query, args...)
+}
+
+func (d instrumentedDB) QueryContext(ctx context.Context, query string, args ...any) (_ *sql.Rows, err error) {
+	ctx, done := instrumentQuery(ctx, query)
+	defer func() { done(err) }()
+	return d.DB.QueryContext(ctx, query, args...)
+}
+
+func (d instrumentedDB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	ctx, done := instrumentQuery(ctx, query)
+	row := d.DB.QueryRowContext(ctx, query, args...)
+	done(row.Err())
+	return row
+}
+
+// instrumentQuery starts a span for the query. The returned function ends the span and observes the duration
+func instrumentQuery(ctx context.Context, query string) (context.Context, func(error)) {
+	name := queryName(query)
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, "db."+name, attribute.String("db.system", "sqlite"))
+
+	return ctx, func(err error) {
+		metrics.Since(metrics.DBQueryDuration.WithLabelValues(name), start)
+		tracing.End(span, err)
+	}
+}
+
+// queryName gets the name from a query generated by sqlc, which starts with a comment like "-- name: GetTour :one"
+func queryName(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) < 3 || fields[0] != "--" || fields[1] != "name:" {
+		return "unknown"
+	}
+	return fields[2]
+}
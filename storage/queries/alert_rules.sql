@@ -0,0 +1,31 @@
+-- name: GetAlertRule :one
+SELECT
+    *
+FROM
+    alert_rules
+WHERE
+    id = ?
+LIMIT
+    1;
+
+-- name: ListAlertRules :many
+SELECT
+    *
+FROM
+    alert_rules;
+
+-- name: UpsertAlertRule :exec
+INSERT INTO
+    alert_rules (id, tour_uuid, change_type, urgent)
+VALUES
+    (?, ?, ?, ?) ON CONFLICT (id) DO
+UPDATE
+SET
+    tour_uuid = EXCLUDED.tour_uuid,
+    change_type = EXCLUDED.change_type,
+    urgent = EXCLUDED.urgent;
+
+-- name: DeleteAlertRule :exec
+DELETE FROM alert_rules
+WHERE
+    id = ?;
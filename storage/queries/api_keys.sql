@@ -0,0 +1,28 @@
+-- name: AddAPIKey :exec
+INSERT INTO
+    api_keys (id, name, key_hash, scope, created_at)
+VALUES
+    (?, ?, ?, ?, ?);
+
+-- name: GetAPIKeyByHash :one
+SELECT
+    *
+FROM
+    api_keys
+WHERE
+    key_hash = ?
+LIMIT
+    1;
+
+-- name: ListAPIKeys :many
+SELECT
+    *
+FROM
+    api_keys
+ORDER BY
+    created_at;
+
+-- name: DeleteAPIKey :exec
+DELETE FROM api_keys
+WHERE
+    id = ?;
@@ -0,0 +1,59 @@
+-- name: GetAvailabilitySnapshot :one
+SELECT
+    *
+FROM
+    availability_snapshots
+WHERE
+    tour_uuid = ?;
+
+-- name: UpsertAvailabilitySnapshot :exec
+INSERT INTO
+    availability_snapshots (tour_uuid, recorded_at, raw_data, window_end)
+VALUES
+    (?, CURRENT_TIMESTAMP, ?, ?) ON CONFLICT (tour_uuid) DO
+UPDATE
+SET
+    recorded_at = EXCLUDED.recorded_at,
+    raw_data = EXCLUDED.raw_data,
+    window_end = EXCLUDED.window_end;
+
+-- name: AddAvailabilityChange :exec
+INSERT INTO
+    availability_changes (
+        tour_uuid,
+        recorded_at,
+        change_type,
+        slot,
+        vacancies,
+        previous_vacancies,
+        previous_price,
+        raw_data
+    )
+VALUES
+    (?, CURRENT_TIMESTAMP, ?, ?, ?, ?, ?, ?);
+
+-- name: ListAvailabilityChanges :many
+-- change_type uses LIKE so '%' can be used to match all types
+SELECT
+    *
+FROM
+    availability_changes
+WHERE
+    change_type LIKE ?
+ORDER BY
+    id DESC
+LIMIT
+    ?;
+
+-- name: ListAvailabilityChangesForTour :many
+SELECT
+    *
+FROM
+    availability_changes
+WHERE
+    tour_uuid = ?
+    AND change_type LIKE ?
+ORDER BY
+    id DESC
+LIMIT
+    ?;
@@ -45,3 +45,14 @@ INSERT INTO
     )
 VALUES
     (?, CURRENT_TIMESTAMP, ?, ?);
+
+-- name: ListLatestAvailabilities :many
+SELECT
+    recorded_at,
+    availability_date
+FROM
+    latest_availabilities
+WHERE
+    tour_uuid = ?
+ORDER BY
+    recorded_at;
@@ -0,0 +1,77 @@
+-- name: AddNotification :one
+INSERT INTO
+    notifications (
+        title,
+        message,
+        raw_data,
+        status,
+        created_at,
+        next_attempt_at,
+        user_uuid
+    )
+VALUES
+    (?, ?, ?, ?, ?, ?, ?) RETURNING *;
+
+-- name: UpdateNotificationAttempt :exec
+UPDATE notifications
+SET
+    status = ?,
+    attempts = ?,
+    last_error = ?,
+    next_attempt_at = ?,
+    sent_at = ?
+WHERE
+    id = ?;
+
+-- name: ListDueNotifications :many
+SELECT
+    *
+FROM
+    notifications
+WHERE
+    status = 'pending'
+    AND next_attempt_at <= ?
+ORDER BY
+    id;
+
+-- name: ListNotifications :many
+SELECT
+    *
+FROM
+    notifications
+ORDER BY
+    id DESC
+LIMIT
+    ?;
+
+-- name: AddNotificationDelivery :one
+INSERT INTO
+    notification_deliveries (
+        notification_id,
+        notifier,
+        status,
+        next_attempt_at
+    )
+VALUES
+    (?, ?, ?, ?) RETURNING *;
+
+-- name: UpdateNotificationDelivery :exec
+UPDATE notification_deliveries
+SET
+    status = ?,
+    attempts = ?,
+    last_error = ?,
+    next_attempt_at = ?,
+    sent_at = ?
+WHERE
+    id = ?;
+
+-- name: ListNotificationDeliveries :many
+SELECT
+    *
+FROM
+    notification_deliveries
+WHERE
+    notification_id = ?
+ORDER BY
+    id;
@@ -0,0 +1,36 @@
+-- name: AddPushoverReceipt :exec
+INSERT INTO
+    pushover_receipts (receipt, title, message, created_at)
+VALUES
+    (?, ?, ?, ?);
+
+-- name: UpdatePushoverReceipt :exec
+UPDATE pushover_receipts
+SET
+    acknowledged = ?,
+    acknowledged_by = ?,
+    acknowledged_at = ?,
+    expired = ?,
+    expires_at = ?,
+    last_checked_at = ?
+WHERE
+    receipt = ?;
+
+-- name: ListPendingPushoverReceipts :many
+SELECT
+    *
+FROM
+    pushover_receipts
+WHERE
+    acknowledged = FALSE
+    AND expired = FALSE;
+
+-- name: ListPushoverReceipts :many
+SELECT
+    *
+FROM
+    pushover_receipts
+ORDER BY
+    created_at DESC
+LIMIT
+    ?;
@@ -16,14 +16,37 @@ FROM
 
 -- name: UpsertTour :exec
 INSERT INTO
-    tours (uuid, name, link, api_url)
+    tours (
+        uuid,
+        name,
+        link,
+        api_url,
+        last_minute_window,
+        last_minute_min_vacancies,
+        last_minute_quiet_hours,
+        notification_priority,
+        notification_sound,
+        notification_retry,
+        notification_expire,
+        paused,
+        tags
+    )
 VALUES
-    (?, ?, ?, ?) ON CONFLICT (uuid) DO
+    (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT (uuid) DO
 UPDATE
 SET
     name = EXCLUDED.name,
     link = EXCLUDED.link,
-    api_url = EXCLUDED.api_url;
+    api_url = EXCLUDED.api_url,
+    last_minute_window = EXCLUDED.last_minute_window,
+    last_minute_min_vacancies = EXCLUDED.last_minute_min_vacancies,
+    last_minute_quiet_hours = EXCLUDED.last_minute_quiet_hours,
+    notification_priority = EXCLUDED.notification_priority,
+    notification_sound = EXCLUDED.notification_sound,
+    notification_retry = EXCLUDED.notification_retry,
+    notification_expire = EXCLUDED.notification_expire,
+    paused = EXCLUDED.paused,
+    tags = EXCLUDED.tags;
 
 -- name: DeleteTour :exec
 DELETE FROM tours
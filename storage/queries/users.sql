@@ -0,0 +1,116 @@
+-- name: GetUser :one
+SELECT
+    *
+FROM
+    users
+WHERE
+    id = ?
+LIMIT
+    1;
+
+-- name: GetUserByUsername :one
+SELECT
+    *
+FROM
+    users
+WHERE
+    username = ?
+LIMIT
+    1;
+
+-- name: ListUsers :many
+SELECT
+    *
+FROM
+    users
+ORDER BY
+    username;
+
+-- name: UpsertUser :exec
+INSERT INTO
+    users (
+        id,
+        username,
+        password_hash,
+        pushover_recipient,
+        email,
+        ntfy_topic,
+        created_at
+    )
+VALUES
+    (?, ?, ?, ?, ?, ?, ?) ON CONFLICT (id) DO
+UPDATE
+SET
+    username = EXCLUDED.username,
+    password_hash = EXCLUDED.password_hash,
+    pushover_recipient = EXCLUDED.pushover_recipient,
+    email = EXCLUDED.email,
+    ntfy_topic = EXCLUDED.ntfy_topic;
+
+-- name: DeleteUser :exec
+DELETE FROM users
+WHERE
+    id = ?;
+
+-- name: AddSession :exec
+INSERT INTO
+    sessions (token_hash, user_uuid, created_at, expires_at)
+VALUES
+    (?, ?, ?, ?);
+
+-- name: GetSession :one
+SELECT
+    *
+FROM
+    sessions
+WHERE
+    token_hash = ?
+    AND expires_at > ?
+LIMIT
+    1;
+
+-- name: DeleteSession :exec
+DELETE FROM sessions
+WHERE
+    token_hash = ?;
+
+-- name: DeleteUserSessions :exec
+DELETE FROM sessions
+WHERE
+    user_uuid = ?;
+
+-- name: DeleteExpiredSessions :exec
+DELETE FROM sessions
+WHERE
+    expires_at <= ?;
+
+-- name: ListSubscriptions :many
+SELECT
+    *
+FROM
+    subscriptions;
+
+-- name: ListUserSubscriptions :many
+SELECT
+    *
+FROM
+    subscriptions
+WHERE
+    user_uuid = ?;
+
+-- name: AddSubscription :exec
+INSERT INTO
+    subscriptions (id, user_uuid, tour_uuid, rule_uuid)
+VALUES
+    (?, ?, ?, ?);
+
+-- name: DeleteSubscription :exec
+DELETE FROM subscriptions
+WHERE
+    id = ?
+    AND user_uuid = ?;
+
+-- name: DeleteUserSubscriptions :exec
+DELETE FROM subscriptions
+WHERE
+    user_uuid = ?;
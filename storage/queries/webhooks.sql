@@ -0,0 +1,81 @@
+-- name: GetWebhook :one
+SELECT
+    *
+FROM
+    webhooks
+WHERE
+    id = ?
+LIMIT
+    1;
+
+-- name: ListWebhooks :many
+SELECT
+    *
+FROM
+    webhooks;
+
+-- name: UpsertWebhook :exec
+INSERT INTO
+    webhooks (id, url, secret, tour_uuid, event_types)
+VALUES
+    (?, ?, ?, ?, ?) ON CONFLICT (id) DO
+UPDATE
+SET
+    url = EXCLUDED.url,
+    secret = EXCLUDED.secret,
+    tour_uuid = EXCLUDED.tour_uuid,
+    event_types = EXCLUDED.event_types;
+
+-- name: DeleteWebhook :exec
+DELETE FROM webhooks
+WHERE
+    id = ?;
+
+-- name: AddWebhookDelivery :one
+INSERT INTO
+    webhook_deliveries (
+        webhook_uuid,
+        event_uuid,
+        event_type,
+        payload,
+        status,
+        created_at,
+        next_attempt_at
+    )
+VALUES
+    (?, ?, ?, ?, ?, ?, ?) RETURNING *;
+
+-- name: UpdateWebhookDeliveryAttempt :exec
+UPDATE webhook_deliveries
+SET
+    status = ?,
+    attempts = ?,
+    response_code = ?,
+    last_error = ?,
+    next_attempt_at = ?,
+    delivered_at = ?
+WHERE
+    id = ?;
+
+-- name: ListDueWebhookDeliveries :many
+SELECT
+    *
+FROM
+    webhook_deliveries
+WHERE
+    status = 'pending'
+    AND next_attempt_at <= ?
+ORDER BY
+    id;
+
+-- name: ListWebhookDeliveries :many
+SELECT
+    *
+FROM
+    webhook_deliveries
+WHERE
+    webhook_uuid = ?
+ORDER BY
+    id DESC
+LIMIT
+    ?;
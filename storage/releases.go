@@ -0,0 +1,27 @@
+package storage
+
+import (
+	"context"
+
+	"walks-of-italy/tours"
+
+	"github.com/google/uuid"
+)
+
+// Releases gets the history of a tour's latest dates, oldest first
+func (c Client) Releases(ctx context.Context, tourID uuid.UUID) ([]tours.Release, error) {
+	results, err := c.Queries.ListLatestAvailabilities(ctx, tourID)
+	if err != nil {
+		return nil, err
+	}
+
+	var releases []tours.Release
+	for i, r := range results {
+		releases = append(releases, tours.Release{
+			RecordedAt: r.RecordedAt,
+			Date:       r.AvailabilityDate,
+			First:      i == 0,
+		})
+	}
+	return releases, nil
+}
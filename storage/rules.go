@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/url"
+
+	"walks-of-italy/storage/db"
+	"walks-of-italy/tours"
+
+	"github.com/calvinmclean/babyapi"
+	"github.com/google/uuid"
+)
+
+// RuleClient implements babyapi.Storage for AlertRules
+type RuleClient struct {
+	q *db.Queries
+}
+
+var _ babyapi.Storage[*tours.AlertRule] = RuleClient{}
+
+func (c Client) Rules() RuleClient {
+	return RuleClient{c.Queries}
+}
+
+func ruleFromDB(rule db.AlertRule) *tours.AlertRule {
+	return &tours.AlertRule{
+		ID:         rule.ID,
+		TourID:     rule.TourUuid,
+		ChangeType: tours.ChangeType(rule.ChangeType),
+		Urgent:     rule.Urgent,
+	}
+}
+
+func (c RuleClient) Get(ctx context.Context, id string) (*tours.AlertRule, error) {
+	asUUID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, err
+	}
+	rule, err := c.q.GetAlertRule(ctx, asUUID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, babyapi.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return ruleFromDB(rule), nil
+}
+
+func (c RuleClient) GetAll(ctx context.Context, query url.Values) ([]*tours.AlertRule, error) {
+	results, err := c.q.ListAlertRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*tours.AlertRule
+	for _, item := range results {
+		result = append(result, ruleFromDB(item))
+	}
+
+	return result, nil
+}
+
+func (c RuleClient) Set(ctx context.Context, rule *tours.AlertRule) error {
+	return c.q.UpsertAlertRule(ctx, db.UpsertAlertRuleParams{
+		ID:         rule.ID,
+		TourUuid:   rule.TourID,
+		ChangeType: string(rule.ChangeType),
+		Urgent:     rule.Urgent,
+	})
+}
+
+func (c RuleClient) Delete(ctx context.Context, id string) error {
+	asUUID, err := uuid.Parse(id)
+	if err != nil {
+		return err
+	}
+	return c.q.DeleteAlertRule(ctx, asUUID)
+}
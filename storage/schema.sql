@@ -19,3 +19,182 @@ ADD COLUMN api_url TEXT NOT NULL DEFAULT '';
 -- rename url to link since it is a link to the site, but not used by the application
 ALTER TABLE tours
 RENAME COLUMN url TO link;
+
+-- latest full availability for each tour, used to detect changes between polls
+CREATE TABLE IF NOT EXISTS availability_snapshots (
+    tour_uuid UUID PRIMARY KEY,
+    recorded_at DATETIME NOT NULL,
+    raw_data TEXT NOT NULL,
+    FOREIGN KEY (tour_uuid) REFERENCES tours (uuid)
+);
+
+CREATE TABLE IF NOT EXISTS availability_changes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    tour_uuid UUID NOT NULL,
+    recorded_at DATETIME NOT NULL,
+    change_type TEXT NOT NULL,
+    slot DATETIME NOT NULL,
+    vacancies INTEGER NOT NULL,
+    previous_vacancies INTEGER NOT NULL,
+    raw_data TEXT NOT NULL,
+    FOREIGN KEY (tour_uuid) REFERENCES tours (uuid)
+);
+
+CREATE TABLE IF NOT EXISTS alert_rules (
+    id UUID PRIMARY KEY,
+    tour_uuid UUID NOT NULL,
+    change_type TEXT NOT NULL
+);
+
+-- urgent rules bypass quiet hours and digests
+ALTER TABLE alert_rules
+ADD COLUMN urgent BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- settings for last-minute opening alerts
+ALTER TABLE tours
+ADD COLUMN last_minute_window INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE tours
+ADD COLUMN last_minute_min_vacancies INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE tours
+ADD COLUMN last_minute_quiet_hours TEXT NOT NULL DEFAULT '';
+
+-- outbox and history for notifications. raw_data is the full notification as JSON
+CREATE TABLE IF NOT EXISTS notifications (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    title TEXT NOT NULL,
+    message TEXT NOT NULL,
+    raw_data TEXT NOT NULL,
+    status TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    next_attempt_at DATETIME NOT NULL,
+    sent_at DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS webhooks (
+    id UUID PRIMARY KEY,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    tour_uuid UUID NOT NULL,
+    event_types TEXT NOT NULL
+);
+
+-- delivery log for webhooks. payload is the exact request body so the signature is stable across retries
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    webhook_uuid UUID NOT NULL,
+    event_uuid UUID NOT NULL,
+    event_type TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    status TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    response_code INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    next_attempt_at DATETIME NOT NULL,
+    delivered_at DATETIME
+);
+
+-- per-tour notification settings. retry and expire are seconds
+ALTER TABLE tours
+ADD COLUMN notification_priority INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE tours
+ADD COLUMN notification_sound TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE tours
+ADD COLUMN notification_retry INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE tours
+ADD COLUMN notification_expire INTEGER NOT NULL DEFAULT 0;
+
+-- receipts for emergency-priority Pushover messages, which repeat until acknowledged or expired
+CREATE TABLE IF NOT EXISTS pushover_receipts (
+    receipt TEXT PRIMARY KEY,
+    title TEXT NOT NULL,
+    message TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    acknowledged BOOLEAN NOT NULL DEFAULT FALSE,
+    acknowledged_by TEXT NOT NULL DEFAULT '',
+    acknowledged_at DATETIME,
+    expired BOOLEAN NOT NULL DEFAULT FALSE,
+    expires_at DATETIME,
+    last_checked_at DATETIME
+);
+
+-- paused tours are kept but not polled
+ALTER TABLE tours
+ADD COLUMN paused BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- comma-separated tags for grouping tours, like in calendar feeds
+ALTER TABLE tours
+ADD COLUMN tags TEXT NOT NULL DEFAULT '';
+
+-- user accounts for the web UI. The notifier columns are each user's settings for the server's
+-- notification backends
+CREATE TABLE IF NOT EXISTS users (
+    id UUID PRIMARY KEY,
+    username TEXT NOT NULL UNIQUE,
+    password_hash TEXT NOT NULL,
+    pushover_recipient TEXT NOT NULL DEFAULT '',
+    email TEXT NOT NULL DEFAULT '',
+    ntfy_topic TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL
+);
+
+-- login sessions. Only a hash of the session token is stored
+CREATE TABLE IF NOT EXISTS sessions (
+    token_hash TEXT PRIMARY KEY,
+    user_uuid UUID NOT NULL,
+    created_at DATETIME NOT NULL,
+    expires_at DATETIME NOT NULL,
+    FOREIGN KEY (user_uuid) REFERENCES users (id)
+);
+
+-- a subscription is to either a tour or an alert rule, so one of tour_uuid and rule_uuid is nil
+CREATE TABLE IF NOT EXISTS subscriptions (
+    id UUID PRIMARY KEY,
+    user_uuid UUID NOT NULL,
+    tour_uuid UUID NOT NULL,
+    rule_uuid UUID NOT NULL,
+    FOREIGN KEY (user_uuid) REFERENCES users (id)
+);
+
+-- API keys for the REST API. Only a hash of each key is stored
+CREATE TABLE IF NOT EXISTS api_keys (
+    id UUID PRIMARY KEY,
+    name TEXT NOT NULL,
+    key_hash TEXT NOT NULL UNIQUE,
+    scope TEXT NOT NULL,
+    created_at DATETIME NOT NULL
+);
+
+-- adult price in cents before a price change
+ALTER TABLE availability_changes
+ADD COLUMN previous_price INTEGER NOT NULL DEFAULT 0;
+
+-- the user a notification is sent to. It is nil for notifications to the server's notifiers
+ALTER TABLE notifications
+ADD COLUMN user_uuid UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000';
+
+-- the last day of the search window for the snapshot, so slots that only appear because the window
+-- moved aren't reported as added
+ALTER TABLE availability_snapshots
+ADD COLUMN window_end DATETIME;
+
+-- each backend's delivery of a notification, so a failed backend is retried without sending to the
+-- others again
+CREATE TABLE IF NOT EXISTS notification_deliveries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    notification_id INTEGER NOT NULL,
+    notifier TEXT NOT NULL,
+    status TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    next_attempt_at DATETIME NOT NULL,
+    sent_at DATETIME,
+    FOREIGN KEY (notification_id) REFERENCES notifications (id)
+);
@@ -0,0 +1,180 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"walks-of-italy/storage/db"
+	"walks-of-italy/tours"
+
+	"github.com/calvinmclean/babyapi"
+	"github.com/google/uuid"
+)
+
+// UserClient stores users along with their sessions and subscriptions
+type UserClient struct {
+	q *db.Queries
+}
+
+func (c Client) Users() UserClient {
+	return UserClient{c.Queries}
+}
+
+func userFromDB(u db.User) *tours.User {
+	return &tours.User{
+		ID:           u.ID,
+		Username:     u.Username,
+		PasswordHash: u.PasswordHash,
+		Notifier: tours.UserNotifier{
+			PushoverRecipient: u.PushoverRecipient,
+			Email:             u.Email,
+			NtfyTopic:         u.NtfyTopic,
+		},
+		CreatedAt: u.CreatedAt,
+	}
+}
+
+func (c UserClient) Get(ctx context.Context, id uuid.UUID) (*tours.User, error) {
+	u, err := c.q.GetUser(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, babyapi.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return userFromDB(u), nil
+}
+
+func (c UserClient) GetByUsername(ctx context.Context, username string) (*tours.User, error) {
+	u, err := c.q.GetUserByUsername(ctx, username)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, babyapi.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return userFromDB(u), nil
+}
+
+func (c UserClient) GetAll(ctx context.Context) ([]*tours.User, error) {
+	results, err := c.q.ListUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*tours.User
+	for _, u := range results {
+		result = append(result, userFromDB(u))
+	}
+	return result, nil
+}
+
+func (c UserClient) Set(ctx context.Context, u *tours.User) error {
+	return c.q.UpsertUser(ctx, db.UpsertUserParams{
+		ID:                u.ID,
+		Username:          u.Username,
+		PasswordHash:      u.PasswordHash,
+		PushoverRecipient: u.Notifier.PushoverRecipient,
+		Email:             u.Notifier.Email,
+		NtfyTopic:         u.Notifier.NtfyTopic,
+		CreatedAt:         u.CreatedAt,
+	})
+}
+
+// Delete removes the user along with their sessions and subscriptions
+func (c UserClient) Delete(ctx context.Context, id uuid.UUID) error {
+	err := c.q.DeleteUserSessions(ctx, id)
+	if err != nil {
+		return fmt.Errorf("error deleting sessions: %w", err)
+	}
+
+	err = c.q.DeleteUserSubscriptions(ctx, id)
+	if err != nil {
+		return fmt.Errorf("error deleting subscriptions: %w", err)
+	}
+
+	return c.q.DeleteUser(ctx, id)
+}
+
+// AddSession stores a session using a hash of its token
+func (c UserClient) AddSession(ctx context.Context, tokenHash string, userID uuid.UUID, now, expiresAt time.Time) error {
+	return c.q.AddSession(ctx, db.AddSessionParams{
+		TokenHash: tokenHash,
+		UserUuid:  userID,
+		CreatedAt: now,
+		ExpiresAt: expiresAt,
+	})
+}
+
+// SessionUser gets the user for an unexpired session. It returns babyapi.ErrNotFound if the session
+// doesn't exist or is expired
+func (c UserClient) SessionUser(ctx context.Context, tokenHash string, now time.Time) (*tours.User, error) {
+	session, err := c.q.GetSession(ctx, db.GetSessionParams{TokenHash: tokenHash, ExpiresAt: now})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, babyapi.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Get(ctx, session.UserUuid)
+}
+
+func (c UserClient) DeleteSession(ctx context.Context, tokenHash string) error {
+	return c.q.DeleteSession(ctx, tokenHash)
+}
+
+func (c UserClient) DeleteExpiredSessions(ctx context.Context, now time.Time) error {
+	return c.q.DeleteExpiredSessions(ctx, now)
+}
+
+func subscriptionFromDB(s db.Subscription) *tours.Subscription {
+	return &tours.Subscription{
+		ID:     s.ID,
+		UserID: s.UserUuid,
+		TourID: s.TourUuid,
+		RuleID: s.RuleUuid,
+	}
+}
+
+func subscriptionsFromDB(results []db.Subscription) []*tours.Subscription {
+	var result []*tours.Subscription
+	for _, s := range results {
+		result = append(result, subscriptionFromDB(s))
+	}
+	return result
+}
+
+// Subscriptions lists subscriptions for all users
+func (c UserClient) Subscriptions(ctx context.Context) ([]*tours.Subscription, error) {
+	results, err := c.q.ListSubscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return subscriptionsFromDB(results), nil
+}
+
+func (c UserClient) UserSubscriptions(ctx context.Context, userID uuid.UUID) ([]*tours.Subscription, error) {
+	results, err := c.q.ListUserSubscriptions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return subscriptionsFromDB(results), nil
+}
+
+func (c UserClient) AddSubscription(ctx context.Context, s *tours.Subscription) error {
+	return c.q.AddSubscription(ctx, db.AddSubscriptionParams{
+		ID:       s.ID,
+		UserUuid: s.UserID,
+		TourUuid: s.TourID,
+		RuleUuid: s.RuleID,
+	})
+}
+
+// DeleteSubscription deletes one of the user's subscriptions
+func (c UserClient) DeleteSubscription(ctx context.Context, userID, id uuid.UUID) error {
+	return c.q.DeleteSubscription(ctx, db.DeleteSubscriptionParams{ID: id, UserUuid: userID})
+}
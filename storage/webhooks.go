@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/url"
+	"strings"
+
+	"walks-of-italy/storage/db"
+	"walks-of-italy/tours"
+
+	"github.com/calvinmclean/babyapi"
+	"github.com/google/uuid"
+)
+
+// WebhookClient implements babyapi.Storage for Webhooks
+type WebhookClient struct {
+	q *db.Queries
+}
+
+var _ babyapi.Storage[*tours.Webhook] = WebhookClient{}
+
+func (c Client) Webhooks() WebhookClient {
+	return WebhookClient{c.Queries}
+}
+
+func webhookFromDB(wh db.Webhook) *tours.Webhook {
+	var eventTypes []tours.EventType
+	if wh.EventTypes != "" {
+		for _, et := range strings.Split(wh.EventTypes, ",") {
+			eventTypes = append(eventTypes, tours.EventType(et))
+		}
+	}
+
+	return &tours.Webhook{
+		ID:         wh.ID,
+		URL:        wh.Url,
+		Secret:     wh.Secret,
+		TourID:     wh.TourUuid,
+		EventTypes: eventTypes,
+	}
+}
+
+func (c WebhookClient) Get(ctx context.Context, id string) (*tours.Webhook, error) {
+	asUUID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, err
+	}
+	wh, err := c.q.GetWebhook(ctx, asUUID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, babyapi.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return webhookFromDB(wh), nil
+}
+
+func (c WebhookClient) GetAll(ctx context.Context, query url.Values) ([]*tours.Webhook, error) {
+	results, err := c.q.ListWebhooks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*tours.Webhook
+	for _, item := range results {
+		result = append(result, webhookFromDB(item))
+	}
+
+	return result, nil
+}
+
+func (c WebhookClient) Set(ctx context.Context, wh *tours.Webhook) error {
+	eventTypes := make([]string, len(wh.EventTypes))
+	for i, et := range wh.EventTypes {
+		eventTypes[i] = string(et)
+	}
+
+	return c.q.UpsertWebhook(ctx, db.UpsertWebhookParams{
+		ID:         wh.ID,
+		Url:        wh.URL,
+		Secret:     wh.Secret,
+		TourUuid:   wh.TourID,
+		EventTypes: strings.Join(eventTypes, ","),
+	})
+}
+
+func (c WebhookClient) Delete(ctx context.Context, id string) error {
+	asUUID, err := uuid.Parse(id)
+	if err != nil {
+		return err
+	}
+	return c.q.DeleteWebhook(ctx, asUUID)
+}
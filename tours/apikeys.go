@@ -0,0 +1,84 @@
+package tours
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// APIKeyPrefix starts every API key so they are easy to recognize
+const APIKeyPrefix = "woi_"
+
+// Scope controls what an API key can do
+type Scope string
+
+const (
+	// ScopeRead allows read-only requests
+	ScopeRead Scope = "read"
+	// ScopeAdmin allows all requests, including creating, updating, and deleting resources
+	ScopeAdmin Scope = "admin"
+)
+
+func (s Scope) Validate() error {
+	switch s {
+	case ScopeRead, ScopeAdmin:
+		return nil
+	default:
+		return fmt.Errorf("invalid scope %q: must be %q or %q", s, ScopeRead, ScopeAdmin)
+	}
+}
+
+// Allows returns true if the scope includes the required scope
+func (s Scope) Allows(required Scope) bool {
+	return s == ScopeAdmin || s == required
+}
+
+// APIKey authenticates requests to the REST API. The key itself is only available when it's created
+type APIKey struct {
+	ID        uuid.UUID
+	Name      string
+	KeyHash   string `json:"-"`
+	Scope     Scope
+	CreatedAt time.Time
+}
+
+// NewAPIKey creates an APIKey and returns it along with the key, which is not stored
+func NewAPIKey(name string, scope Scope) (*APIKey, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", errors.New("missing required name")
+	}
+
+	err := scope.Validate()
+	if err != nil {
+		return nil, "", err
+	}
+
+	keyBytes := make([]byte, 32)
+	_, err = rand.Read(keyBytes)
+	if err != nil {
+		return nil, "", fmt.Errorf("error generating key: %w", err)
+	}
+	key := APIKeyPrefix + hex.EncodeToString(keyBytes)
+
+	return &APIKey{
+		ID:        uuid.New(),
+		Name:      name,
+		KeyHash:   HashAPIKey(key),
+		Scope:     scope,
+		CreatedAt: time.Now().UTC(),
+	}, key, nil
+}
+
+// HashAPIKey returns the hex-encoded SHA-256 hash of the key. Keys are random, so a fast hash is
+// enough and allows looking keys up by their hash
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
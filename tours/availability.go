@@ -7,15 +7,38 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"text/template"
 	"time"
 
+	"walks-of-italy/metrics"
+	"walks-of-italy/tracing"
+
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// DefaultOptionID is the product option that availability is requested for unless another is chosen
+const DefaultOptionID = "DEFAULT"
+
 func (td TourDetail) GetAvailability(ctx context.Context, accessToken string, start, end Date) (Availabilities, error) {
+	return td.GetOptionAvailability(ctx, accessToken, DefaultOptionID, start, end)
+}
+
+// GetOptionAvailability gets availability for one of the product's options, like a language
+func (td TourDetail) GetOptionAvailability(ctx context.Context, accessToken, optionID string, start, end Date) (_ Availabilities, err error) {
+	ctx, span := tracing.Start(ctx, "tours.GetAvailability",
+		attribute.String("tour.id", td.ProductID.String()),
+		attribute.String("tour.name", td.Name),
+		attribute.String("option", optionID),
+		attribute.String("start", start.String()),
+		attribute.String("end", end.String()),
+	)
+	defer func() { tracing.End(span, err) }()
+
 	requestBody := NewAvailabilityRequest(td.ProductID, start, end)
+	requestBody.OptionID = optionID
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, availabilityURL, requestBody.JSON())
 	if err != nil {
 		return Availabilities{}, fmt.Errorf("error creating request: %w", err)
@@ -35,11 +58,15 @@ func (td TourDetail) GetAvailability(ctx context.Context, accessToken string, st
 	req.Header.Set("Octo-Env", "live")
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", accessToken))
 
+	requestStart := time.Now()
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
+		metrics.Since(metrics.VentrataRequestDuration.WithLabelValues(td.ProductID.String(), "error"), requestStart)
 		return Availabilities{}, fmt.Errorf("error executing request: %w", err)
 	}
 	defer resp.Body.Close()
+	metrics.Since(metrics.VentrataRequestDuration.WithLabelValues(td.ProductID.String(), strconv.Itoa(resp.StatusCode)), requestStart)
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -70,7 +97,7 @@ type AvailabilityRequest struct {
 func NewAvailabilityRequest(productID uuid.UUID, start, end Date) AvailabilityRequest {
 	return AvailabilityRequest{
 		ProductID:      productID,
-		OptionID:       "DEFAULT",
+		OptionID:       DefaultOptionID,
 		LocalDateStart: start,
 		LocalDateEnd:   end,
 		Currency:       "USD",
@@ -104,17 +131,41 @@ func (a Availabilities) PrettySummary(w io.Writer) error {
 	return tmpl.Execute(w, a)
 }
 
+// Latest returns the furthest-out available date. If nothing is available, the result only has
+// LocalDateTimeStart set to the start date
+func (a Availabilities) Latest(start Date) AvailabilityDetail {
+	latest := AvailabilityDetail{LocalDateTimeStart: start.ToTime()}
+	for _, detail := range a {
+		if !detail.Available {
+			continue
+		}
+
+		if detail.LocalDateTimeStart.After(latest.LocalDateTimeStart) {
+			latest = detail
+		}
+	}
+
+	return latest
+}
+
 // AdultPrice returns the price for one adult in USD
 func (a AvailabilityDetail) AdultPrice() string {
-	var adultPricing UnitPricing
+	return FormatPrice(a.AdultRetail())
+}
+
+// AdultRetail returns the price for one adult in cents, or 0 if it doesn't have adult pricing
+func (a AvailabilityDetail) AdultRetail() int {
 	for _, p := range a.UnitPricing {
 		if p.UnitType == "ADULT" {
-			adultPricing = p
-			break
+			return p.Retail
 		}
 	}
+	return 0
+}
 
-	return fmt.Sprintf("$%.2f", float64(adultPricing.Retail)/100.0)
+// FormatPrice formats a price in cents as USD
+func FormatPrice(cents int) string {
+	return fmt.Sprintf("$%.2f", float64(cents)/100.0)
 }
 
 // https://docs.ventrata.com/octo-core/availability
@@ -171,3 +222,16 @@ type Pricing struct {
 	Currency          string `json:"currency"`
 	CurrencyPrecision int    `json:"currencyPrecision"`
 }
+
+// Between returns the slots that start on or after the start date and on or before the end date
+func (a Availabilities) Between(start, end Date) Availabilities {
+	var result Availabilities
+	for _, detail := range a {
+		d := DateFromTime(detail.LocalDateTimeStart).ToTime()
+		if d.Before(start.ToTime()) || d.After(end.ToTime()) {
+			continue
+		}
+		result = append(result, detail)
+	}
+	return result
+}
@@ -0,0 +1,120 @@
+package tours
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// ChangeType describes how a single availability slot changed between two polls
+type ChangeType string
+
+const (
+	ChangeSlotAdded        ChangeType = "slot_added"
+	ChangeSlotRemoved      ChangeType = "slot_removed"
+	ChangeSoldOut          ChangeType = "sold_out"
+	ChangeReopened         ChangeType = "reopened"
+	ChangeVacanciesChanged ChangeType = "vacancies_changed"
+	ChangePriceChanged     ChangeType = "price_changed"
+)
+
+var changeTypes = []ChangeType{
+	ChangeSlotAdded,
+	ChangeSlotRemoved,
+	ChangeSoldOut,
+	ChangeReopened,
+	ChangeVacanciesChanged,
+	ChangePriceChanged,
+}
+
+func (ct ChangeType) Validate() error {
+	if !slices.Contains(changeTypes, ct) {
+		return fmt.Errorf("invalid change type %q", ct)
+	}
+	return nil
+}
+
+// Change is a typed difference for one slot between a previous and current poll. Availability
+// is the current slot details, or the previous details if the slot was removed
+type Change struct {
+	Type              ChangeType `json:"type"`
+	Slot              time.Time  `json:"slot"`
+	Vacancies         int        `json:"vacancies"`
+	PreviousVacancies int        `json:"previousVacancies"`
+	// PreviousPrice is the adult price in cents before a price change
+	PreviousPrice int                `json:"previousPrice,omitempty"`
+	Availability  AvailabilityDetail `json:"availability"`
+}
+
+// Diff compares the receiver to the previous poll and returns the changes, ordered by slot. Previous
+// slots before the start date are ignored since they naturally drop out of the search window, and
+// current slots after previousEnd, the last day of the previous poll's window, are ignored since
+// they only appear because the window moved forward
+func (a Availabilities) Diff(previous Availabilities, start, previousEnd Date) []Change {
+	current := map[string]AvailabilityDetail{}
+	for _, detail := range a {
+		current[detail.slotKey()] = detail
+	}
+
+	var changes []Change
+	seen := map[string]bool{}
+	for _, prev := range previous {
+		key := prev.slotKey()
+		seen[key] = true
+
+		cur, ok := current[key]
+		if !ok {
+			if prev.LocalDateTimeStart.Before(start.ToTime()) {
+				continue
+			}
+			changes = append(changes, newChange(ChangeSlotRemoved, prev, prev.Vacancies, 0))
+			continue
+		}
+
+		switch {
+		case prev.Available && !cur.Available:
+			changes = append(changes, newChange(ChangeSoldOut, cur, prev.Vacancies, cur.Vacancies))
+		case !prev.Available && cur.Available:
+			changes = append(changes, newChange(ChangeReopened, cur, prev.Vacancies, cur.Vacancies))
+		case prev.Vacancies != cur.Vacancies:
+			changes = append(changes, newChange(ChangeVacanciesChanged, cur, prev.Vacancies, cur.Vacancies))
+		}
+
+		// slots without a price, like sold out ones, aren't compared
+		prevPrice, curPrice := prev.AdultRetail(), cur.AdultRetail()
+		if prevPrice != 0 && curPrice != 0 && prevPrice != curPrice {
+			c := newChange(ChangePriceChanged, cur, cur.Vacancies, cur.Vacancies)
+			c.PreviousPrice = prevPrice
+			changes = append(changes, c)
+		}
+	}
+
+	for _, cur := range a {
+		if seen[cur.slotKey()] || DateFromTime(cur.LocalDateTimeStart).ToTime().After(previousEnd.ToTime()) {
+			continue
+		}
+		changes = append(changes, newChange(ChangeSlotAdded, cur, 0, cur.Vacancies))
+	}
+
+	slices.SortStableFunc(changes, func(x, y Change) int {
+		return x.Slot.Compare(y.Slot)
+	})
+
+	return changes
+}
+
+func newChange(ct ChangeType, detail AvailabilityDetail, previousVacancies, vacancies int) Change {
+	return Change{
+		Type:              ct,
+		Slot:              detail.LocalDateTimeStart,
+		Vacancies:         vacancies,
+		PreviousVacancies: previousVacancies,
+		Availability:      detail,
+	}
+}
+
+// slotKey identifies a slot across polls. The ID is the slot's start time, so it is formatted
+// rather than compared directly to avoid differences in time.Location
+func (a AvailabilityDetail) slotKey() string {
+	return a.ID.Format(time.RFC3339)
+}
@@ -0,0 +1,73 @@
+package tours
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAvailabilitiesDiff(t *testing.T) {
+	slot := func(day, vacancies int, available bool) AvailabilityDetail {
+		start := time.Date(2025, time.May, day, 9, 0, 0, 0, time.UTC)
+		return AvailabilityDetail{
+			ID:                 start,
+			LocalDateTimeStart: start,
+			Available:          available,
+			Vacancies:          vacancies,
+		}
+	}
+	priced := func(a AvailabilityDetail, retail int) AvailabilityDetail {
+		a.UnitPricing = []UnitPricing{{UnitType: "ADULT", Retail: retail}}
+		return a
+	}
+
+	previous := Availabilities{
+		slot(1, 5, true),               // before start, dropped from window
+		slot(2, 5, true),               // removed
+		slot(3, 2, true),               // sold out
+		slot(4, 0, false),              // reopened
+		slot(5, 8, true),               // vacancies changed
+		slot(6, 8, true),               // unchanged
+		priced(slot(8, 4, true), 9900), // price changed
+	}
+	current := Availabilities{
+		slot(3, 0, false),
+		slot(4, 3, true),
+		slot(5, 6, true),
+		slot(6, 8, true),
+		slot(7, 10, true), // added
+		priced(slot(8, 4, true), 10900),
+		slot(9, 6, true),  // last day of the previous window
+		slot(10, 6, true), // after the previous window, so it isn't new
+	}
+
+	changes := current.Diff(previous, NewDate(2025, time.May, 2), NewDate(2025, time.May, 9))
+
+	expected := []struct {
+		ct        ChangeType
+		day       int
+		prev, cur int
+	}{
+		{ChangeSlotRemoved, 2, 5, 0},
+		{ChangeSoldOut, 3, 2, 0},
+		{ChangeReopened, 4, 0, 3},
+		{ChangeVacanciesChanged, 5, 8, 6},
+		{ChangeSlotAdded, 7, 0, 10},
+		{ChangePriceChanged, 8, 4, 4},
+		{ChangeSlotAdded, 9, 0, 6},
+	}
+
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %d changes, got %d: %+v", len(expected), len(changes), changes)
+	}
+
+	for i, e := range expected {
+		c := changes[i]
+		if c.Type != e.ct || c.Slot.Day() != e.day || c.PreviousVacancies != e.prev || c.Vacancies != e.cur {
+			t.Errorf("unexpected change at %d: %+v", i, c)
+		}
+	}
+
+	if price := changes[len(changes)-2].PreviousPrice; price != 9900 {
+		t.Errorf("expected previous price 9900, got %d", price)
+	}
+}
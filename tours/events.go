@@ -0,0 +1,86 @@
+package tours
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EventVersion is the version of the Event JSON format. It is incremented for breaking changes
+const EventVersion = 1
+
+// EventType identifies an event from the watch loop. Each ChangeType is also an EventType
+type EventType string
+
+const (
+	EventNewLatestDate EventType = "new_latest_date"
+	EventPollFailed    EventType = "poll_failed"
+)
+
+// EventTypes returns all of the valid event types
+func EventTypes() []EventType {
+	result := []EventType{EventNewLatestDate, EventPollFailed}
+	for _, ct := range changeTypes {
+		result = append(result, EventType(ct))
+	}
+	return result
+}
+
+func (et EventType) Validate() error {
+	if !slices.Contains(EventTypes(), et) {
+		return fmt.Errorf("invalid event type %q", et)
+	}
+	return nil
+}
+
+// Event is published for a tour when the watch loop detects something new or fails to poll. Date is
+// the new latest date or the slot that changed
+type Event struct {
+	Version  int        `json:"version"`
+	ID       uuid.UUID  `json:"id"`
+	Type     EventType  `json:"type"`
+	Time     time.Time  `json:"time"`
+	TourID   uuid.UUID  `json:"tourId"`
+	TourName string     `json:"tourName"`
+	Date     *time.Time `json:"date,omitempty"`
+
+	Availability *AvailabilityDetail `json:"availability,omitempty"`
+	Change       *Change             `json:"change,omitempty"`
+	Error        string              `json:"error,omitempty"`
+}
+
+func newEvent(tour TourDetail, et EventType, now time.Time) Event {
+	return Event{
+		Version:  EventVersion,
+		ID:       uuid.New(),
+		Type:     et,
+		Time:     now,
+		TourID:   tour.ProductID,
+		TourName: tour.Name,
+	}
+}
+
+// NewLatestDateEvent creates an event for a newly-published furthest-out date
+func NewLatestDateEvent(tour TourDetail, availability AvailabilityDetail, now time.Time) Event {
+	e := newEvent(tour, EventNewLatestDate, now)
+	e.Date = &availability.LocalDateTimeStart
+	e.Availability = &availability
+	return e
+}
+
+// NewChangeEvent creates an event for a change to a single slot
+func NewChangeEvent(tour TourDetail, c Change, now time.Time) Event {
+	e := newEvent(tour, EventType(c.Type), now)
+	e.Date = &c.Slot
+	e.Change = &c
+	return e
+}
+
+// NewPollFailedEvent creates an event for an error getting a tour's availability
+func NewPollFailedEvent(tour TourDetail, err error, now time.Time) Event {
+	e := newEvent(tour, EventPollFailed, now)
+	e.Error = err.Error()
+	return e
+}
@@ -0,0 +1,156 @@
+package tours
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AvailabilityFilter selects available slots. Zero values don't filter anything, so the zero value
+// matches every available slot
+type AvailabilityFilter struct {
+	// MinVacancies is the fewest vacancies a slot can have
+	MinVacancies int
+	// Party is the size of a group that books together, so a slot needs at least as many vacancies
+	Party int
+	// MaxPrice is the highest adult price in cents
+	MaxPrice int
+	// Weekdays are the days a slot can start on
+	Weekdays []time.Weekday
+	// After and Before are times of day, in minutes after midnight, that a slot has to start at or
+	// after, and before. Before isn't used when it's 0
+	After, Before int
+}
+
+// ParseAvailabilityFilter creates a filter from text values, like query parameters or flags, and
+// validates it. Empty values don't filter anything
+func ParseAvailabilityFilter(minVacancies, party int, maxPrice, weekdays, after, before string) (AvailabilityFilter, error) {
+	f := AvailabilityFilter{MinVacancies: minVacancies, Party: party}
+
+	var err error
+	if maxPrice != "" {
+		f.MaxPrice, err = ParsePrice(maxPrice)
+		if err != nil {
+			return AvailabilityFilter{}, fmt.Errorf("invalid max price: %w", err)
+		}
+	}
+	if weekdays != "" {
+		f.Weekdays, err = ParseWeekdays(weekdays)
+		if err != nil {
+			return AvailabilityFilter{}, fmt.Errorf("invalid weekday: %w", err)
+		}
+	}
+	if after != "" {
+		f.After, err = ParseTimeOfDay(after)
+		if err != nil {
+			return AvailabilityFilter{}, fmt.Errorf("invalid after: %w", err)
+		}
+	}
+	if before != "" {
+		f.Before, err = ParseTimeOfDay(before)
+		if err != nil {
+			return AvailabilityFilter{}, fmt.Errorf("invalid before: %w", err)
+		}
+	}
+
+	return f, f.Validate()
+}
+
+// Validate checks for negative values and an empty time range
+func (f AvailabilityFilter) Validate() error {
+	var errs []error
+	if f.MinVacancies < 0 {
+		errs = append(errs, errors.New("min vacancies cannot be negative"))
+	}
+	if f.Party < 0 {
+		errs = append(errs, errors.New("party cannot be negative"))
+	}
+	if f.MaxPrice < 0 {
+		errs = append(errs, errors.New("max price cannot be negative"))
+	}
+	if f.Before != 0 && f.Before <= f.After {
+		errs = append(errs, errors.New("before must be later than after"))
+	}
+	return errors.Join(errs...)
+}
+
+// Match returns true if the slot is available and matches all of the criteria. Slots use their own
+// time zone for the weekday and time of day
+func (f AvailabilityFilter) Match(a AvailabilityDetail) bool {
+	if !a.Available || a.Vacancies < max(f.MinVacancies, f.Party, 1) {
+		return false
+	}
+	if f.MaxPrice > 0 && a.AdultRetail() > f.MaxPrice {
+		return false
+	}
+
+	start := a.LocalDateTimeStart
+	if len(f.Weekdays) > 0 && !slices.Contains(f.Weekdays, start.Weekday()) {
+		return false
+	}
+
+	m := start.Hour()*60 + start.Minute()
+	if m < f.After || (f.Before != 0 && m >= f.Before) {
+		return false
+	}
+
+	return true
+}
+
+// Apply returns the slots that match the filter
+func (f AvailabilityFilter) Apply(availability Availabilities) Availabilities {
+	result := Availabilities{}
+	for _, a := range availability {
+		if f.Match(a) {
+			result = append(result, a)
+		}
+	}
+	return result
+}
+
+// ParseWeekdays parses comma-separated weekday names or their first three letters, like "sat,sunday"
+func ParseWeekdays(s string) ([]time.Weekday, error) {
+	var result []time.Weekday
+	for _, name := range strings.Split(s, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		i := slices.IndexFunc(weekdays, func(wd time.Weekday) bool {
+			full := strings.ToLower(wd.String())
+			return name == full || name == full[:3]
+		})
+		if i < 0 {
+			return nil, fmt.Errorf("unknown weekday %q", name)
+		}
+		result = append(result, weekdays[i])
+	}
+	return result, nil
+}
+
+var weekdays = []time.Weekday{
+	time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday,
+}
+
+// ParseTimeOfDay parses a time like 14:30 as minutes after midnight
+func ParseTimeOfDay(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not formatted like 14:30", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// ParsePrice parses a price in USD, like 99.50, as cents
+func ParsePrice(s string) (int, error) {
+	price, err := strconv.ParseFloat(strings.TrimPrefix(s, "$"), 64)
+	if err != nil || price < 0 || math.IsNaN(price) || math.IsInf(price, 0) {
+		return 0, fmt.Errorf("%q is not a positive number", s)
+	}
+	return int(math.Round(price * 100)), nil
+}
@@ -0,0 +1,96 @@
+package tours
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestAvailabilityFilterMatch(t *testing.T) {
+	// Saturday
+	start := time.Date(2025, time.November, 1, 9, 30, 0, 0, time.UTC)
+	slot := AvailabilityDetail{
+		LocalDateTimeStart: start,
+		LocalDateTimeEnd:   start.Add(3 * time.Hour),
+		Available:          true,
+		Vacancies:          3,
+		UnitPricing:        []UnitPricing{{UnitType: "ADULT", Retail: 9900}},
+	}
+
+	tests := []struct {
+		name     string
+		filter   AvailabilityFilter
+		slot     AvailabilityDetail
+		expected bool
+	}{
+		{"ZeroValue", AvailabilityFilter{}, slot, true},
+		{"Unavailable", AvailabilityFilter{}, AvailabilityDetail{LocalDateTimeStart: start, Vacancies: 3}, false},
+		{"NoVacancies", AvailabilityFilter{}, AvailabilityDetail{LocalDateTimeStart: start, Available: true}, false},
+		{"MinVacancies", AvailabilityFilter{MinVacancies: 3}, slot, true},
+		{"TooFewVacancies", AvailabilityFilter{MinVacancies: 4}, slot, false},
+		{"PartyTooLarge", AvailabilityFilter{Party: 4}, slot, false},
+		{"MaxPrice", AvailabilityFilter{MaxPrice: 9900}, slot, true},
+		{"TooExpensive", AvailabilityFilter{MaxPrice: 9899}, slot, false},
+		{"Weekday", AvailabilityFilter{Weekdays: []time.Weekday{time.Saturday, time.Sunday}}, slot, true},
+		{"WrongWeekday", AvailabilityFilter{Weekdays: []time.Weekday{time.Monday}}, slot, false},
+		{"After", AvailabilityFilter{After: 9*60 + 30}, slot, true},
+		{"TooEarly", AvailabilityFilter{After: 10 * 60}, slot, false},
+		{"Before", AvailabilityFilter{Before: 10 * 60}, slot, true},
+		{"TooLate", AvailabilityFilter{Before: 9*60 + 30}, slot, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Match(tt.slot); got != tt.expected {
+				t.Errorf("expected %t, got %t", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseAvailabilityFilter(t *testing.T) {
+	t.Run("Valid", func(t *testing.T) {
+		f, err := ParseAvailabilityFilter(1, 2, "$120.50", "Sat, sunday", "08:00", "14:30")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.MinVacancies != 1 || f.Party != 2 || f.MaxPrice != 12050 || f.After != 8*60 || f.Before != 14*60+30 {
+			t.Errorf("unexpected filter: %+v", f)
+		}
+		if !slices.Equal(f.Weekdays, []time.Weekday{time.Saturday, time.Sunday}) {
+			t.Errorf("unexpected weekdays: %v", f.Weekdays)
+		}
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		f, err := ParseAvailabilityFilter(0, 0, "", "", "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.MaxPrice != 0 || f.Weekdays != nil || f.After != 0 || f.Before != 0 {
+			t.Errorf("unexpected filter: %+v", f)
+		}
+	})
+
+	errTests := []struct {
+		name                             string
+		minVacancies                     int
+		maxPrice, weekday, after, before string
+	}{
+		{"NegativeVacancies", -1, "", "", "", ""},
+		{"InvalidPrice", 0, "cheap", "", "", ""},
+		{"NaNPrice", 0, "NaN", "", "", ""},
+		{"InfinitePrice", 0, "+Inf", "", "", ""},
+		{"InvalidWeekday", 0, "", "someday", "", ""},
+		{"InvalidAfter", 0, "", "", "9am", ""},
+		{"BeforeNotAfterAfter", 0, "", "", "12:00", "09:00"},
+	}
+	for _, tt := range errTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseAvailabilityFilter(tt.minVacancies, 0, tt.maxPrice, tt.weekday, tt.after, tt.before)
+			if err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
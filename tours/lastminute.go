@@ -0,0 +1,104 @@
+package tours
+
+import (
+	"errors"
+	"time"
+)
+
+// Duration is a time.Duration that uses strings like "72h" for JSON
+type Duration struct {
+	time.Duration
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	if d.Duration == 0 {
+		return []byte{}, nil
+	}
+	return []byte(d.String()), nil
+}
+
+func (d *Duration) UnmarshalText(in []byte) error {
+	if len(in) == 0 {
+		d.Duration = 0
+		return nil
+	}
+
+	var err error
+	d.Duration, err = time.ParseDuration(string(in))
+	return err
+}
+
+// LastMinuteSettings enables alerts when a slot opens up in a rolling window starting now, like the
+// next 72 hours. A slot is considered open when it is available with at least MinVacancies.
+// Alerts are disabled when the Window is zero
+type LastMinuteSettings struct {
+	Window       Duration   `yaml:"window"`
+	MinVacancies int        `yaml:"minVacancies"`
+	QuietHours   QuietHours `yaml:"quietHours"`
+}
+
+func (s LastMinuteSettings) Enabled() bool {
+	return s.Window.Duration > 0
+}
+
+func (s LastMinuteSettings) Validate() error {
+	if s.Window.Duration < 0 {
+		return errors.New("last minute window cannot be negative")
+	}
+	if s.MinVacancies < 0 {
+		return errors.New("last minute minimum vacancies cannot be negative")
+	}
+	return nil
+}
+
+// Openings returns the changes where a slot within the window became open. Use Hold to check if
+// alerts for them should wait until quiet hours end
+func (s LastMinuteSettings) Openings(changes []Change, now time.Time) []Change {
+	if !s.Enabled() {
+		return nil
+	}
+
+	windowEnd := now.Add(s.Window.Duration)
+
+	var result []Change
+	for _, c := range changes {
+		if c.Slot.Before(now) || c.Slot.After(windowEnd) {
+			continue
+		}
+
+		if s.isOpen(c) && !s.wasOpen(c) {
+			result = append(result, c)
+		}
+	}
+
+	return result
+}
+
+// Hold returns the end of quiet hours if an alert for the opening should be held until then, or
+// false if it can be sent now. Without a time zone, quiet hours use the slot's time zone, which is
+// local to the tour
+func (s LastMinuteSettings) Hold(c Change, now time.Time) (time.Time, bool) {
+	now = now.In(c.Slot.Location())
+	if !s.QuietHours.Contains(now) {
+		return time.Time{}, false
+	}
+	return s.QuietHours.Next(now), true
+}
+
+func (s LastMinuteSettings) isOpen(c Change) bool {
+	switch c.Type {
+	case ChangeSlotAdded, ChangeReopened, ChangeVacanciesChanged:
+		return c.Availability.Available && c.Vacancies >= max(s.MinVacancies, 1)
+	default:
+		return false
+	}
+}
+
+func (s LastMinuteSettings) wasOpen(c Change) bool {
+	switch c.Type {
+	case ChangeVacanciesChanged, ChangeSoldOut, ChangeSlotRemoved:
+		return c.PreviousVacancies >= max(s.MinVacancies, 1)
+	default:
+		return false
+	}
+}
@@ -0,0 +1,124 @@
+package tours
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuietHours(t *testing.T) {
+	var q QuietHours
+	err := q.UnmarshalText([]byte("22:00-07:30"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if q.String() != "22:00-07:30" {
+		t.Errorf("unexpected string: %q", q.String())
+	}
+
+	tests := []struct {
+		hour, minute int
+		expected     bool
+	}{
+		{21, 59, false},
+		{22, 0, true},
+		{3, 0, true},
+		{7, 29, true},
+		{7, 30, false},
+		{12, 0, false},
+	}
+	for _, tt := range tests {
+		tm := time.Date(2025, time.May, 1, tt.hour, tt.minute, 0, 0, time.UTC)
+		if q.Contains(tm) != tt.expected {
+			t.Errorf("unexpected result for %s", tm.Format("15:04"))
+		}
+	}
+
+	next := q.Next(time.Date(2025, time.May, 1, 23, 0, 0, 0, time.UTC))
+	if !next.Equal(time.Date(2025, time.May, 2, 7, 30, 0, 0, time.UTC)) {
+		t.Errorf("unexpected end of quiet hours: %v", next)
+	}
+}
+
+func TestQuietHoursTimeZone(t *testing.T) {
+	var q QuietHours
+	err := q.UnmarshalText([]byte("22:00-07:00 Europe/Rome"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.String() != "22:00-07:00 Europe/Rome" {
+		t.Errorf("unexpected string: %q", q.String())
+	}
+
+	// 21:30 UTC is 23:30 in Rome during summer time
+	if !q.Contains(time.Date(2025, time.July, 1, 21, 30, 0, 0, time.UTC)) {
+		t.Error("expected quiet hours in Rome")
+	}
+	if q.Contains(time.Date(2025, time.July, 1, 6, 0, 0, 0, time.UTC)) {
+		t.Error("expected 08:00 in Rome to not be quiet")
+	}
+
+	next := q.Next(time.Date(2025, time.July, 1, 21, 30, 0, 0, time.UTC))
+	if !next.Equal(time.Date(2025, time.July, 2, 5, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected end of quiet hours: %v", next)
+	}
+
+	err = q.UnmarshalText([]byte("22:00-07:00 Mars/Olympus"))
+	if err == nil {
+		t.Error("expected error for invalid time zone")
+	}
+}
+
+func TestLastMinuteOpenings(t *testing.T) {
+	now := time.Date(2025, time.May, 1, 12, 0, 0, 0, time.UTC)
+	change := func(ct ChangeType, hours, prev, cur int) Change {
+		return Change{
+			Type:              ct,
+			Slot:              now.Add(time.Duration(hours) * time.Hour),
+			Vacancies:         cur,
+			PreviousVacancies: prev,
+			Availability:      AvailabilityDetail{Available: cur > 0},
+		}
+	}
+
+	settings := LastMinuteSettings{
+		Window:       Duration{72 * time.Hour},
+		MinVacancies: 2,
+	}
+
+	changes := []Change{
+		change(ChangeReopened, 24, 0, 4),         // opened
+		change(ChangeReopened, 24, 0, 1),         // not enough vacancies
+		change(ChangeVacanciesChanged, 30, 1, 2), // now enough vacancies
+		change(ChangeVacanciesChanged, 30, 3, 2), // was already open
+		change(ChangeSlotAdded, 100, 0, 5),       // outside of window
+		change(ChangeSoldOut, 10, 3, 0),          // closed
+	}
+
+	openings := settings.Openings(changes, now)
+	if len(openings) != 2 {
+		t.Fatalf("expected 2 openings, got %d: %+v", len(openings), openings)
+	}
+	if openings[0].Vacancies != 4 || openings[1].Vacancies != 2 {
+		t.Errorf("unexpected openings: %+v", openings)
+	}
+
+	if _, hold := settings.Hold(openings[0], now); hold {
+		t.Error("expected no hold without quiet hours")
+	}
+
+	// quiet hours use the slot's time zone, where it's 14:00
+	rome := time.FixedZone("+02:00", 2*60*60)
+	opening := openings[0]
+	opening.Slot = opening.Slot.In(rome)
+	settings.QuietHours = QuietHours{Start: 13 * 60, End: 15 * 60}
+	until, hold := settings.Hold(opening, now)
+	if !hold || !until.Equal(now.Add(time.Hour)) {
+		t.Errorf("expected hold until 15:00 in the slot's time zone, got %v %t", until, hold)
+	}
+
+	settings.QuietHours.TimeZone = "UTC"
+	if _, hold := settings.Hold(opening, now); hold {
+		t.Error("expected no hold at 12:00 UTC")
+	}
+}
@@ -0,0 +1,49 @@
+package tours
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Notification priorities use Pushover's levels
+const (
+	PriorityLowest    = -2
+	PriorityLow       = -1
+	PriorityNormal    = 0
+	PriorityHigh      = 1
+	PriorityEmergency = 2
+
+	minEmergencyRetry  = 30 * time.Second
+	maxEmergencyExpire = 3 * time.Hour
+)
+
+// NotificationSettings customize notifications for a tour on backends that support them. Emergency
+// priority notifications repeat every Retry until they are acknowledged or Expire passes, which is
+// useful for rare tours that sell out quickly. Sound is the name of a Pushover sound
+type NotificationSettings struct {
+	Priority int      `yaml:"priority"`
+	Sound    string   `yaml:"sound"`
+	Retry    Duration `yaml:"retry"`
+	Expire   Duration `yaml:"expire"`
+}
+
+func (s NotificationSettings) Emergency() bool {
+	return s.Priority == PriorityEmergency
+}
+
+func (s NotificationSettings) Validate() error {
+	if s.Priority < PriorityLowest || s.Priority > PriorityEmergency {
+		return fmt.Errorf("notification priority must be between %d and %d", PriorityLowest, PriorityEmergency)
+	}
+	if s.Retry.Duration != 0 && s.Retry.Duration < minEmergencyRetry {
+		return fmt.Errorf("notification retry must be at least %s", minEmergencyRetry)
+	}
+	if s.Expire.Duration < 0 || s.Expire.Duration > maxEmergencyExpire {
+		return fmt.Errorf("notification expire must be between 0 and %s", maxEmergencyExpire)
+	}
+	if !s.Emergency() && (s.Retry.Duration != 0 || s.Expire.Duration != 0) {
+		return errors.New("notification retry and expire can only be set for emergency priority")
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package tours
+
+import (
+	"slices"
+	"time"
+)
+
+// Confidence is how reliable a ReleasePrediction is, based on how many releases it's from and how
+// regular they are
+type Confidence string
+
+const (
+	ConfidenceLow    Confidence = "low"
+	ConfidenceMedium Confidence = "medium"
+	ConfidenceHigh   Confidence = "high"
+)
+
+const (
+	// minPredictionWindow is the shortest predicted window, so releases a few minutes off are still
+	// inside it
+	minPredictionWindow = time.Hour
+	// dominantShare is the share of releases that need to be on the same weekday or hour for it to
+	// be used in the prediction
+	dominantShare = 0.5
+)
+
+// ReleasePrediction is when a tour's next batch of dates is expected to be released, based on the
+// cadence of previous releases
+type ReleasePrediction struct {
+	// Interval is the median time between releases
+	Interval Duration `json:"interval"`
+	// LeadDays is the median number of days ahead that dates are released
+	LeadDays int `json:"leadDays"`
+	// BatchDays is the median number of days that the latest date moves out with each release
+	BatchDays int `json:"batchDays"`
+	// Weekday and Hour are the most common weekday and hour for releases, if most releases happen
+	// then. They are nil otherwise
+	Weekday *time.Weekday `json:"weekday,omitempty"`
+	Hour    *int          `json:"hour,omitempty"`
+
+	// Start and End are the window that the next release is expected in
+	Start      time.Time  `json:"start"`
+	End        time.Time  `json:"end"`
+	Confidence Confidence `json:"confidence"`
+	// Releases is the number of releases used for the prediction
+	Releases int `json:"releases"`
+}
+
+// Contains returns true if t is in the predicted window
+func (p ReleasePrediction) Contains(t time.Time) bool {
+	return !t.Before(p.Start) && !t.After(p.End)
+}
+
+// PredictRelease predicts the next release from the history of a tour's latest dates. The weekday
+// and hour are found in loc. It returns false if there aren't enough releases to predict from. If the
+// predicted release was missed, the window moves forward by the interval until it ends after now
+func PredictRelease(releases []Release, now time.Time, loc *time.Location) (ReleasePrediction, bool) {
+	var recorded []time.Time
+	var leadDays, batchDays []int
+	var weekdays [7]int
+	var hours [24]int
+	for i, r := range releases {
+		if r.First {
+			continue
+		}
+
+		t := r.RecordedAt.In(loc)
+		recorded = append(recorded, t)
+		weekdays[t.Weekday()]++
+		hours[t.Hour()]++
+		leadDays = append(leadDays, r.LeadDays())
+		if i > 0 {
+			batchDays = append(batchDays, int(DateFromTime(r.Date).ToTime().Sub(DateFromTime(releases[i-1].Date).ToTime()).Hours()/24))
+		}
+	}
+
+	// at least two releases are needed to know how often they happen
+	if len(recorded) < 2 {
+		return ReleasePrediction{}, false
+	}
+
+	var intervals []time.Duration
+	for i := 1; i < len(recorded); i++ {
+		intervals = append(intervals, recorded[i].Sub(recorded[i-1]))
+	}
+	interval := median(intervals)
+	if interval <= 0 {
+		return ReleasePrediction{}, false
+	}
+
+	// the window is wide enough to include the typical difference from the interval
+	var deviations []time.Duration
+	for _, d := range intervals {
+		deviations = append(deviations, (d - interval).Abs())
+	}
+	spread := min(max(median(deviations), minPredictionWindow/2), interval/2)
+
+	p := ReleasePrediction{
+		Interval:   Duration{interval},
+		LeadDays:   median(leadDays),
+		BatchDays:  median(batchDays),
+		Confidence: confidence(len(intervals), spread, interval),
+		Releases:   len(recorded),
+	}
+
+	next := recorded[len(recorded)-1].Add(interval)
+
+	if weekday, share := mostCommon(weekdays[:]); share >= dominantShare {
+		wd := time.Weekday(weekday)
+		p.Weekday = &wd
+		// move to the closest matching weekday when releases are at least a week apart
+		if interval >= 7*24*time.Hour {
+			diff := (weekday - int(next.Weekday()) + 7) % 7
+			if diff > 3 {
+				diff -= 7
+			}
+			next = next.AddDate(0, 0, diff)
+		}
+	}
+	if hour, share := mostCommon(hours[:]); share >= dominantShare {
+		p.Hour = &hour
+		// use the usual hour when releases are at least a day apart
+		if interval >= 24*time.Hour {
+			next = time.Date(next.Year(), next.Month(), next.Day(), hour, 0, 0, 0, loc)
+		}
+	}
+
+	for next.Add(spread).Before(now) {
+		next = next.Add(interval)
+	}
+
+	p.Start = next.Add(-spread)
+	p.End = next.Add(spread)
+
+	return p, true
+}
+
+func confidence(intervals int, spread, interval time.Duration) Confidence {
+	ratio := float64(spread) / float64(interval)
+	switch {
+	case intervals >= 5 && ratio <= 0.1:
+		return ConfidenceHigh
+	case intervals >= 3 && ratio <= 0.25:
+		return ConfidenceMedium
+	default:
+		return ConfidenceLow
+	}
+}
+
+func median[T int | time.Duration](values []T) T {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	return sorted[len(sorted)/2]
+}
+
+// mostCommon returns the index with the highest count and its share of the total
+func mostCommon(counts []int) (int, float64) {
+	most, total := 0, 0
+	for i, c := range counts {
+		total += c
+		if c > counts[most] {
+			most = i
+		}
+	}
+	if total == 0 {
+		return 0, 0
+	}
+	return most, float64(counts[most]) / float64(total)
+}
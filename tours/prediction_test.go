@@ -0,0 +1,63 @@
+package tours
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPredictRelease(t *testing.T) {
+	// weekly releases on Monday around 09:00, one week of dates at a time
+	first := time.Date(2025, time.March, 3, 9, 0, 0, 0, time.UTC)
+	releases := []Release{{RecordedAt: first.AddDate(0, 0, -3), Date: first.AddDate(0, 4, -7), First: true}}
+	for i := range 6 {
+		recorded := first.AddDate(0, 0, 7*i).Add(time.Duration(i%2) * 10 * time.Minute)
+		releases = append(releases, Release{RecordedAt: recorded, Date: first.AddDate(0, 4, 7*i)})
+	}
+	last := releases[len(releases)-1].RecordedAt
+
+	t.Run("Weekly", func(t *testing.T) {
+		p, ok := PredictRelease(releases, last.Add(time.Hour), time.UTC)
+		if !ok {
+			t.Fatal("expected a prediction")
+		}
+
+		if (p.Interval.Duration - 7*24*time.Hour).Abs() > 10*time.Minute {
+			t.Errorf("unexpected interval: %s", p.Interval)
+		}
+		if p.BatchDays != 7 {
+			t.Errorf("unexpected batch days: %d", p.BatchDays)
+		}
+		if p.Weekday == nil || *p.Weekday != time.Monday {
+			t.Errorf("expected Monday, got %v", p.Weekday)
+		}
+		if p.Hour == nil || *p.Hour != 9 {
+			t.Errorf("expected hour 9, got %v", p.Hour)
+		}
+		if p.Confidence != ConfidenceHigh {
+			t.Errorf("expected high confidence, got %s", p.Confidence)
+		}
+
+		expected := time.Date(2025, time.April, 14, 9, 0, 0, 0, time.UTC)
+		if !p.Contains(expected) || p.Contains(expected.Add(-time.Hour)) {
+			t.Errorf("unexpected window: %s to %s", p.Start, p.End)
+		}
+	})
+
+	t.Run("Missed", func(t *testing.T) {
+		now := last.AddDate(0, 0, 10)
+		p, ok := PredictRelease(releases, now, time.UTC)
+		if !ok {
+			t.Fatal("expected a prediction")
+		}
+		if p.End.Before(now) || !p.Contains(time.Date(2025, time.April, 21, 9, 0, 0, 0, time.UTC)) {
+			t.Errorf("unexpected window: %s to %s", p.Start, p.End)
+		}
+	})
+
+	t.Run("NotEnoughReleases", func(t *testing.T) {
+		_, ok := PredictRelease(releases[:2], last, time.UTC)
+		if ok {
+			t.Error("expected no prediction with one release")
+		}
+	})
+}
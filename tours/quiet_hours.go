@@ -0,0 +1,121 @@
+package tours
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// QuietHours is a daily time range, like "22:00-07:00", that can wrap past midnight. It can end with
+// a time zone, like "22:00-07:00 Europe/Rome". The zero value is disabled and doesn't contain any time
+type QuietHours struct {
+	// Start and End are minutes after midnight
+	Start int
+	End   int
+	// TimeZone is an IANA time zone name. Times are used in their own location if it's empty
+	TimeZone string
+}
+
+// Enabled returns false for the zero value or when Start and End are the same
+func (q QuietHours) Enabled() bool {
+	return q.Start != q.End
+}
+
+// In converts the time to the quiet hours' time zone. It's unchanged if there isn't a time zone
+func (q QuietHours) In(t time.Time) time.Time {
+	if q.TimeZone == "" {
+		return t
+	}
+
+	// the time zone is checked when parsing, so this only fails for invalid values set directly
+	loc, err := time.LoadLocation(q.TimeZone)
+	if err != nil {
+		return t
+	}
+	return t.In(loc)
+}
+
+// Contains returns true if the time of day is within the quiet hours. The time is used in the quiet
+// hours' time zone, or its own location if there isn't one
+func (q QuietHours) Contains(t time.Time) bool {
+	if !q.Enabled() {
+		return false
+	}
+
+	t = q.In(t)
+	m := t.Hour()*60 + t.Minute()
+	if q.Start < q.End {
+		return m >= q.Start && m < q.End
+	}
+	return m >= q.Start || m < q.End
+}
+
+// Next returns the end of the quiet hours that contain t, or t if it is not in quiet hours
+func (q QuietHours) Next(t time.Time) time.Time {
+	if !q.Contains(t) {
+		return t
+	}
+
+	t = q.In(t)
+	end := time.Date(t.Year(), t.Month(), t.Day(), q.End/60, q.End%60, 0, 0, t.Location())
+	if !end.After(t) {
+		end = end.AddDate(0, 0, 1)
+	}
+	return end
+}
+
+func (q QuietHours) String() string {
+	if !q.Enabled() {
+		return ""
+	}
+	result := fmt.Sprintf("%02d:%02d-%02d:%02d", q.Start/60, q.Start%60, q.End/60, q.End%60)
+	if q.TimeZone != "" {
+		result += " " + q.TimeZone
+	}
+	return result
+}
+
+// Set implements flag.Value so QuietHours can be used as a CLI flag
+func (q *QuietHours) Set(s string) error {
+	return q.UnmarshalText([]byte(s))
+}
+
+func (q QuietHours) MarshalText() ([]byte, error) {
+	return []byte(q.String()), nil
+}
+
+func (q *QuietHours) UnmarshalText(in []byte) error {
+	if len(in) == 0 {
+		*q = QuietHours{}
+		return nil
+	}
+
+	hours, timeZone, _ := strings.Cut(strings.TrimSpace(string(in)), " ")
+	startStr, endStr, ok := strings.Cut(hours, "-")
+	if !ok {
+		return fmt.Errorf("invalid quiet hours %q: expected format 22:00-07:00 or 22:00-07:00 Europe/Rome", in)
+	}
+
+	start, err := time.Parse("15:04", startStr)
+	if err != nil {
+		return fmt.Errorf("invalid quiet hours start: %w", err)
+	}
+
+	end, err := time.Parse("15:04", endStr)
+	if err != nil {
+		return fmt.Errorf("invalid quiet hours end: %w", err)
+	}
+
+	timeZone = strings.TrimSpace(timeZone)
+	if timeZone != "" {
+		_, err = time.LoadLocation(timeZone)
+		if err != nil {
+			return fmt.Errorf("invalid quiet hours time zone: %w", err)
+		}
+	}
+
+	q.Start = start.Hour()*60 + start.Minute()
+	q.End = end.Hour()*60 + end.Minute()
+	q.TimeZone = timeZone
+	return nil
+}
@@ -0,0 +1,59 @@
+package tours
+
+import (
+	"slices"
+	"time"
+)
+
+// Release is when a new furthest-out date was first seen
+type Release struct {
+	RecordedAt time.Time
+	Date       time.Time
+	// First is true for the first recorded date. It was the furthest date when tracking started, so
+	// it's not known when it was released
+	First bool
+}
+
+// LeadDays is how many days ahead the date was when it was released
+func (r Release) LeadDays() int {
+	return int(DateFromTime(r.Date).ToTime().Sub(DateFromTime(r.RecordedAt).ToTime()).Hours() / 24)
+}
+
+// ReleaseSummary describes how far ahead and when a tour's dates are released. Weekdays and Hours
+// count releases by when they were recorded, in the location used to create the summary
+type ReleaseSummary struct {
+	Count          int
+	MinLeadDays    int
+	MaxLeadDays    int
+	MedianLeadDays int
+	Weekdays       [7]int
+	Hours          [24]int
+}
+
+// SummarizeReleases summarizes the releases. The first recorded date is skipped since it's not a release
+func SummarizeReleases(releases []Release, loc *time.Location) ReleaseSummary {
+	var s ReleaseSummary
+	var leadDays []int
+	for _, r := range releases {
+		if r.First {
+			continue
+		}
+
+		recorded := r.RecordedAt.In(loc)
+		s.Weekdays[recorded.Weekday()]++
+		s.Hours[recorded.Hour()]++
+		leadDays = append(leadDays, r.LeadDays())
+	}
+
+	s.Count = len(leadDays)
+	if s.Count == 0 {
+		return s
+	}
+
+	slices.Sort(leadDays)
+	s.MinLeadDays = leadDays[0]
+	s.MaxLeadDays = leadDays[len(leadDays)-1]
+	s.MedianLeadDays = leadDays[len(leadDays)/2]
+
+	return s
+}
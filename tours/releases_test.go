@@ -0,0 +1,33 @@
+package tours
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSummarizeReleases(t *testing.T) {
+	recorded := func(day, hour int) time.Time {
+		return time.Date(2025, time.March, day, hour, 0, 0, 0, time.UTC)
+	}
+	releases := []Release{
+		{RecordedAt: recorded(1, 8), Date: time.Date(2025, time.June, 30, 0, 0, 0, 0, time.UTC), First: true},
+		// Monday
+		{RecordedAt: recorded(3, 9), Date: time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC)},
+		{RecordedAt: recorded(10, 9), Date: time.Date(2025, time.July, 10, 0, 0, 0, 0, time.UTC)},
+		{RecordedAt: recorded(12, 15), Date: time.Date(2025, time.July, 31, 0, 0, 0, 0, time.UTC)},
+	}
+
+	s := SummarizeReleases(releases, time.UTC)
+	if s.Count != 3 {
+		t.Errorf("expected 3 releases, got %d", s.Count)
+	}
+	if s.MinLeadDays != 120 || s.MaxLeadDays != 141 || s.MedianLeadDays != 122 {
+		t.Errorf("unexpected lead days: min %d, max %d, median %d", s.MinLeadDays, s.MaxLeadDays, s.MedianLeadDays)
+	}
+	if s.Weekdays[time.Monday] != 2 || s.Weekdays[time.Wednesday] != 1 {
+		t.Errorf("unexpected weekdays: %v", s.Weekdays)
+	}
+	if s.Hours[9] != 2 || s.Hours[15] != 1 || s.Hours[8] != 0 {
+		t.Errorf("unexpected hours: %v", s.Hours)
+	}
+}
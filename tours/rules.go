@@ -0,0 +1,44 @@
+package tours
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// AlertRule subscribes to a type of availability change. If TourID is not set, the rule
+// applies to all tours. Urgent rules send notifications immediately, even during quiet hours
+type AlertRule struct {
+	ID         uuid.UUID
+	TourID     uuid.UUID
+	ChangeType ChangeType
+	Urgent     bool
+}
+
+func (r AlertRule) GetID() string {
+	return r.ID.String()
+}
+
+func (r *AlertRule) Bind(req *http.Request) error {
+	if req.Method == http.MethodPost {
+		if r.ID != uuid.Nil {
+			return errors.New("ID cannot be set when creating a rule")
+		}
+		r.ID = uuid.New()
+	}
+
+	return r.ChangeType.Validate()
+}
+
+func (*AlertRule) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// Matches returns true if the rule applies to this type of change for the tour
+func (r AlertRule) Matches(tourID uuid.UUID, ct ChangeType) bool {
+	if r.TourID != uuid.Nil && r.TourID != tourID {
+		return false
+	}
+	return r.ChangeType == ct
+}
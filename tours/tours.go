@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,10 +17,35 @@ const (
 )
 
 type TourDetail struct {
-	Name      string
-	Link      string
-	ApiUrl    string
-	ProductID uuid.UUID
+	Name          string
+	Link          string
+	ApiUrl        string
+	ProductID     uuid.UUID
+	LastMinute    LastMinuteSettings
+	Notifications NotificationSettings
+	// Paused tours are kept, but their availability is not polled
+	Paused bool
+	// Tags group tours, like by city
+	Tags []string
+}
+
+// ParseTags splits comma-separated tags, removing spaces and empty tags
+func ParseTags(s string) []string {
+	var tags []string
+	for _, tag := range strings.Split(s, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// HasTag returns true if the tour has the tag, ignoring case
+func (td TourDetail) HasTag(tag string) bool {
+	return slices.ContainsFunc(td.Tags, func(t string) bool {
+		return strings.EqualFold(t, tag)
+	})
 }
 
 func (td TourDetail) GetID() string {
@@ -26,7 +53,7 @@ func (td TourDetail) GetID() string {
 }
 
 func (td *TourDetail) Bind(r *http.Request) error {
-	return nil
+	return errors.Join(td.LastMinute.Validate(), td.Notifications.Validate())
 }
 
 func (*TourDetail) Render(w http.ResponseWriter, r *http.Request) error {
@@ -64,16 +91,5 @@ func (td TourDetail) GetLatestAvailability(ctx context.Context, accessToken stri
 		return AvailabilityDetail{}, fmt.Errorf("error getting availability: %w", err)
 	}
 
-	latest := AvailabilityDetail{LocalDateTimeStart: start.ToTime()}
-	for _, a := range availability {
-		if !a.Available {
-			continue
-		}
-
-		if a.LocalDateTimeStart.After(latest.LocalDateTimeStart) {
-			latest = a
-		}
-	}
-
-	return latest, nil
+	return availability.Latest(start), nil
 }
@@ -0,0 +1,104 @@
+package tours
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const minPasswordLength = 8
+
+// User is an account for the web UI. Users subscribe to tours and alert rules and receive notifications
+// for them with their own notifier settings
+type User struct {
+	ID           uuid.UUID
+	Username     string
+	PasswordHash string `json:"-"`
+	Notifier     UserNotifier
+	CreatedAt    time.Time
+}
+
+// UserNotifier is a user's settings for the server's notification backends. Each one that is set is
+// used, as long as the server has that backend configured
+type UserNotifier struct {
+	PushoverRecipient string
+	Email             string
+	NtfyTopic         string
+}
+
+// Enabled returns true if any notifier settings are set
+func (n UserNotifier) Enabled() bool {
+	return n != UserNotifier{}
+}
+
+// NewUser creates a user with a hashed password
+func NewUser(username, password string) (*User, error) {
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return nil, errors.New("missing required username")
+	}
+
+	u := &User{ID: uuid.New(), Username: username, CreatedAt: time.Now().UTC()}
+	err := u.SetPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
+func (u *User) SetPassword(password string) error {
+	if len(password) < minPasswordLength {
+		return fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("error hashing password: %w", err)
+	}
+
+	u.PasswordHash = string(hash)
+	return nil
+}
+
+// dummyPasswordHash is a bcrypt hash with the default cost that is checked when there is no user
+const dummyPasswordHash = "$2a$10$Db1UA1cnSLfCpzcdfXv6eefmmPgGYFG7VOJZmVmqRJzmT6r5RW9gW"
+
+// CheckPassword returns true if the password matches. A nil User never matches, but the password is
+// still compared to a dummy hash so logging in as an unknown user takes as long as a wrong password
+func (u *User) CheckPassword(password string) bool {
+	hash := dummyPasswordHash
+	if u != nil {
+		hash = u.PasswordHash
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil && u != nil
+}
+
+// Subscription subscribes a user to all notifications for a tour, or to changes matching an alert
+// rule. Exactly one of TourID and RuleID is set
+type Subscription struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+	TourID uuid.UUID
+	RuleID uuid.UUID
+}
+
+func (s Subscription) Validate() error {
+	if (s.TourID == uuid.Nil) == (s.RuleID == uuid.Nil) {
+		return errors.New("subscription must have either a tour or a rule")
+	}
+	return nil
+}
+
+// Matches returns true if the subscription is for the tour or one of the rules that matched
+func (s Subscription) Matches(tourID uuid.UUID, ruleIDs []uuid.UUID) bool {
+	if s.TourID != uuid.Nil {
+		return s.TourID == tourID
+	}
+	return slices.Contains(ruleIDs, s.RuleID)
+}
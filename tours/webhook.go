@@ -0,0 +1,85 @@
+package tours
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// Webhook receives events as signed JSON. If TourID is not set, it receives events for all tours
+// and if EventTypes is empty, it receives all types of events. The Secret is used to sign requests.
+// It is generated on create if not provided, kept on update if omitted, and is only included in
+// responses when the Webhook is created, updated, or its secret is rotated
+type Webhook struct {
+	ID         uuid.UUID
+	URL        string
+	Secret     string
+	TourID     uuid.UUID
+	EventTypes []EventType
+}
+
+func (wh Webhook) GetID() string {
+	return wh.ID.String()
+}
+
+func (wh *Webhook) Bind(r *http.Request) error {
+	if r.Method == http.MethodPost {
+		if wh.ID != uuid.Nil {
+			return errors.New("ID cannot be set when creating a webhook")
+		}
+		wh.ID = uuid.New()
+
+		if wh.Secret == "" {
+			err := wh.GenerateSecret()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	u, err := url.Parse(wh.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid URL %q", wh.URL)
+	}
+
+	for _, et := range wh.EventTypes {
+		err := et.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GenerateSecret replaces the Webhook's Secret with a new random one
+func (wh *Webhook) GenerateSecret() error {
+	secret := make([]byte, 32)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return fmt.Errorf("error generating secret: %w", err)
+	}
+	wh.Secret = hex.EncodeToString(secret)
+	return nil
+}
+
+func (wh *Webhook) Render(w http.ResponseWriter, r *http.Request) error {
+	if r.Method == http.MethodGet {
+		wh.Secret = ""
+	}
+	return nil
+}
+
+// Matches returns true if the webhook should receive the event
+func (wh Webhook) Matches(e Event) bool {
+	if wh.TourID != uuid.Nil && wh.TourID != e.TourID {
+		return false
+	}
+	return len(wh.EventTypes) == 0 || slices.Contains(wh.EventTypes, e.Type)
+}
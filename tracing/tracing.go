@@ -0,0 +1,170 @@
+// Package tracing configures OpenTelemetry tracing and provides helpers for creating spans throughout
+// the application
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	serviceName = "walks-of-italy"
+
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+	ExporterOTLP   = "otlp"
+)
+
+// Config chooses where spans are exported. Spans are still created when the exporter is "none", but
+// they are not recorded
+type Config struct {
+	// Exporter is one of none, stdout, file, or otlp
+	Exporter string
+	// File is the filename that spans are written to when using the file exporter
+	File string
+	// Endpoint is the host and port of an OTLP HTTP receiver. If it is empty, the standard
+	// OTEL_EXPORTER_OTLP_* environment variables are used
+	Endpoint string
+	// Insecure disables TLS for the OTLP exporter
+	Insecure bool
+}
+
+// Setup configures the global tracer provider and propagator. The returned function flushes remaining
+// spans and should be called before exiting
+func Setup(ctx context.Context, config Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	exporter, closer, err := newExporter(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	if exporter == nil {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName)))
+	if err != nil {
+		return nil, fmt.Errorf("error creating resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closer != nil {
+			err = errors.Join(err, closer.Close())
+		}
+		return err
+	}, nil
+}
+
+func newExporter(ctx context.Context, config Config) (sdktrace.SpanExporter, io.Closer, error) {
+	switch config.Exporter {
+	case "", ExporterNone:
+		return nil, nil, nil
+	case ExporterStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		if err != nil {
+			return nil, nil, fmt.Errorf("error creating stdout exporter: %w", err)
+		}
+		return exporter, nil, nil
+	case ExporterFile:
+		if config.File == "" {
+			return nil, nil, errors.New("a filename is required for the file exporter")
+		}
+		f, err := os.OpenFile(config.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error opening trace file: %w", err)
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			f.Close()
+			return nil, nil, fmt.Errorf("error creating file exporter: %w", err)
+		}
+		return exporter, f, nil
+	case ExporterOTLP:
+		var opts []otlptracehttp.Option
+		if config.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(config.Endpoint))
+		}
+		if config.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err := otlptracehttp.New(ctx, opts...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error creating OTLP exporter: %w", err)
+		}
+		return exporter, nil, nil
+	default:
+		return nil, nil, fmt.Errorf("invalid trace exporter %q: expected one of none, stdout, file, otlp", config.Exporter)
+	}
+}
+
+// Start creates a span that is a child of any span in the context
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(serviceName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records the error, if any, and ends the span. It is intended to be deferred with a named error
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Middleware creates a span for each HTTP request. Trace context from incoming headers is used as
+// the parent and the span is named using the route pattern after the request is handled
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := otel.Tracer(serviceName).Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(r.URL.Path),
+			),
+		)
+		defer span.End()
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			span.SetName(r.Method + " " + rctx.RoutePattern())
+			span.SetAttributes(semconv.HTTPRoute(rctx.RoutePattern()))
+		}
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
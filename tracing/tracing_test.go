@@ -0,0 +1,82 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestMiddleware(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Get("/tours/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, span := Start(r.Context(), "child")
+		End(span, errors.New("failed"))
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/tours/abc", nil))
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+
+	child, server := spans[0], spans[1]
+	if server.Name != "GET /tours/{id}" {
+		t.Errorf("unexpected span name: %q", server.Name)
+	}
+	if server.Status.Code != codes.Error {
+		t.Errorf("expected error status for server span")
+	}
+	if child.Parent.SpanID() != server.SpanContext.SpanID() {
+		t.Errorf("expected child span to be a child of the server span")
+	}
+	if child.Status.Code != codes.Error || len(child.Events) != 1 {
+		t.Errorf("expected error to be recorded on child span")
+	}
+}
+
+func TestSetupFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "traces.json")
+
+	shutdown, err := Setup(context.Background(), Config{Exporter: ExporterFile, File: filename})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, span := Start(context.Background(), "test")
+	End(span, nil)
+
+	err = shutdown(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"Name":"test"`) {
+		t.Errorf("expected span in file: %s", data)
+	}
+
+	_, err = Setup(context.Background(), Config{Exporter: "invalid"})
+	if err == nil {
+		t.Errorf("expected error for invalid exporter")
+	}
+}